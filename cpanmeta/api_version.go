@@ -1,6 +1,12 @@
 package cpanmeta
 
-import "github.com/cmburn/perlutils/version"
+import (
+	"encoding/json"
+	"strconv"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
 
 type APIVersion struct {
 	// Version is the version of CPAN::Meta::Spec that the metadata
@@ -9,4 +15,33 @@ type APIVersion struct {
 
 	// URL is the URL of the specification document.
 	URL string `json:"url"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+// MarshalJSON marshals the version as a number, as CPAN::Meta does, if it
+// is one.
+func (a APIVersion) MarshalJSON() ([]byte, error) {
+	obj := a.Custom.object()
+	if err := setJSON(obj, "url", a.URL); err != nil {
+		return nil, err
+	}
+	v := a.Version.Raw()
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		obj["version"] = json.Number(v)
+	} else {
+		obj["version"] = v
+	}
+	return marshalJSON(obj)
+}
+
+func (a *APIVersion) UnmarshalJSON(data []byte) error {
+	type apiVersion APIVersion
+	if err := json.Unmarshal(data, (*apiVersion)(a)); err != nil {
+		return err
+	}
+	var err error
+	a.Custom, err = unmarshalCustom(data)
+	return err
 }
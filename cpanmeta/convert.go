@@ -0,0 +1,475 @@
+package cpanmeta
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+const (
+	specURL2  = "http://search.cpan.org/perldoc?CPAN::Meta::Spec"
+	specURL14 = "http://module-build.sourceforge.net/META-spec-v1.4.html"
+)
+
+// LoadMetaYML reads a META.yml file. Files written to any version of
+// CPAN::Meta::Spec before 2 are upgraded as Upgrade does.
+func LoadMetaYML(r io.Reader) (*Spec, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseYAML(string(src))
+	if err != nil {
+		return nil, err
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errNotMapping
+	}
+	return Upgrade(m)
+}
+
+// WriteMetaYML writes s to w as a META.yml file, downgraded to version 1.4
+// of CPAN::Meta::Spec as Downgrade does.
+func WriteMetaYML(w io.Writer, s *Spec) error {
+	_, err := io.WriteString(w, dumpYAML(Downgrade(s)))
+	return err
+}
+
+// Upgrade converts a metadata document, as decoded from YAML or JSON, to a
+// Spec, in the same way as CPAN::Meta::Converter. Documents written to
+// versions 1.0 to 1.4 of CPAN::Meta::Spec have their fields renamed and
+// restructured; for instance, requires and build_requires become runtime
+// and build prereqs, licenses like "perl" become "perl_5", and a repository
+// given as a URL becomes a repository with that URL. Values that can't be
+// converted, such as invalid versions, are replaced with defaults rather
+// than being errors, and custom fields are kept. Documents that are already
+// version 2 are decoded as they are.
+func Upgrade(doc map[string]interface{}) (*Spec, error) {
+	if specVersion(doc) >= 2 {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		s := &Spec{}
+		if err = json.Unmarshal(data, s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	s := &Spec{
+		Abstract:      yamlString(doc["abstract"]),
+		Author:        yamlStrings(doc["author"]),
+		DynamicConfig: true,
+		GeneratedBy:   yamlString(doc["generated_by"]),
+		Name:          yamlString(doc["name"]),
+		Version: version.JSON{
+			Version: cleanVersion(doc["version"]),
+		},
+		Keywords: yamlStrings(doc["keywords"]),
+		Prereqs:  upgradePrereqs(doc),
+		Provides: upgradeProvides(doc["provides"]),
+	}
+	s.MetaSpec.Version.Version = version.MustParse("2")
+	s.MetaSpec.URL = specURL2
+	if s.Abstract == "" {
+		s.Abstract = "unknown"
+	}
+	if s.Name == "" {
+		s.Name = "unknown"
+	}
+	if dc, ok := doc["dynamic_config"].(string); ok {
+		s.DynamicConfig = dc != "" && dc != "0" && dc != "false"
+	}
+	if strings.Contains(s.Version.Raw(), "_") {
+		s.ReleaseStatus = ReleaseStatusTesting
+	}
+	lic := yamlString(doc["license"])
+	if l, ok := licenseUpgrades[strings.ToLower(lic)]; ok {
+		s.License = []License{l}
+	} else if l, err := NewLicense(lic); err == nil {
+		s.License = []License{l}
+	} else {
+		s.License = []License{LicenseUnknown}
+	}
+	noIndex := doc["no_index"]
+	if noIndex == nil {
+		// 1.0 called it private
+		noIndex = doc["private"]
+	}
+	if m, ok := noIndex.(map[string]interface{}); ok {
+		s.NoIndex.File = yamlStrings(m["file"])
+		s.NoIndex.Directory = yamlStrings(m["directory"])
+		if s.NoIndex.Directory == nil {
+			s.NoIndex.Directory = yamlStrings(m["dir"])
+		}
+		s.NoIndex.Package = yamlStrings(m["package"])
+		s.NoIndex.Namespace = yamlStrings(m["namespace"])
+	}
+	if fs, ok := doc["optional_features"].(map[string]interface{}); ok {
+		s.OptionalFeatures = make(map[string]OptionalFeature, len(fs))
+		for name, f := range fs {
+			m, _ := f.(map[string]interface{})
+			s.OptionalFeatures[name] = OptionalFeature{
+				Description: yamlString(m["description"]),
+				Prereqs:     upgradePrereqs(m),
+			}
+		}
+	}
+	upgradeResources(&s.Resources, doc)
+	for k, v := range doc {
+		if isCustomKey(k) {
+			if s.Custom == nil {
+				s.Custom = make(Custom)
+			}
+			s.Custom[k] = v
+		}
+	}
+	return s, nil
+}
+
+// Downgrade converts s to a document written to version 1.4 of
+// CPAN::Meta::Spec, in the same way as CPAN::Meta::Converter. Build and
+// test prereqs are merged into build_requires, develop prereqs are dropped,
+// and licenses are mapped to the nearest 1.4 license. Custom fields are
+// kept at the top level and in resources, and empty fields are left out.
+func Downgrade(s *Spec) map[string]interface{} {
+	doc := map[string]interface{}{
+		"meta-spec": map[string]interface{}{
+			"version": "1.4",
+			"url":     specURL14,
+		},
+		"abstract":       s.Abstract,
+		"dynamic_config": 0,
+		"license":        downgradeLicense(s.License),
+		"name":           s.Name,
+		"version":        s.Version.Raw(),
+	}
+	for k, v := range s.Custom {
+		doc[k] = v
+	}
+	if s.Abstract == "" {
+		doc["abstract"] = "unknown"
+	}
+	if s.DynamicConfig {
+		doc["dynamic_config"] = 1
+	}
+	setYAML(doc, "author", yamlList(s.Author))
+	setYAML(doc, "generated_by", s.GeneratedBy)
+	setYAML(doc, "keywords", yamlList(s.Keywords))
+	downgradePrereqs(doc, &s.Prereqs, true)
+	noIndex := map[string]interface{}{}
+	setYAML(noIndex, "file", yamlList(s.NoIndex.File))
+	setYAML(noIndex, "directory", yamlList(s.NoIndex.Directory))
+	setYAML(noIndex, "package", yamlList(s.NoIndex.Package))
+	setYAML(noIndex, "namespace", yamlList(s.NoIndex.Namespace))
+	setYAML(doc, "no_index", noIndex)
+	features := map[string]interface{}{}
+	for name, f := range s.OptionalFeatures {
+		m := map[string]interface{}{}
+		setYAML(m, "description", f.Description)
+		downgradePrereqs(m, &f.Prereqs, false)
+		features[name] = m
+	}
+	setYAML(doc, "optional_features", features)
+	provides := map[string]interface{}{}
+	for name, f := range s.Provides {
+		m := map[string]interface{}{"file": f.File}
+		setYAML(m, "version", f.Version.Raw())
+		provides[name] = m
+	}
+	setYAML(doc, "provides", provides)
+	res := s.Resources.Custom.object()
+	setYAML(res, "homepage", s.Resources.Homepage)
+	if len(s.Resources.License) > 0 {
+		res["license"] = s.Resources.License[0]
+	}
+	setYAML(res, "bugtracker", s.Resources.BugTracker.Web)
+	setYAML(res, "repository", s.Resources.Repository.URL)
+	setYAML(doc, "resources", res)
+	return doc
+}
+
+// specVersion returns the version of CPAN::Meta::Spec a document was
+// written to, which is 1.0 if it doesn't say.
+func specVersion(doc map[string]interface{}) float64 {
+	var v interface{}
+	if m, ok := doc["meta-spec"].(map[string]interface{}); ok {
+		v = m["version"]
+	}
+	switch v := v.(type) {
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case float64:
+		return v
+	}
+	return 1.0
+}
+
+// upgradePrereqs returns the prereqs in a 1.x document or optional feature.
+func upgradePrereqs(doc map[string]interface{}) Prereqs {
+	var p Prereqs
+	p.Runtime.Requires = upgradeRequirements(doc["requires"])
+	p.Runtime.Recommends = upgradeRequirements(doc["recommends"])
+	p.Runtime.Conflicts = upgradeRequirements(doc["conflicts"])
+	p.Build.Requires = upgradeRequirements(doc["build_requires"])
+	p.Configure.Requires = upgradeRequirements(doc["configure_requires"])
+	return p
+}
+
+func upgradeRequirements(v interface{}) map[string]version.RangeJSON {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+	reqs := make(map[string]version.RangeJSON, len(m))
+	for module, v := range m {
+		s := strings.TrimSpace(yamlString(v))
+		if s == "" {
+			s = "0"
+		}
+		r, err := parseRequirement(s)
+		if err != nil {
+			r, _ = parseRequirement("0")
+		}
+		reqs[module] = r
+	}
+	return reqs
+}
+
+func upgradeProvides(v interface{}) map[string]File {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+	provides := make(map[string]File, len(m))
+	for name, v := range m {
+		f, _ := v.(map[string]interface{})
+		provides[name] = File{
+			File: yamlString(f["file"]),
+			Version: version.JSON{
+				Version: cleanVersion(f["version"]),
+			},
+		}
+	}
+	return provides
+}
+
+// upgradeResources fills res from a 1.x document, where each resource is
+// just a URL, and the license URL may be a top-level license_uri. Resources
+// 1.x doesn't define, such as MailingList, become custom resources.
+func upgradeResources(res *Resources, doc map[string]interface{}) {
+	m, _ := doc["resources"].(map[string]interface{})
+	for k, v := range m {
+		switch k {
+		case "homepage", "license", "bugtracker", "repository":
+			continue
+		}
+		if !isCustomKey(k) {
+			k = "x_" + k
+		}
+		if res.Custom == nil {
+			res.Custom = make(Custom)
+		}
+		res.Custom[k] = v
+	}
+	res.Homepage = yamlString(m["homepage"])
+	res.License = yamlStrings(m["license"])
+	if res.License == nil {
+		res.License = yamlStrings(doc["license_uri"])
+	}
+	switch bt := m["bugtracker"].(type) {
+	case string:
+		res.BugTracker.Web = bt
+	case map[string]interface{}:
+		res.BugTracker.Web = yamlString(bt["web"])
+		res.BugTracker.MailTo = yamlString(bt["mailto"])
+	}
+	switch repo := m["repository"].(type) {
+	case string:
+		res.Repository.URL = repo
+	case map[string]interface{}:
+		res.Repository.Type = yamlString(repo["type"])
+		res.Repository.URL = yamlString(repo["url"])
+		res.Repository.Web = yamlString(repo["web"])
+	}
+}
+
+// downgradePrereqs adds the 1.4 fields for p to doc. Optional features
+// can't have configure_requires.
+func downgradePrereqs(doc map[string]interface{}, p *Prereqs,
+	configure bool) {
+	setYAML(doc, "requires", downgradeRequirements(p.Runtime.Requires))
+	setYAML(doc, "recommends", downgradeRequirements(
+		p.Runtime.Recommends))
+	setYAML(doc, "conflicts", downgradeRequirements(p.Runtime.Conflicts))
+	setYAML(doc, "build_requires", downgradeRequirements(p.Build.Requires,
+		p.Test.Requires))
+	if configure {
+		setYAML(doc, "configure_requires", downgradeRequirements(
+			p.Configure.Requires))
+	}
+}
+
+// downgradeRequirements merges requirements into a 1.4 map. A module in
+// more than one of them must satisfy all of their ranges.
+func downgradeRequirements(
+	reqs ...map[string]version.RangeJSON) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, req := range reqs {
+		for module, r := range req {
+			s := r.Raw()
+			if s == "" {
+				s = "0"
+			}
+			prev, _ := m[module].(string)
+			if prev != "" && prev != "0" && prev != s && s != "0" {
+				s = prev + ", " + s
+			} else if s == "0" && prev != "" {
+				s = prev
+			}
+			m[module] = s
+		}
+	}
+	return m
+}
+
+func downgradeLicense(ls []License) string {
+	if len(ls) == 1 {
+		return licenseDowngrades[ls[0]]
+	}
+	if len(ls) == 0 {
+		return "unknown"
+	}
+	for _, l := range ls {
+		switch licenseDowngrades[l] {
+		case "unknown", "restrictive":
+			return "unknown"
+		}
+	}
+	return "open_source"
+}
+
+// cleanVersion parses a version, which is 0 if it's missing or invalid.
+func cleanVersion(v interface{}) version.Version {
+	s := strings.TrimSpace(yamlString(v))
+	if ver, err := version.Parse(s); err == nil && s != "" {
+		return ver
+	}
+	return version.MustParse("0")
+}
+
+// yamlString returns v if it's a string, and "" otherwise.
+func yamlString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return ""
+}
+
+// yamlStrings returns v as a list of strings. 1.x documents often give a
+// single string where a list is allowed.
+func yamlStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			if e, ok := e.(string); ok {
+				s = append(s, e)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+func yamlList(s []string) []interface{} {
+	l := make([]interface{}, len(s))
+	for i, e := range s {
+		l[i] = e
+	}
+	return l
+}
+
+// setYAML sets doc[key] to v, unless v is empty.
+func setYAML(doc map[string]interface{}, key string, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return
+		}
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return
+		}
+	}
+	doc[key] = v
+}
+
+// licenseUpgrades maps the 1.x licenses that changed name in 2.
+var licenseUpgrades = map[string]License{
+	"apache":      LicenseApache2_0,
+	"artistic":    LicenseArtistic1,
+	"artistic2":   LicenseArtistic2,
+	"gpl":         LicenseOpenSource,
+	"lgpl":        LicenseOpenSource,
+	"mozilla":     LicenseOpenSource,
+	"perl":        LicensePerl5,
+	"restrictive": LicenseRestricted,
+}
+
+// licenseDowngrades maps every license to its 1.4 equivalent.
+var licenseDowngrades = map[License]string{
+	LicenseUnknown:      "unknown",
+	LicenseOpenSource:   "open_source",
+	LicenseRestricted:   "restrictive",
+	LicenseUnrestricted: "unrestricted",
+	LicenseAGPL3:        "open_source",
+	LicenseApache1_1:    "apache",
+	LicenseApache2_0:    "apache",
+	LicenseArtistic1:    "artistic",
+	LicenseArtistic2:    "artistic_2",
+	LicenseBSD:          "bsd",
+	LicenseFreeBSD:      "open_source",
+	LicenseGFDL1_2:      "open_source",
+	LicenseGFDL1_3:      "open_source",
+	LicenseGPL1:         "gpl",
+	LicenseGPL2:         "gpl",
+	LicenseGPL3:         "gpl",
+	LicenseLGPL2_1:      "lgpl",
+	LicenseLGPL3_0:      "lgpl",
+	LicenseMIT:          "mit",
+	LicenseMozilla1_0:   "mozilla",
+	LicenseMozilla1_1:   "mozilla",
+	LicenseOpenSSL:      "open_source",
+	LicensePerl5:        "perl",
+	LicenseQPL1_0:       "open_source",
+	LicenseSSLeay:       "open_source",
+	LicenseSun:          "open_source",
+	LicenseZlib:         "open_source",
+}
+
+var (
+	errNotMapping = errors.New("metadata isn't a mapping")
+)
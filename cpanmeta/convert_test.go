@@ -0,0 +1,330 @@
+package cpanmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// tMetaYML14 is a META.yml written to version 1.4 of CPAN::Meta::Spec, as
+// ExtUtils::MakeMaker writes them.
+const tMetaYML14 = `---
+abstract: 'Frobnicate widgets'
+author:
+  - 'Jane Doe <jane@example.com>'
+build_requires:
+  ExtUtils::MakeMaker: 0
+  Test::More: 0.88
+configure_requires:
+  ExtUtils::MakeMaker: 6.30
+dynamic_config: 0
+generated_by: 'ExtUtils::MakeMaker version 7.34'
+license: perl
+meta-spec:
+  url: http://module-build.sourceforge.net/META-spec-v1.4.html
+  version: 1.4
+name: Foo-Bar
+no_index:
+  directory:
+    - t
+    - inc
+optional_features:
+  xs:
+    description: 'Faster frobnication'
+    requires:
+      Foo::XS: 1.2
+provides:
+  Foo::Bar:
+    file: lib/Foo/Bar.pm
+    version: 1.02_01
+recommends:
+  JSON::XS: 2
+requires:
+  Carp: 0
+  Moo: '>= 2, < 3'
+  perl: 5.006
+resources:
+  bugtracker: https://rt.cpan.org/Dist/Display.html?Name=Foo-Bar
+  homepage: https://example.com/foo-bar
+  MailingList: ` + tMailingList + `
+  repository: https://github.com/jane/Foo-Bar.git
+version: 1.02_01
+x_custom: {a: [1, 2]}
+`
+
+const tMailingList = "http://lists.example.com/foo"
+
+// tMetaYML10 is a META.yml written to version 1.0 of CPAN::Meta::Spec,
+// which didn't say which version it was written to.
+const tMetaYML10 = `--- #YAML:1.0
+name: Foo-Old
+version: not a version
+license: gpl
+license_uri: http://www.gnu.org/licenses/gpl.html
+distribution_type: module
+requires:
+  Carp:
+private:
+  dir:
+    - inc
+`
+
+func TestLoadMetaYML(t *testing.T) {
+	t.Parallel()
+	s, err := LoadMetaYML(strings.NewReader(tMetaYML14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name          string
+		got, expected interface{}
+	}{
+		{"meta-spec", s.MetaSpec.Version.Raw(), "2"},
+		{"name", s.Name, "Foo-Bar"},
+		{"version", s.Version.Raw(), "1.02_01"},
+		{"release_status", s.ReleaseStatus, ReleaseStatusTesting},
+		{"dynamic_config", s.DynamicConfig, false},
+		{"license", s.License, []License{LicensePerl5}},
+		{"author", s.Author, []string{"Jane Doe <jane@example.com>"}},
+		{"no_index", s.NoIndex.Directory, []string{"t", "inc"}},
+		{"requires", tRequirements(s.Prereqs.Runtime.Requires),
+			map[string]string{"Carp": "0", "Moo": ">= 2, < 3",
+				"perl": "5.006"}},
+		{"recommends", tRequirements(s.Prereqs.Runtime.Recommends),
+			map[string]string{"JSON::XS": "2"}},
+		{"build_requires", tRequirements(s.Prereqs.Build.Requires),
+			map[string]string{"ExtUtils::MakeMaker": "0",
+				"Test::More": "0.88"}},
+		{"configure_requires", tRequirements(
+			s.Prereqs.Configure.Requires),
+			map[string]string{"ExtUtils::MakeMaker": "6.30"}},
+		{"optional_features", tRequirements(
+			s.OptionalFeatures["xs"].Prereqs.Runtime.Requires),
+			map[string]string{"Foo::XS": "1.2"}},
+		{"provides", s.Provides["Foo::Bar"].File, "lib/Foo/Bar.pm"},
+		{"homepage", s.Resources.Homepage,
+			"https://example.com/foo-bar"},
+		{"bugtracker", s.Resources.BugTracker.Web,
+			"https://rt.cpan.org/Dist/Display.html?Name=Foo-Bar"},
+		{"repository", s.Resources.Repository.URL,
+			"https://github.com/jane/Foo-Bar.git"},
+		{"custom resources", s.Resources.Custom,
+			Custom{"x_MailingList": tMailingList}},
+		{"custom", s.Custom, Custom{"x_custom": map[string]interface{}{
+			"a": []interface{}{"1", "2"}}}},
+	} {
+		if !reflect.DeepEqual(test.got, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name,
+				test.expected, test.got)
+		}
+	}
+
+	s, err = LoadMetaYML(strings.NewReader(tMetaYML10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name          string
+		got, expected interface{}
+	}{
+		{"version", s.Version.Raw(), "0"},
+		{"abstract", s.Abstract, "unknown"},
+		{"dynamic_config", s.DynamicConfig, true},
+		{"license", s.License, []License{LicenseOpenSource}},
+		{"license_uri", s.Resources.License,
+			[]string{"http://www.gnu.org/licenses/gpl.html"}},
+		{"private", s.NoIndex.Directory, []string{"inc"}},
+		{"requires", tRequirements(s.Prereqs.Runtime.Requires),
+			map[string]string{"Carp": "0"}},
+	} {
+		if !reflect.DeepEqual(test.got, test.expected) {
+			t.Errorf("1.0 %s: expected %#v, got %#v", test.name,
+				test.expected, test.got)
+		}
+	}
+
+	for _, test := range []struct {
+		license  string
+		expected License
+	}{
+		{"perl", LicensePerl5},
+		{"apache", LicenseApache2_0},
+		{"artistic", LicenseArtistic1},
+		{"artistic2", LicenseArtistic2},
+		{"restrictive", LicenseRestricted},
+		{"mit", LicenseMIT},
+		{"wtfpl", LicenseUnknown},
+	} {
+		s, err = LoadMetaYML(strings.NewReader("name: Foo\nlicense: " +
+			test.license + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s.License) != 1 || s.License[0] != test.expected {
+			t.Errorf("license %s: expected %v, got %v",
+				test.license, test.expected, s.License)
+		}
+	}
+
+	s, err = LoadMetaYML(strings.NewReader(`
+meta-spec: {version: 2}
+name: Foo-New
+version: '1.0'
+resources:
+  repository:
+    type: git
+    url: https://example.com/foo.git
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Foo-New" || s.Resources.Repository.Type != "git" {
+		t.Errorf("unexpected version 2 spec %#v", s)
+	}
+	for _, src := range []string{"- a\n", "a: [\n", "--- a\n"} {
+		if _, err = LoadMetaYML(strings.NewReader(src)); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
+func TestWriteMetaYML(t *testing.T) {
+	t.Parallel()
+	s, err := LoadMetaYML(strings.NewReader(tMetaYML14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = WriteMetaYML(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseYAML(buf.String())
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, buf.String())
+	}
+	m := doc.(map[string]interface{})
+	for _, test := range []struct {
+		key      string
+		expected interface{}
+	}{
+		{"license", "perl"},
+		{"dynamic_config", "0"},
+		{"build_requires", map[string]interface{}{
+			"ExtUtils::MakeMaker": "0", "Test::More": "0.88"}},
+		{"meta-spec", map[string]interface{}{"version": "1.4",
+			"url": specURL14}},
+	} {
+		if !reflect.DeepEqual(m[test.key], test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.key,
+				test.expected, m[test.key])
+		}
+	}
+	if !strings.HasPrefix(buf.String(), "---\nabstract: ") {
+		t.Errorf("unexpected META.yml\n%s", buf.String())
+	}
+}
+
+// TestMetaYML_roundTrip writes each META.json in testdata as a META.yml,
+// which is version 1.4, and reads it back, checking that everything 1.4
+// can hold survives.
+func TestMetaYML_roundTrip(t *testing.T) {
+	t.Parallel()
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no META.json files found: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Spec
+		if err = json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		var buf bytes.Buffer
+		if err = WriteMetaYML(&buf, &s); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		got, err := LoadMetaYML(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		build := tRequirements(s.Prereqs.Build.Requires)
+		for module, r := range tRequirements(s.Prereqs.Test.Requires) {
+			if prev, ok := build[module]; ok && prev != r {
+				r = prev + ", " + r
+			}
+			build[module] = r
+		}
+		for _, test := range []struct {
+			name          string
+			got, expected interface{}
+		}{
+			{"name", got.Name, s.Name},
+			{"version", got.Version.Raw(), s.Version.Raw()},
+			{"abstract", got.Abstract, s.Abstract},
+			{"author", got.Author, s.Author},
+			{"dynamic_config", got.DynamicConfig, s.DynamicConfig},
+			{"release_status", got.ReleaseStatus, s.ReleaseStatus},
+			{"no_index", got.NoIndex.Directory,
+				s.NoIndex.Directory},
+			{"keywords", got.Keywords, s.Keywords},
+			{"requires", tRequirements(
+				got.Prereqs.Runtime.Requires), tRequirements(
+				s.Prereqs.Runtime.Requires)},
+			{"build_requires", tRequirements(
+				got.Prereqs.Build.Requires), build},
+			{"configure_requires", tRequirements(
+				got.Prereqs.Configure.Requires), tRequirements(
+				s.Prereqs.Configure.Requires)},
+			{"homepage", got.Resources.Homepage,
+				s.Resources.Homepage},
+			{"repository", got.Resources.Repository.URL,
+				s.Resources.Repository.URL},
+			{"bugtracker", got.Resources.BugTracker.Web,
+				s.Resources.BugTracker.Web},
+			// YAML scalars have no type, so only keys are compared
+			{"custom", tKeys(got.Custom), tKeys(s.Custom)},
+		} {
+			if !reflect.DeepEqual(test.got, test.expected) {
+				t.Errorf("%s: %s: expected %#v, got %#v", path,
+					test.name, test.expected, test.got)
+			}
+		}
+		for name, f := range s.Provides {
+			if got.Provides[name].File != f.File {
+				t.Errorf("%s: provides %s: expected %q, got %q",
+					path, name, f.File,
+					got.Provides[name].File)
+			}
+		}
+	}
+}
+
+// tRequirements returns reqs as strings, so that they can be compared.
+func tRequirements(reqs map[string]version.RangeJSON) map[string]string {
+	m := make(map[string]string, len(reqs))
+	for module, r := range reqs {
+		m[module] = r.Raw()
+	}
+	return m
+}
+
+// tKeys returns the keys of c, sorted.
+func tKeys(c Custom) []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,546 @@
+package cpanmeta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Cpanfile is a parsed cpanfile, the Perl DSL used by Module::CPANfile to
+// declare a distribution's dependencies.
+type Cpanfile struct {
+	// Prereqs contains the prerequisites declared outside of any feature.
+	Prereqs Prereqs
+
+	// OptionalFeatures contains the features declared with "feature".
+	OptionalFeatures map[string]OptionalFeature
+
+	// Mirrors is a list of the mirrors declared with "mirror".
+	Mirrors []string
+}
+
+// ParseCpanfile parses a cpanfile. Only the declarative subset of the DSL
+// is understood: requires, recommends, suggests and conflicts, along with
+// their configure_, build_, test_ and author_ shorthands, on blocks,
+// feature blocks and mirror. Anything else, such as arbitrary Perl code, is
+// a *SyntaxError.
+//
+// Modules without a version are given a version of 0, and versions may be
+// ranges, such as ">= 1.0, < 2".
+func ParseCpanfile(r io.Reader) (*Cpanfile, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &cpanfileParser{
+		lex:      cpanfileLexer{src: string(src), line: 1, bol: true},
+		features: make(map[string]*OptionalFeature),
+	}
+	c := &Cpanfile{}
+	if err = p.statements(&c.Prereqs, c, "", ""); err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.kind != cpanfileEOF {
+		return nil, p.unexpected(tok)
+	}
+	if len(p.features) > 0 {
+		c.OptionalFeatures = make(map[string]OptionalFeature,
+			len(p.features))
+		for name, f := range p.features {
+			c.OptionalFeatures[name] = *f
+		}
+	}
+	return c, nil
+}
+
+// WriteCpanfile writes p to w as a cpanfile, in the same layout as
+// (*Cpanfile).WriteTo.
+func WriteCpanfile(w io.Writer, p *Prereqs) error {
+	_, err := (&Cpanfile{Prereqs: *p}).WriteTo(w)
+	return err
+}
+
+// WriteTo writes c to w as a canonical cpanfile: mirrors first, then
+// runtime prerequisites at the top level, then an on block for each other
+// phase, then features sorted by name. Within each, relationships are
+// written in the order requires, recommends, suggests and conflicts, with
+// modules sorted by name, and versions of 0 are left out.
+func (c *Cpanfile) WriteTo(w io.Writer) (int64, error) {
+	var sections []string
+	if len(c.Mirrors) > 0 {
+		var sb strings.Builder
+		for _, m := range c.Mirrors {
+			sb.WriteString("mirror " + quoteCpanfile(m) + ";\n")
+		}
+		sections = append(sections, sb.String())
+	}
+	sections = append(sections, cpanfilePrereqs(&c.Prereqs, "")...)
+	names := make([]string, 0, len(c.OptionalFeatures))
+	for name := range c.OptionalFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := c.OptionalFeatures[name]
+		var sb strings.Builder
+		sb.WriteString("feature " + quoteCpanfile(name))
+		if f.Description != "" {
+			sb.WriteString(", " + quoteCpanfile(f.Description))
+		}
+		sb.WriteString(" => sub {\n")
+		sb.WriteString(strings.Join(cpanfilePrereqs(&f.Prereqs,
+			"    "), "\n"))
+		sb.WriteString("};\n")
+		sections = append(sections, sb.String())
+	}
+	bw := bufio.NewWriter(w)
+	n, err := bw.WriteString(strings.Join(sections, "\n"))
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), bw.Flush()
+}
+
+// cpanfilePrereqs returns the sections of a cpanfile declaring p, indented
+// by indent.
+func cpanfilePrereqs(p *Prereqs, indent string) []string {
+	var sections []string
+	if s := cpanfilePhase(&p.Runtime, indent); s != "" {
+		sections = append(sections, s)
+	}
+	for _, ph := range []struct {
+		name  string
+		phase *Phase
+	}{
+		{"configure", &p.Configure},
+		{"build", &p.Build},
+		{"test", &p.Test},
+		{"develop", &p.Develop},
+	} {
+		s := cpanfilePhase(ph.phase, indent+"    ")
+		if s == "" {
+			continue
+		}
+		sections = append(sections, indent+"on "+ph.name+
+			" => sub {\n"+s+indent+"};\n")
+	}
+	return sections
+}
+
+// cpanfilePhase returns the declarations in ph, indented by indent, or ""
+// if there are none.
+func cpanfilePhase(ph *Phase, indent string) string {
+	var sb strings.Builder
+	for _, rel := range []struct {
+		name string
+		reqs map[string]version.RangeJSON
+	}{
+		{"requires", ph.Requires},
+		{"recommends", ph.Recommends},
+		{"suggests", ph.Suggests},
+		{"conflicts", ph.Conflicts},
+	} {
+		modules := make([]string, 0, len(rel.reqs))
+		for m := range rel.reqs {
+			modules = append(modules, m)
+		}
+		sort.Strings(modules)
+		for _, m := range modules {
+			r := rel.reqs[m]
+			sb.WriteString(indent + rel.name + " " +
+				quoteCpanfile(m))
+			if v := r.Raw(); v != "" && v != "0" {
+				sb.WriteString(", " + quoteCpanfile(v))
+			}
+			sb.WriteString(";\n")
+		}
+	}
+	return sb.String()
+}
+
+func quoteCpanfile(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+type cpanfileParser struct {
+	lex      cpanfileLexer
+	peeked   *cpanfileToken
+	features map[string]*OptionalFeature
+}
+
+// statements parses statements into p until the end of the file or a
+// closing brace, which is left unread. phase is the phase of the enclosing
+// on block, and feature the name of the enclosing feature block, if any.
+func (cp *cpanfileParser) statements(p *Prereqs, c *Cpanfile, phase,
+	feature string) error {
+	for {
+		tok := cp.peek()
+		switch {
+		case tok.kind == cpanfileEOF || tok.is("}"):
+			return nil
+		case tok.is(";"):
+			cp.next()
+			continue
+		}
+		if err := cp.statement(p, c, phase, feature); err != nil {
+			return err
+		}
+		tok = cp.peek()
+		switch {
+		case tok.is(";"):
+			cp.next()
+		case tok.kind == cpanfileEOF || tok.is("}"):
+		default:
+			return cp.unexpected(tok)
+		}
+	}
+}
+
+func (cp *cpanfileParser) statement(p *Prereqs, c *Cpanfile, phase,
+	feature string) error {
+	cmd := cp.next()
+	if cmd.kind != cpanfileWord {
+		return cp.unexpected(cmd)
+	}
+	args, block, err := cp.args()
+	if err != nil {
+		return err
+	}
+	switch cmd.text {
+	case "on":
+		if len(args) != 1 || !block {
+			return cp.errorf(cmd.line, "usage: on PHASE => "+
+				"sub { ... }")
+		}
+		if phase != "" {
+			return cp.errorf(cmd.line, "on blocks can't be nested")
+		}
+		if prereqsPhase(p, args[0].text) == nil {
+			return cp.errorf(args[0].line, "unknown phase %q",
+				args[0].text)
+		}
+		return cp.block(p, c, args[0].text, feature)
+	case "feature":
+		if len(args) < 1 || len(args) > 2 || !block {
+			return cp.errorf(cmd.line, "usage: feature NAME[, "+
+				"DESCRIPTION] => sub { ... }")
+		}
+		if feature != "" || phase != "" {
+			return cp.errorf(cmd.line, "feature blocks must be at "+
+				"the top level")
+		}
+		if _, ok := cp.features[args[0].text]; ok {
+			return cp.errorf(cmd.line, "feature %q already "+
+				"declared", args[0].text)
+		}
+		f := &OptionalFeature{}
+		if len(args) == 2 {
+			f.Description = args[1].text
+		}
+		cp.features[args[0].text] = f
+		return cp.block(&f.Prereqs, c, "", args[0].text)
+	case "mirror":
+		if len(args) != 1 || block {
+			return cp.errorf(cmd.line, "usage: mirror URL")
+		}
+		if feature != "" || phase != "" {
+			return cp.errorf(cmd.line, "mirror must be at the top "+
+				"level")
+		}
+		c.Mirrors = append(c.Mirrors, args[0].text)
+		return nil
+	}
+	ph, rel := phase, cmd.text
+	if short, ok := cpanfileShorthands[cmd.text]; ok {
+		if phase != "" {
+			return cp.errorf(cmd.line, "%s can't be used in an on "+
+				"block", cmd.text)
+		}
+		ph, rel = short, "requires"
+	}
+	if ph == "" {
+		ph = "runtime"
+	}
+	reqs := phaseRelationship(prereqsPhase(p, ph), rel)
+	if reqs == nil {
+		return cp.errorf(cmd.line, "unknown command %q", cmd.text)
+	}
+	if len(args) < 1 || len(args) > 2 || block {
+		return cp.errorf(cmd.line, "usage: %s MODULE[, VERSION]",
+			cmd.text)
+	}
+	v := "0"
+	if len(args) == 2 {
+		v = args[1].text
+	}
+	r, err := parseRequirement(v)
+	if err != nil {
+		return cp.errorf(args[len(args)-1].line, "%s: %s", v, err)
+	}
+	if *reqs == nil {
+		*reqs = make(map[string]version.RangeJSON)
+	}
+	(*reqs)[args[0].text] = r
+	return nil
+}
+
+// args parses the arguments to a command, reporting whether they end in a
+// block, which is left to be parsed by block.
+func (cp *cpanfileParser) args() ([]cpanfileToken, bool, error) {
+	paren := false
+	if cp.peek().is("(") {
+		cp.next()
+		paren = true
+	}
+	var args []cpanfileToken
+	block := false
+	for {
+		tok := cp.peek()
+		if tok.kind == cpanfileError {
+			return nil, false, cp.unexpected(tok)
+		}
+		if tok.kind == cpanfileString || (tok.kind == cpanfileWord &&
+			tok.text != "sub") {
+			cp.next()
+			args = append(args, tok)
+		} else if tok.is("sub") {
+			cp.next()
+			if tok = cp.next(); !tok.is("{") {
+				return nil, false, cp.unexpected(tok)
+			}
+			block = true
+		} else {
+			break
+		}
+		if block {
+			break
+		}
+		if tok = cp.peek(); !tok.is(",") && !tok.is("=>") {
+			break
+		}
+		cp.next()
+	}
+	if paren && !block {
+		if tok := cp.next(); !tok.is(")") {
+			return nil, false, cp.unexpected(tok)
+		}
+	}
+	return args, block, nil
+}
+
+// block parses the rest of a block, after its opening brace.
+func (cp *cpanfileParser) block(p *Prereqs, c *Cpanfile, phase,
+	feature string) error {
+	if err := cp.statements(p, c, phase, feature); err != nil {
+		return err
+	}
+	if tok := cp.next(); !tok.is("}") {
+		return cp.unexpected(tok)
+	}
+	return nil
+}
+
+func (cp *cpanfileParser) peek() cpanfileToken {
+	if cp.peeked == nil {
+		tok := cp.lex.next()
+		cp.peeked = &tok
+	}
+	return *cp.peeked
+}
+
+func (cp *cpanfileParser) next() cpanfileToken {
+	tok := cp.peek()
+	if tok.kind != cpanfileEOF && tok.kind != cpanfileError {
+		cp.peeked = nil
+	}
+	return tok
+}
+
+func (cp *cpanfileParser) unexpected(tok cpanfileToken) error {
+	switch tok.kind {
+	case cpanfileError:
+		return cp.errorf(tok.line, "%s", tok.text)
+	case cpanfileEOF:
+		return cp.errorf(tok.line, "unexpected end of file")
+	}
+	return cp.errorf(tok.line, "unexpected %q", tok.text)
+}
+
+func (cp *cpanfileParser) errorf(line int, format string,
+	args ...interface{}) error {
+	return &SyntaxError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+type cpanfileTokenKind int
+
+const (
+	cpanfileEOF cpanfileTokenKind = iota
+	cpanfileError
+	cpanfileWord
+	cpanfileString
+	cpanfilePunct
+)
+
+type cpanfileToken struct {
+	kind cpanfileTokenKind
+	text string
+	line int
+}
+
+// is reports whether tok is the given punctuation or bareword.
+func (tok cpanfileToken) is(s string) bool {
+	return (tok.kind == cpanfilePunct || tok.kind == cpanfileWord) &&
+		tok.text == s
+}
+
+type cpanfileLexer struct {
+	src  string
+	pos  int
+	line int
+
+	// bol is whether pos is at the beginning of a line
+	bol bool
+}
+
+func (l *cpanfileLexer) next() cpanfileToken {
+	l.skip()
+	if l.pos >= len(l.src) {
+		return cpanfileToken{kind: cpanfileEOF, line: l.line}
+	}
+	line := l.line
+	c := l.src[l.pos]
+	switch {
+	case c == '\'' || c == '"':
+		s, ok := l.quoted(c)
+		if !ok {
+			return cpanfileToken{kind: cpanfileError,
+				text: "unterminated string", line: line}
+		}
+		return cpanfileToken{kind: cpanfileString, text: s, line: line}
+	case strings.HasPrefix(l.src[l.pos:], "=>"):
+		l.pos += 2
+		return cpanfileToken{kind: cpanfilePunct, text: "=>",
+			line: line}
+	case strings.IndexByte(",;(){}", c) >= 0:
+		l.pos++
+		return cpanfileToken{kind: cpanfilePunct, text: string(c),
+			line: line}
+	case isCpanfileWord(c):
+		start := l.pos
+		for l.pos < len(l.src) && isCpanfileWord(l.src[l.pos]) {
+			l.pos++
+		}
+		return cpanfileToken{kind: cpanfileWord,
+			text: l.src[start:l.pos], line: line}
+	}
+	return cpanfileToken{kind: cpanfileError,
+		text: fmt.Sprintf("unexpected %q", c), line: line}
+}
+
+// skip skips whitespace, comments, POD and anything after __END__.
+func (l *cpanfileLexer) skip() {
+	for l.pos < len(l.src) {
+		rest := l.src[l.pos:]
+		switch c := rest[0]; {
+		case c == '\n':
+			l.line++
+			l.pos++
+			l.bol = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+			continue
+		case c == '#':
+			l.skipLine()
+			continue
+		case l.bol && c == '=' && len(rest) > 1 && isLetter(rest[1]):
+			for l.pos < len(l.src) {
+				cut := strings.HasPrefix(l.src[l.pos:], "=cut")
+				l.skipLine()
+				if cut {
+					break
+				}
+			}
+			continue
+		case l.bol && (strings.HasPrefix(rest, "__END__") ||
+			strings.HasPrefix(rest, "__DATA__")):
+			l.pos = len(l.src)
+			return
+		}
+		l.bol = false
+		return
+	}
+}
+
+// skipLine skips to the start of the next line.
+func (l *cpanfileLexer) skipLine() {
+	if i := strings.IndexByte(l.src[l.pos:], '\n'); i >= 0 {
+		l.pos += i + 1
+		l.line++
+		l.bol = true
+	} else {
+		l.pos = len(l.src)
+	}
+}
+
+// quoted reads a string quoted with q. Only backslashed quotes and
+// backslashes are unescaped; there's no interpolation.
+func (l *cpanfileLexer) quoted(q byte) (string, bool) {
+	var sb strings.Builder
+	for i := l.pos + 1; i < len(l.src); i++ {
+		switch c := l.src[i]; c {
+		case q:
+			l.pos = i + 1
+			return sb.String(), true
+		case '\\':
+			if i+1 < len(l.src) && (l.src[i+1] == q ||
+				l.src[i+1] == '\\') {
+				i++
+				c = l.src[i]
+			}
+			sb.WriteByte(c)
+		case '\n':
+			l.line++
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+func isCpanfileWord(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_' || c == ':' ||
+		c == '.'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// cpanfileShorthands maps the shorthands for requires to their phases.
+var cpanfileShorthands = map[string]string{
+	"configure_requires": "configure",
+	"build_requires":     "build",
+	"test_requires":      "test",
+	"author_requires":    "develop",
+}
+
+// SyntaxError is returned when a file can't be parsed.
+type SyntaxError struct {
+	// Line is the line number the error is on, starting at 1.
+	Line int
+
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
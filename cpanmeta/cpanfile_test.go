@@ -0,0 +1,253 @@
+package cpanmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tCpanfile exercises each form of the DSL that ParseCpanfile understands.
+const tCpanfile = `# a comment at the start
+mirror 'https://cpan.example.com/';
+
+requires 'perl', '5.008001';
+requires "Moo" => ">= 2, < 3"; # a comment after a statement
+requires('Carp');
+recommends 'JSON::XS', '2.0';
+suggests 'YAML';
+conflicts 'Foo::Old', '< 1.5'
+
+;configure_requires 'ExtUtils::MakeMaker', '6.30';
+build_requires 'Module::Build';
+test_requires 'Test::More', '0.88';
+author_requires 'Test::Pod';
+
+on test => sub {
+    requires 'Test::Deep';
+    recommends 'Test::Differences', '0.6';
+};
+
+on 'develop' => sub {
+    suggests 'Perl::Critic'
+};
+
+=pod
+
+requires 'Not::Required';
+
+=cut
+
+feature 'sqlite', 'SQLite support' => sub {
+    requires 'DBD::SQLite', '1.40';
+    on test => sub {
+        requires 'Test::SQLite';
+    };
+};
+
+feature "postgres" => sub { requires 'DBD::Pg' };
+
+__END__
+requires 'Not::Required';
+`
+
+func TestParseCpanfile(t *testing.T) {
+	t.Parallel()
+	c, err := ParseCpanfile(strings.NewReader(tCpanfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite := c.OptionalFeatures["sqlite"]
+	postgres := c.OptionalFeatures["postgres"]
+	for _, test := range []struct {
+		name          string
+		got, expected interface{}
+	}{
+		{"mirror", c.Mirrors, []string{"https://cpan.example.com/"}},
+		{"requires", tRequirements(c.Prereqs.Runtime.Requires),
+			map[string]string{"perl": "5.008001",
+				"Moo": ">= 2, < 3", "Carp": "0"}},
+		{"recommends", tRequirements(c.Prereqs.Runtime.Recommends),
+			map[string]string{"JSON::XS": "2.0"}},
+		{"suggests", tRequirements(c.Prereqs.Runtime.Suggests),
+			map[string]string{"YAML": "0"}},
+		{"conflicts", tRequirements(c.Prereqs.Runtime.Conflicts),
+			map[string]string{"Foo::Old": "< 1.5"}},
+		{"configure_requires", tRequirements(
+			c.Prereqs.Configure.Requires),
+			map[string]string{"ExtUtils::MakeMaker": "6.30"}},
+		{"build_requires", tRequirements(c.Prereqs.Build.Requires),
+			map[string]string{"Module::Build": "0"}},
+		{"test requires", tRequirements(c.Prereqs.Test.Requires),
+			map[string]string{"Test::More": "0.88",
+				"Test::Deep": "0"}},
+		{"test recommends", tRequirements(c.Prereqs.Test.Recommends),
+			map[string]string{"Test::Differences": "0.6"}},
+		{"author_requires", tRequirements(c.Prereqs.Develop.Requires),
+			map[string]string{"Test::Pod": "0"}},
+		{"develop suggests", tRequirements(
+			c.Prereqs.Develop.Suggests),
+			map[string]string{"Perl::Critic": "0"}},
+		{"features", len(c.OptionalFeatures), 2},
+		{"feature description", sqlite.Description, "SQLite support"},
+		{"feature requires", tRequirements(
+			sqlite.Prereqs.Runtime.Requires),
+			map[string]string{"DBD::SQLite": "1.40"}},
+		{"feature on", tRequirements(sqlite.Prereqs.Test.Requires),
+			map[string]string{"Test::SQLite": "0"}},
+		{"feature without description", postgres.Description, ""},
+		{"feature on one line", tRequirements(
+			postgres.Prereqs.Runtime.Requires),
+			map[string]string{"DBD::Pg": "0"}},
+	} {
+		if !reflect.DeepEqual(test.got, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name,
+				test.expected, test.got)
+		}
+	}
+}
+
+func TestParseCpanfile_errors(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		input string
+		line  int
+	}{
+		{"requires 'Foo'\nrequires 'Bar';\n", 2},
+		{"requires 'Foo', '1', '2';\n", 1},
+		{"requires;\n", 1},
+		{"\nrequires 'Foo', 'not a version';\n", 2},
+		{"requires 'Foo\n\n", 1},
+		{"use strict;\n", 1},
+		{"my $x = 1;\n", 1},
+		{"frobnicate 'Foo';\n", 1},
+		{"on runtime => sub {\n  requires 'Foo';\n", 3},
+		{"on install => sub {};\n", 1},
+		{"on test => sub {\n  on build => sub {};\n};\n", 2},
+		{"on test => sub {\n  test_requires 'Foo';\n};\n", 2},
+		{"on test;\n", 1},
+		{"feature 'a' => sub {};\nfeature 'a' => sub {};\n", 2},
+		{"feature 'a' => sub {\n  feature 'b' => sub {};\n};\n", 2},
+		{"feature 'a';\n", 1},
+		{"on test => sub {\n  mirror 'http://a';\n};\n", 2},
+		{"mirror 'a', 'b';\n", 1},
+		{"requires 'Foo';\n}\n", 2},
+		{"requires('Foo';\n", 1},
+	} {
+		_, err := ParseCpanfile(strings.NewReader(test.input))
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a SyntaxError, got %v",
+				test.input, err)
+		} else if se.Line != test.line {
+			t.Errorf("%q: expected an error on line %d, got %v",
+				test.input, test.line, err)
+		}
+	}
+}
+
+func TestCpanfile_WriteTo(t *testing.T) {
+	t.Parallel()
+	c, err := ParseCpanfile(strings.NewReader(tCpanfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := c.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buf.Len(), n)
+	}
+	const expected = `mirror 'https://cpan.example.com/';
+
+requires 'Carp';
+requires 'Moo', '>= 2, < 3';
+requires 'perl', '5.008001';
+recommends 'JSON::XS', '2.0';
+suggests 'YAML';
+conflicts 'Foo::Old', '< 1.5';
+
+on configure => sub {
+    requires 'ExtUtils::MakeMaker', '6.30';
+};
+
+on build => sub {
+    requires 'Module::Build';
+};
+
+on test => sub {
+    requires 'Test::Deep';
+    requires 'Test::More', '0.88';
+    recommends 'Test::Differences', '0.6';
+};
+
+on develop => sub {
+    requires 'Test::Pod';
+    suggests 'Perl::Critic';
+};
+
+feature 'postgres' => sub {
+    requires 'DBD::Pg';
+};
+
+feature 'sqlite', 'SQLite support' => sub {
+    requires 'DBD::SQLite', '1.40';
+
+    on test => sub {
+        requires 'Test::SQLite';
+    };
+};
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	// parsing what was written gives back what was parsed
+	got, err := ParseCpanfile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tJSON(t, got) != tJSON(t, c) {
+		t.Errorf("round trip: expected %s, got %s", tJSON(t, c),
+			tJSON(t, got))
+	}
+}
+
+func TestWriteCpanfile(t *testing.T) {
+	t.Parallel()
+	c, err := ParseCpanfile(strings.NewReader(
+		"requires 'Foo', 'v1.2.3';\n" +
+			"requires 'It\\'s', '1';\n" +
+			"on test => sub { requires 'Bar' };\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = WriteCpanfile(&buf, &c.Prereqs); err != nil {
+		t.Fatal(err)
+	}
+	const expected = "requires 'Foo', 'v1.2.3';\n" +
+		"requires 'It\\'s', '1';\n" +
+		"\n" +
+		"on test => sub {\n" +
+		"    requires 'Bar';\n" +
+		"};\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+// tJSON returns v encoded as JSON, for comparing values that may have been
+// built differently.
+func tJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
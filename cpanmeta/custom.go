@@ -0,0 +1,154 @@
+package cpanmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Custom holds the custom fields of a map in the metadata, whose keys start
+// with "x_" or "X_". CPAN::Meta::Spec allows them anywhere, and they're kept
+// so that metadata round-trips. Values are as decoded by encoding/json, with
+// numbers as json.Number.
+type Custom map[string]interface{}
+
+// WriteMetaJSON writes s to w as a META.json file, formatted as CPAN::Meta
+// writes it: keys sorted, indented by three spaces, with a space either side
+// of each colon.
+func WriteMetaJSON(w io.Writer, s *Spec) error {
+	data, err := marshalJSON(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(prettyJSON(data))
+	return err
+}
+
+// isCustomKey reports whether k is the key of a custom field.
+func isCustomKey(k string) bool {
+	return strings.HasPrefix(k, "x_") || strings.HasPrefix(k, "X_")
+}
+
+// unmarshalCustom returns the custom fields of the JSON object in data, or
+// nil if there are none.
+func unmarshalCustom(data []byte) (Custom, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var c Custom
+	for k, raw := range fields {
+		if !isCustomKey(k) {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if c == nil {
+			c = make(Custom)
+		}
+		c[k] = v
+	}
+	return c, nil
+}
+
+// object returns a new JSON object holding the custom fields, for the
+// other fields to be added to.
+func (c Custom) object() map[string]interface{} {
+	obj := make(map[string]interface{}, len(c))
+	for k, v := range c {
+		obj[k] = v
+	}
+	return obj
+}
+
+// setJSON sets obj[key] to v, unless v marshals to an empty value.
+func setJSON(obj map[string]interface{}, key string, v interface{}) error {
+	data, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	switch string(data) {
+	case `""`, "{}", "[]", "null":
+		return nil
+	}
+	obj[key] = json.RawMessage(data)
+	return nil
+}
+
+// marshalJSON marshals v without escaping HTML characters, which ranges
+// such as ">= 1.0" are full of.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// parseRequirement parses the version range of a prerequisite, keeping the
+// string it was written as.
+func parseRequirement(s string) (version.RangeJSON, error) {
+	var r version.RangeJSON
+	data, err := marshalJSON(strings.TrimSpace(s))
+	if err != nil {
+		return r, err
+	}
+	err = r.UnmarshalJSON(data)
+	return r, err
+}
+
+// prettyJSON reformats compact JSON as JSON::PP's pretty option does.
+func prettyJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	depth := 0
+	newline := func() {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat("   ", depth))
+	}
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"':
+			j := i + 1
+			for ; data[j] != '"'; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			buf.Write(data[i : j+1])
+			i = j
+		case '{', '[':
+			buf.WriteByte(c)
+			if i+1 < len(data) && (data[i+1] == '}' ||
+				data[i+1] == ']') {
+				buf.WriteByte(data[i+1])
+				i++
+				continue
+			}
+			depth++
+			newline()
+		case '}', ']':
+			depth--
+			newline()
+			buf.WriteByte(c)
+		case ',':
+			buf.WriteByte(c)
+			newline()
+		case ':':
+			buf.WriteString(" : ")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
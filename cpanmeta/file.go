@@ -1,6 +1,11 @@
 package cpanmeta
 
-import "github.com/cmburn/perlutils/version"
+import (
+	"encoding/json"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
 
 type File struct {
 	// File is the name of the file.
@@ -8,4 +13,26 @@ type File struct {
 
 	// Version is the version of the file.
 	Version version.JSON `json:"version"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+func (f File) MarshalJSON() ([]byte, error) {
+	obj := f.Custom.object()
+	obj["file"] = f.File
+	if err := setJSON(obj, "version", f.Version); err != nil {
+		return nil, err
+	}
+	return marshalJSON(obj)
+}
+
+func (f *File) UnmarshalJSON(data []byte) error {
+	type file File
+	if err := json.Unmarshal(data, (*file)(f)); err != nil {
+		return err
+	}
+	var err error
+	f.Custom, err = unmarshalCustom(data)
+	return err
 }
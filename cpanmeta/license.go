@@ -168,8 +168,8 @@ const (
 	LicenseZlib
 )
 
-func (l *License) String() string {
-	switch *l {
+func (l License) String() string {
+	switch l {
 	case LicenseOpenSource:
 		return "open_source"
 	case LicenseRestricted:
@@ -303,6 +303,6 @@ func (l *License) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (l *License) MarshalJSON() ([]byte, error) {
+func (l License) MarshalJSON() ([]byte, error) {
 	return internal.WrapEnumTypeJSON(l)
 }
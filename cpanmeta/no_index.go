@@ -1,5 +1,7 @@
 package cpanmeta
 
+import "encoding/json"
+
 type NoIndex struct {
 	// File is a list of files that should not be indexed.
 	File []string `json:"file"`
@@ -12,4 +14,35 @@ type NoIndex struct {
 
 	// Namespace is a list of namespaces that should not be indexed.
 	Namespace []string `json:"namespace"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+func (n NoIndex) MarshalJSON() ([]byte, error) {
+	obj := n.Custom.object()
+	for _, f := range []struct {
+		key   string
+		value []string
+	}{
+		{"file", n.File},
+		{"directory", n.Directory},
+		{"package", n.Package},
+		{"namespace", n.Namespace},
+	} {
+		if err := setJSON(obj, f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
+}
+
+func (n *NoIndex) UnmarshalJSON(data []byte) error {
+	type noIndex NoIndex
+	if err := json.Unmarshal(data, (*noIndex)(n)); err != nil {
+		return err
+	}
+	var err error
+	n.Custom, err = unmarshalCustom(data)
+	return err
 }
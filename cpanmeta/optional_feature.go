@@ -1,9 +1,35 @@
 package cpanmeta
 
+import "encoding/json"
+
 type OptionalFeature struct {
 	// Description is a description of the feature.
 	Description string `json:"description"`
 
 	// Prereqs is a list of prerequisites for the feature.
 	Prereqs Prereqs `json:"prereqs"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+func (o OptionalFeature) MarshalJSON() ([]byte, error) {
+	obj := o.Custom.object()
+	if err := setJSON(obj, "description", o.Description); err != nil {
+		return nil, err
+	}
+	if err := setJSON(obj, "prereqs", o.Prereqs); err != nil {
+		return nil, err
+	}
+	return marshalJSON(obj)
+}
+
+func (o *OptionalFeature) UnmarshalJSON(data []byte) error {
+	type optionalFeature OptionalFeature
+	if err := json.Unmarshal(data, (*optionalFeature)(o)); err != nil {
+		return err
+	}
+	var err error
+	o.Custom, err = unmarshalCustom(data)
+	return err
 }
@@ -1,17 +1,92 @@
 package cpanmeta
 
-import "github.com/cmburn/perlutils/version"
+import (
+	"encoding/json"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
 
 type Phase struct {
 	// Conflicts is a list of modules that conflict with this phase.
-	Conflicts map[string]version.JSON `json:"conflicts"`
+	Conflicts map[string]version.RangeJSON `json:"conflicts"`
 
 	// Recommends is a list of modules that are recommended for this phase.
-	Recommends map[string]version.JSON `json:"recommends"`
+	Recommends map[string]version.RangeJSON `json:"recommends"`
 
 	// Requires is a list of modules that are required for this phase.
-	Requires map[string]version.JSON `json:"requires"`
+	Requires map[string]version.RangeJSON `json:"requires"`
 
 	// Suggests is a list of modules that are suggested for this phase.
-	Suggests map[string]version.JSON `json:"suggests"`
+	Suggests map[string]version.RangeJSON `json:"suggests"`
+
+	// Custom contains any custom relationships.
+	Custom Custom `json:"-"`
+}
+
+func (p Phase) MarshalJSON() ([]byte, error) {
+	obj := p.Custom.object()
+	for _, rel := range []struct {
+		key  string
+		reqs map[string]version.RangeJSON
+	}{
+		{"conflicts", p.Conflicts},
+		{"recommends", p.Recommends},
+		{"requires", p.Requires},
+		{"suggests", p.Suggests},
+	} {
+		if err := setJSON(obj, rel.key, rel.reqs); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
+}
+
+func (p *Phase) UnmarshalJSON(data []byte) error {
+	type phase Phase
+	if err := json.Unmarshal(data, (*phase)(p)); err != nil {
+		return err
+	}
+	var err error
+	p.Custom, err = unmarshalCustom(data)
+	return err
 }
+
+// merge merges the requirements in from into p, as Prereqs.Merge does.
+func (p *Phase) merge(from *Phase) error {
+	for _, name := range relationshipNames {
+		err := mergeRequirements(phaseRelationship(p, name),
+			*phaseRelationship(from, name))
+		if err != nil {
+			return err
+		}
+	}
+	for k, v := range from.Custom {
+		if _, ok := p.Custom[k]; ok {
+			continue
+		}
+		if p.Custom == nil {
+			p.Custom = make(Custom)
+		}
+		p.Custom[k] = v
+	}
+	return nil
+}
+
+func phaseRelationship(ph *Phase,
+	name string) *map[string]version.RangeJSON {
+	switch name {
+	case "requires":
+		return &ph.Requires
+	case "recommends":
+		return &ph.Recommends
+	case "suggests":
+		return &ph.Suggests
+	case "conflicts":
+		return &ph.Conflicts
+	}
+	return nil
+}
+
+var relationshipNames = []string{"requires", "recommends", "suggests",
+	"conflicts"}
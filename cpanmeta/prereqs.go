@@ -1,9 +1,182 @@
 package cpanmeta
 
+import (
+	"encoding/json"
+	"fmt"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Prereqs contains a distribution's prerequisites, by phase.
 type Prereqs struct {
 	Configure Phase `json:"configure"`
 	Runtime   Phase `json:"runtime"`
 	Build     Phase `json:"build"`
 	Test      Phase `json:"test"`
 	Develop   Phase `json:"develop"`
+
+	// Custom contains any custom phases.
+	Custom Custom `json:"-"`
+}
+
+func (p Prereqs) MarshalJSON() ([]byte, error) {
+	obj := p.Custom.object()
+	for _, ph := range []struct {
+		key   string
+		phase Phase
+	}{
+		{"configure", p.Configure},
+		{"runtime", p.Runtime},
+		{"build", p.Build},
+		{"test", p.Test},
+		{"develop", p.Develop},
+	} {
+		if err := setJSON(obj, ph.key, ph.phase); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
+}
+
+func (p *Prereqs) UnmarshalJSON(data []byte) error {
+	type prereqs Prereqs
+	if err := json.Unmarshal(data, (*prereqs)(p)); err != nil {
+		return err
+	}
+	var err error
+	p.Custom, err = unmarshalCustom(data)
+	return err
+}
+
+// Merge returns the prerequisites in either p or other, as CPAN::Meta's
+// with_merged_prereqs does. Where both have a requirement on a module, the
+// merged requirement is for versions in both ranges, and it's a
+// *ConflictError if there aren't any. Custom phases are kept from both,
+// with p's taking precedence.
+func (p *Prereqs) Merge(other *Prereqs) (*Prereqs, error) {
+	merged := &Prereqs{}
+	for _, name := range phaseNames {
+		ph := prereqsPhase(merged, name)
+		for _, from := range []*Prereqs{p, other} {
+			err := ph.merge(prereqsPhase(from, name))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, c := range []Custom{other.Custom, p.Custom} {
+		for k, v := range c {
+			if merged.Custom == nil {
+				merged.Custom = make(Custom)
+			}
+			merged.Custom[k] = v
+		}
+	}
+	return merged, nil
 }
+
+// Requirements returns the requirements in the given phases and
+// relationships, merged as Merge does, as CPAN::Meta's merged_requirements
+// does. As in CPAN::Meta::Spec, a bare version in a range is a minimum.
+func (p *Prereqs) Requirements(phases, relationships []string) (
+	map[string]*version.Range, error) {
+	var merged Phase
+	for _, name := range phases {
+		ph := prereqsPhase(p, name)
+		if ph == nil {
+			return nil, fmt.Errorf("unknown phase: %s", name)
+		}
+		for _, rel := range relationships {
+			reqs := phaseRelationship(ph, rel)
+			if reqs == nil {
+				return nil, fmt.Errorf(
+					"unknown relationship: %s", rel)
+			}
+			if err := mergeRequirements(&merged.Requires,
+				*reqs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ranges := make(map[string]*version.Range, len(merged.Requires))
+	for module, req := range merged.Requires {
+		ranges[module] = &req.Range
+	}
+	return ranges, nil
+}
+
+// ConflictError is returned when the requirements on a module can't all be
+// met by any one version.
+type ConflictError struct {
+	// Module is the module required.
+	Module string
+
+	// Requirements are the requirements that contradict each other.
+	Requirements []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting requirements for %s: %q", e.Module,
+		e.Requirements)
+}
+
+func prereqsPhase(p *Prereqs, name string) *Phase {
+	switch name {
+	case "configure":
+		return &p.Configure
+	case "build":
+		return &p.Build
+	case "test":
+		return &p.Test
+	case "runtime":
+		return &p.Runtime
+	case "develop":
+		return &p.Develop
+	}
+	return nil
+}
+
+// mergeRequirements merges the requirements in from into those in into,
+// creating it if need be.
+func mergeRequirements(into *map[string]version.RangeJSON,
+	from map[string]version.RangeJSON) error {
+	for module, req := range from {
+		if *into == nil {
+			*into = make(map[string]version.RangeJSON)
+		}
+		existing, ok := (*into)[module]
+		if !ok {
+			(*into)[module] = req
+			continue
+		}
+		merged, err := mergeRequirement(module, existing, req)
+		if err != nil {
+			return err
+		}
+		(*into)[module] = merged
+	}
+	return nil
+}
+
+// mergeRequirement returns the requirement on module for versions that
+// meet both a and b. If either already allows only versions the other
+// does, it's returned as it's written.
+func mergeRequirement(module string, a,
+	b version.RangeJSON) (version.RangeJSON, error) {
+	merged := a.Intersect(&b.Range)
+	switch {
+	case merged.IsEmpty():
+		return version.RangeJSON{}, &ConflictError{
+			Module:       module,
+			Requirements: []string{a.Raw(), b.Raw()},
+		}
+	case b.Subsumes(&a.Range):
+		return a, nil
+	case a.Subsumes(&b.Range):
+		return b, nil
+	}
+	return parseRequirement(merged.Simplify().String())
+}
+
+var phaseNames = []string{"configure", "build", "test", "runtime", "develop"}
@@ -0,0 +1,146 @@
+package cpanmeta
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+const tPrereqsSpec = `{
+	"name": "Foo",
+	"prereqs": {
+		"runtime": {
+			"requires": {"Moo": "2", "Carp": "0", "perl": "5.008"},
+			"recommends": {"Moo": "< 3"}
+		},
+		"test": {
+			"requires": {"Test::More": "0.88", "Moo": ">= 2.1"}
+		},
+		"x_custom": {"requires": {"Foo": "1"}}
+	},
+	"optional_features": {
+		"xs": {
+			"prereqs": {
+				"runtime": {"requires": {"Moo": ">= 2, != 2.5"}}
+			}
+		},
+		"old": {
+			"prereqs": {
+				"runtime": {"requires": {"Moo": "< 1"}}
+			}
+		}
+	}
+}`
+
+func TestPrereqs_Merge(t *testing.T) {
+	t.Parallel()
+	var s Spec
+	if err := json.Unmarshal([]byte(tPrereqsSpec), &s); err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.EffectivePrereqs("xs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requires := p.Runtime.Requires
+	if got := requires["Moo"].Raw(); got != ">= 2, != 2.5" {
+		t.Errorf("unexpected requirement on Moo %q", got)
+	}
+	if got := requires["Carp"].Raw(); got != "0" {
+		t.Errorf("unexpected requirement on Carp %q", got)
+	}
+	if p.Custom["x_custom"] == nil {
+		t.Errorf("expected custom phases to be kept, got %v", p.Custom)
+	}
+	if s.Prereqs.Runtime.Requires["Moo"].Raw() != "2" {
+		t.Error("expected the spec's prereqs to be left alone")
+	}
+	_, err = s.EffectivePrereqs("xs", "old")
+	var ce *ConflictError
+	if !errors.As(err, &ce) || ce.Module != "Moo" {
+		t.Errorf("expected a ConflictError for Moo, got %v", err)
+	}
+	if _, err = s.EffectivePrereqs("none"); err == nil {
+		t.Error("expected an error for an unknown feature")
+	}
+}
+
+func TestPrereqs_Requirements(t *testing.T) {
+	t.Parallel()
+	var s Spec
+	if err := json.Unmarshal([]byte(tPrereqsSpec), &s); err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := s.Prereqs.Requirements([]string{"runtime", "test"},
+		[]string{"requires", "recommends"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 4 {
+		t.Errorf("expected 4 requirements, got %v", reqs)
+	}
+	for _, test := range []struct {
+		module, version string
+		in              bool
+	}{
+		{"Moo", "2.0", false},
+		{"Moo", "2.1", true},
+		{"Moo", "3", false},
+		{"Carp", "0", true},
+		{"perl", "5.010", true},
+		{"perl", "5.006", false},
+	} {
+		v := version.MustParse(test.version)
+		if reqs[test.module].Contains(&v) != test.in {
+			t.Errorf("%s %s: expected Contains to be %v",
+				test.module, test.version, test.in)
+		}
+	}
+	_, err = s.Prereqs.Requirements([]string{"runtime"},
+		[]string{"requires", "x_foo"})
+	if err == nil {
+		t.Error("expected an error for an unknown relationship")
+	}
+}
+
+func TestPrereqs_mergeRequirement(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		a, b, expected string
+	}{
+		{"0", "1.5", "1.5"},
+		{"1.5", "0", "1.5"},
+		{"2", ">= 2.1", ">= 2.1"},
+		{"== 1.5", "1", "== 1.5"},
+		{"2", "< 3", ">=2, <3"},
+		{"> 1", "!= 2, < 3", ">1, <3, !=2"},
+		{"< 1", "2", ""},
+	} {
+		a, err := parseRequirement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parseRequirement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged, err := mergeRequirement("Foo", a, b)
+		var ce *ConflictError
+		if test.expected == "" {
+			if !errors.As(err, &ce) {
+				t.Errorf("%q & %q: expected a ConflictError, "+
+					"got %v", test.a, test.b, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q & %q: %v", test.a, test.b, err)
+		} else if merged.Raw() != test.expected {
+			t.Errorf("%q & %q => %q, expected %q", test.a, test.b,
+				merged.Raw(), test.expected)
+		}
+	}
+}
@@ -15,8 +15,8 @@ const (
 	ReleaseStatusUnstable
 )
 
-func (r *ReleaseStatus) String() string {
-	switch *r {
+func (r ReleaseStatus) String() string {
+	switch r {
 	case ReleaseStatusStable:
 		return "stable"
 	case ReleaseStatusTesting:
@@ -42,7 +42,7 @@ func NewReleaseStatus(str string) (ReleaseStatus, error) {
 	}
 }
 
-func (r *ReleaseStatus) MarshalJSON() ([]byte, error) {
+func (r ReleaseStatus) MarshalJSON() ([]byte, error) {
 	return internal.WrapEnumTypeJSON(r)
 }
 
@@ -1,5 +1,7 @@
 package cpanmeta
 
+import "encoding/json"
+
 type Resources struct {
 	// License is a list of URLs to the license for the distribution.
 	License []string `json:"license"`
@@ -9,24 +11,115 @@ type Resources struct {
 
 	// BugTracker contains information about the bug tracker for the
 	// distribution.
-	BugTracker struct {
-		// Web is a URL to the web interface for the bug tracker.
-		Web string `json:"web"`
-
-		// Mailto is an email address to which bug reports should be
-		// sent.
-		MailTo string `json:"mailto"`
-	} `json:"bugtracker"`
+	BugTracker BugTracker `json:"bugtracker"`
 
 	// Repository is a URL to the repository for the distribution.
-	Repository struct {
-		// Type is the type of repository.
-		Type string `json:"type"`
+	Repository Repository `json:"repository"`
+
+	// Custom contains any custom resources.
+	Custom Custom `json:"-"`
+}
+
+// BugTracker contains information about a distribution's bug tracker.
+type BugTracker struct {
+	// Web is a URL to the web interface for the bug tracker.
+	Web string `json:"web"`
+
+	// Mailto is an email address to which bug reports should be
+	// sent.
+	MailTo string `json:"mailto"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+// Repository contains information about a distribution's repository.
+type Repository struct {
+	// Type is the type of repository.
+	Type string `json:"type"`
+
+	// URL is the URL of the repository.
+	URL string `json:"url"`
+
+	// Web is a URL to the web interface for the repository.
+	Web string `json:"web"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+func (r Resources) MarshalJSON() ([]byte, error) {
+	obj := r.Custom.object()
+	for _, f := range []struct {
+		key   string
+		value interface{}
+	}{
+		{"license", r.License},
+		{"homepage", r.Homepage},
+		{"bugtracker", r.BugTracker},
+		{"repository", r.Repository},
+	} {
+		if err := setJSON(obj, f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
+}
+
+func (r *Resources) UnmarshalJSON(data []byte) error {
+	type resources Resources
+	if err := json.Unmarshal(data, (*resources)(r)); err != nil {
+		return err
+	}
+	var err error
+	r.Custom, err = unmarshalCustom(data)
+	return err
+}
+
+func (b BugTracker) MarshalJSON() ([]byte, error) {
+	obj := b.Custom.object()
+	if err := setJSON(obj, "web", b.Web); err != nil {
+		return nil, err
+	}
+	if err := setJSON(obj, "mailto", b.MailTo); err != nil {
+		return nil, err
+	}
+	return marshalJSON(obj)
+}
 
-		// URL is the URL of the repository.
-		URL string `json:"url"`
+func (b *BugTracker) UnmarshalJSON(data []byte) error {
+	type bugTracker BugTracker
+	if err := json.Unmarshal(data, (*bugTracker)(b)); err != nil {
+		return err
+	}
+	var err error
+	b.Custom, err = unmarshalCustom(data)
+	return err
+}
+
+func (r Repository) MarshalJSON() ([]byte, error) {
+	obj := r.Custom.object()
+	for _, f := range []struct {
+		key   string
+		value string
+	}{
+		{"type", r.Type},
+		{"url", r.URL},
+		{"web", r.Web},
+	} {
+		if err := setJSON(obj, f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
+}
 
-		// Web is a URL to the web interface for the repository.
-		Web string `json:"web"`
+func (r *Repository) UnmarshalJSON(data []byte) error {
+	type repository Repository
+	if err := json.Unmarshal(data, (*repository)(r)); err != nil {
+		return err
 	}
+	var err error
+	r.Custom, err = unmarshalCustom(data)
+	return err
 }
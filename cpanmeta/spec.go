@@ -2,6 +2,7 @@ package cpanmeta
 
 import (
 	"encoding/json"
+	"fmt"
 
 	// local
 	"github.com/cmburn/perlutils/internal"
@@ -9,6 +10,10 @@ import (
 )
 
 type Spec struct {
+	// Abstract is a short description of the purpose of the
+	// distribution.
+	Abstract string `json:"abstract"`
+
 	// Author is a list of the distribution's authors/maintainers.
 	//
 	// This takes the form of a list of strings in the form "NAME <EMAIL>".
@@ -63,10 +68,56 @@ type Spec struct {
 
 	// Resources contains info on resources related to the distribution.
 	Resources Resources `json:"resources"`
+
+	// Custom contains any custom fields.
+	Custom Custom `json:"-"`
+}
+
+// MarshalJSON marshals s as CPAN::Meta would: dynamic_config is 0 or 1, the
+// license defaults to unknown, optional fields are left out if they're
+// empty, and custom fields are kept.
+func (s Spec) MarshalJSON() ([]byte, error) {
+	obj := s.Custom.object()
+	obj["abstract"] = s.Abstract
+	obj["author"] = s.Author
+	if s.Author == nil {
+		obj["author"] = []string{}
+	}
+	obj["dynamic_config"] = 0
+	if s.DynamicConfig {
+		obj["dynamic_config"] = 1
+	}
+	obj["generated_by"] = s.GeneratedBy
+	obj["license"] = s.License
+	if len(s.License) == 0 {
+		obj["license"] = []License{LicenseUnknown}
+	}
+	obj["meta-spec"] = s.MetaSpec
+	obj["name"] = s.Name
+	obj["release_status"] = s.ReleaseStatus
+	obj["version"] = s.Version
+	for _, f := range []struct {
+		key   string
+		value interface{}
+	}{
+		{"description", s.Description},
+		{"keywords", s.Keywords},
+		{"no_index", s.NoIndex},
+		{"optional_features", s.OptionalFeatures},
+		{"prereqs", s.Prereqs},
+		{"provides", s.Provides},
+		{"resources", s.Resources},
+	} {
+		if err := setJSON(obj, f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+	return marshalJSON(obj)
 }
 
 func (s *Spec) UnmarshalJSON(data []byte) error {
 	var v struct {
+		Abstract         string                     `json:"abstract"`
 		Author           []string                   `json:"author"`
 		DynamicConfig    interface{}                `json:"dynamic_config"`
 		GeneratedBy      string                     `json:"generated_by"`
@@ -90,6 +141,7 @@ func (s *Spec) UnmarshalJSON(data []byte) error {
 		v.DynamicConfig); err != nil {
 		return err
 	}
+	s.Abstract = v.Abstract
 	s.Author = v.Author
 	s.GeneratedBy = v.GeneratedBy
 	s.License = v.License
@@ -104,5 +156,27 @@ func (s *Spec) UnmarshalJSON(data []byte) error {
 	s.Prereqs = v.Prereqs
 	s.Provides = v.Provides
 	s.Resources = v.Resources
-	return nil
+	var err error
+	s.Custom, err = unmarshalCustom(data)
+	return err
+}
+
+// EffectivePrereqs returns the prerequisites of s, with those of the named
+// optional features merged in, as CPAN::Meta's effective_prereqs does.
+func (s *Spec) EffectivePrereqs(features ...string) (*Prereqs, error) {
+	p, err := s.Prereqs.Merge(&Prereqs{})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range features {
+		f, ok := s.OptionalFeatures[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown optional feature: %s",
+				name)
+		}
+		if p, err = p.Merge(&f.Prereqs); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
 }
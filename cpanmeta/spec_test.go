@@ -0,0 +1,109 @@
+package cpanmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWriteMetaJSON checks that the META.json of real releases is written
+// back byte for byte. Fetch the files with testdata/cpan/fetch.pl and check
+// them in; without them the test is skipped.
+func TestWriteMetaJSON(t *testing.T) {
+	t.Parallel()
+	paths := tGlob(t, filepath.Join("testdata", "cpan", "*.json"))
+	if len(paths) == 0 {
+		t.Skip("no META.json files in testdata/cpan; fetch them with " +
+			"fetch.pl")
+	}
+	for _, path := range paths {
+		tRoundTrip(t, path)
+	}
+}
+
+// TestWriteMetaJSON_synthetic does the same for made-up META.json files:
+// synthetic-edge-cases.json holds the unusual things the spec allows, such
+// as custom phases and nested custom values.
+func TestWriteMetaJSON_synthetic(t *testing.T) {
+	t.Parallel()
+	paths := tGlob(t, filepath.Join("testdata", "synthetic-*.json"))
+	if len(paths) == 0 {
+		t.Fatal("no synthetic golden files found")
+	}
+	for _, path := range paths {
+		tRoundTrip(t, path)
+	}
+}
+
+func TestSpec_MarshalJSON(t *testing.T) {
+	t.Parallel()
+	var s Spec
+	err := json.Unmarshal([]byte(`{"name":"Foo","version":"1.0",
+		"meta-spec":{"version":2},"prereqs":{"runtime":{}},
+		"resources":{},"x_custom":{"a":[1]}}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj map[string]json.RawMessage
+	if err = json.Unmarshal(data, &obj); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"prereqs", "resources", "provides"} {
+		if _, ok := obj[key]; ok {
+			t.Errorf("expected %s to be omitted, got %s", key,
+				obj[key])
+		}
+	}
+	if string(obj["x_custom"]) != `{"a":[1]}` {
+		t.Errorf("unexpected x_custom %s", obj["x_custom"])
+	}
+	if string(obj["license"]) != `["unknown"]` {
+		t.Errorf("unexpected license %s", obj["license"])
+	}
+}
+
+func tGlob(t *testing.T, pattern string) []string {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// tRoundTrip checks that the META.json at path is written back unchanged,
+// and that the Spec survives a trip through json.Marshal.
+func tRoundTrip(t *testing.T, path string) {
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Spec
+	if err = json.Unmarshal(golden, &s); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	var buf bytes.Buffer
+	if err = WriteMetaJSON(&buf, &s); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if buf.String() != string(golden) {
+		t.Errorf("%s: expected\n%s\ngot\n%s", path, golden, buf.String())
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	var s2 Spec
+	if err = json.Unmarshal(data, &s2); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if !reflect.DeepEqual(s, s2) {
+		t.Errorf("%s: spec changed in round trip", path)
+	}
+}
@@ -0,0 +1,24 @@
+#!/usr/bin/env perl
+# Downloads the META.json of each release below from MetaCPAN, for the golden
+# tests in spec_test.go. Run it from this directory with "perl fetch.pl"; it
+# needs IO::Socket::SSL for HTTP::Tiny to speak https.
+use strict;
+use warnings;
+use HTTP::Tiny;
+
+my $base = 'https://fastapi.metacpan.org/v1/source';
+my $http = HTTP::Tiny->new(agent => 'perlutils-fetch/1.0');
+
+while (my $release = <DATA>) {
+	chomp $release;
+	next if $release eq '';
+	my ($author, $dist) = split m{/}, $release;
+	my $res = $http->mirror("$base/$release/META.json", "$dist.json");
+	die "$release: $res->{status} $res->{reason}\n" unless $res->{success};
+}
+
+__DATA__
+DAGOLDEN/CPAN-Meta-2.150010
+ETHER/Moose-2.2207
+ETHER/Try-Tiny-0.31
+SRI/Mojolicious-9.35
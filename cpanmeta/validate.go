@@ -0,0 +1,619 @@
+package cpanmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Severity is how serious a Problem is.
+type Severity int
+
+const (
+	// SeverityError means the metadata doesn't conform to
+	// CPAN::Meta::Spec.
+	SeverityError Severity = iota
+
+	// SeverityWarning means the metadata conforms to the spec, but does
+	// something the spec advises against.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "undef"
+	}
+}
+
+// Problem is something wrong with metadata, as found by Validate.
+type Problem struct {
+	// Path is a JSON pointer to the field with the problem, or "" if the
+	// problem is with the document as a whole.
+	Path string
+
+	// Severity is how serious the problem is.
+	Severity Severity
+
+	// Message describes the problem.
+	Message string
+
+	// Fixed is whether the problem was fixed, which only happens in
+	// fix-up mode.
+	Fixed bool
+}
+
+func (p Problem) Error() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Path, p.Message)
+}
+
+// ValidateOption configures Validate and ValidateJSON.
+type ValidateOption func(*validator)
+
+// WithFixUp sets whether problems are fixed where they can be, leniently, as
+// CPAN::Meta::Converter does: invalid versions become "0", invalid URLs and
+// prerequisites are dropped, unknown fields are given an x_ prefix, missing
+// required fields are given defaults, and so on. Fixed problems are still
+// returned, with Fixed set.
+func WithFixUp(fixUp bool) ValidateOption {
+	return func(v *validator) {
+		v.fixUp = fixUp
+	}
+}
+
+// Validate checks s against version 2 of CPAN::Meta::Spec, as
+// CPAN::Meta::Validator does, and returns the problems found, or nil if
+// there are none. In fix-up mode, s is updated if every error could be
+// fixed.
+func Validate(s *Spec, opts ...ValidateOption) []Problem {
+	data, err := marshalJSON(s)
+	if err != nil {
+		return []Problem{{
+			Severity: SeverityError,
+			Message:  err.Error(),
+		}}
+	}
+	fixed, problems := ValidateJSON(data, opts...)
+	if fixed == nil {
+		return problems
+	}
+	for _, p := range problems {
+		if p.Fixed {
+			*s = *fixed
+			break
+		}
+	}
+	return problems
+}
+
+// ValidateJSON is like Validate, but checks a META.json document, so it
+// finds the problems that would stop it from being unmarshalled. It returns
+// the Spec if there are no errors, or none left after fixing them.
+func ValidateJSON(data []byte, opts ...ValidateOption) (*Spec, []Problem) {
+	v := &validator{}
+	for _, opt := range opts {
+		opt(v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		v.report("", SeverityError, false, "%s", err)
+		return nil, v.problems
+	}
+	doc, _ = checkSpec(v, "", doc)
+	for _, p := range v.problems {
+		if p.Severity == SeverityError && !p.Fixed {
+			return nil, v.problems
+		}
+	}
+	data, err := marshalJSON(doc)
+	if err != nil {
+		v.report("", SeverityError, false, "%s", err)
+		return nil, v.problems
+	}
+	var s Spec
+	if err = json.Unmarshal(data, &s); err != nil {
+		v.report("", SeverityError, false, "%s", err)
+		return nil, v.problems
+	}
+	return &s, v.problems
+}
+
+type validator struct {
+	fixUp    bool
+	problems []Problem
+}
+
+// report records a problem at path, and reports whether to fix it, which is
+// only if it can be fixed and fix-up mode is on.
+func (v *validator) report(path string, sev Severity, canFix bool,
+	format string, args ...interface{}) bool {
+	fix := canFix && v.fixUp
+	v.problems = append(v.problems, Problem{
+		Path:     path,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+		Fixed:    fix,
+	})
+	return fix
+}
+
+// check checks the value at path, returning what to replace it with, or
+// false if it should be removed.
+type check func(v *validator, path string,
+	value interface{}) (interface{}, bool)
+
+// field is a field of an object checked by object.
+type field struct {
+	check    check
+	required bool
+
+	// fix is the value given to a missing required field, or nil if it
+	// can't be fixed.
+	fix interface{}
+}
+
+// object returns a check for an object with the given fields, as well as
+// custom ones. Unknown fields are fixed by making them custom.
+func object(fields map[string]field) check {
+	return func(v *validator, path string,
+		value interface{}) (interface{}, bool) {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.report(path, SeverityError, false,
+				"expected an object")
+			return value, true
+		}
+		seen := make(map[string]bool, len(obj)+len(fields))
+		var keys []string
+		for k := range fields {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+		for k := range obj {
+			if !seen[k] {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := pointer(path, k)
+			f, known := fields[k]
+			switch {
+			case known && isMissing(obj[k]):
+				if f.required && v.report(p,
+					SeverityError, f.fix != nil,
+					"missing required field") {
+					obj[k] = f.fix
+				}
+			case known:
+				if val, keep := f.check(v, p, obj[k]); keep {
+					obj[k] = val
+				} else {
+					delete(obj, k)
+				}
+			case isCustomKey(k):
+			default:
+				_, taken := obj["x_"+k]
+				if v.report(p, SeverityError, !taken,
+					"unknown field; custom fields must "+
+						"start with x_") {
+					obj["x_"+k] = obj[k]
+					delete(obj, k)
+				}
+			}
+		}
+		return obj, true
+	}
+}
+
+// mapOf returns a check for an object whose keys are accepted by valid,
+// and whose values are checked by elem. Invalid keys are fixed by removing
+// them.
+func mapOf(what string, valid func(string) bool, elem check) check {
+	return func(v *validator, path string,
+		value interface{}) (interface{}, bool) {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.report(path, SeverityError, false,
+				"expected an object")
+			return value, true
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := pointer(path, k)
+			if !valid(k) {
+				if v.report(p, SeverityError, true,
+					"invalid %s %q", what, k) {
+					delete(obj, k)
+				}
+				continue
+			}
+			if val, keep := elem(v, p, obj[k]); keep {
+				obj[k] = val
+			} else {
+				delete(obj, k)
+			}
+		}
+		return obj, true
+	}
+}
+
+// listOf returns a check for a list whose elements are checked by elem. A
+// lone string is fixed by putting it in a list.
+func listOf(elem check) check {
+	return func(v *validator, path string,
+		value interface{}) (interface{}, bool) {
+		list, ok := value.([]interface{})
+		if !ok {
+			_, isString := value.(string)
+			if !v.report(path, SeverityError, isString,
+				"expected a list") {
+				return value, true
+			}
+			list = []interface{}{value}
+		}
+		checked := make([]interface{}, 0, len(list))
+		for i, e := range list {
+			p := fmt.Sprintf("%s/%d", path, i)
+			if e, keep := elem(v, p, e); keep {
+				checked = append(checked, e)
+			}
+		}
+		return checked, true
+	}
+}
+
+// scalar checks a string.
+type scalar struct {
+	// what the string is, for messages
+	what string
+
+	// numeric is whether numbers are allowed too, as they are for
+	// versions.
+	numeric bool
+
+	// valid reports whether a string is valid, or is nil if any string
+	// is.
+	valid func(string) bool
+
+	// fix is what replaces invalid strings, or nil if they can't be
+	// fixed.
+	fix interface{}
+
+	// drop is whether invalid strings are fixed by removing them.
+	drop bool
+}
+
+func (sc scalar) check(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	var s string
+	switch value := value.(type) {
+	case string:
+		s = value
+	case json.Number:
+		s = value.String()
+		if !sc.numeric && !v.report(path, SeverityError, true,
+			"expected a string") {
+			return value, true
+		}
+	default:
+		v.report(path, SeverityError, false, "expected a string")
+		return value, true
+	}
+	if sc.valid == nil || sc.valid(s) {
+		if sc.numeric {
+			return value, true
+		}
+		return s, true
+	}
+	if !v.report(path, SeverityError, sc.drop || sc.fix != nil,
+		"invalid %s %q", sc.what, s) {
+		return value, true
+	}
+	if sc.drop {
+		return nil, false
+	}
+	return sc.fix, true
+}
+
+func checkBool(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	switch value {
+	case true, false, json.Number("0"), json.Number("1"):
+		return value, true
+	case "1", "true":
+		if v.report(path, SeverityError, true, "expected a boolean") {
+			return json.Number("1"), true
+		}
+	case "0", "false":
+		if v.report(path, SeverityError, true, "expected a boolean") {
+			return json.Number("0"), true
+		}
+	default:
+		v.report(path, SeverityError, false, "expected a boolean")
+	}
+	return value, true
+}
+
+// checkName checks a distribution name, which shouldn't contain "::", and
+// has it replaced by "-" as CPAN::Meta::Converter does.
+func checkName(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	value, keep := stringCheck.check(v, path, value)
+	if s, ok := value.(string); ok && strings.Contains(s, "::") &&
+		v.report(path, SeverityWarning, true,
+			"distribution name contains \"::\"") {
+		value = strings.ReplaceAll(s, "::", "-")
+	}
+	return value, keep
+}
+
+// checkFeature checks an optional feature, whose prereqs can't include
+// configure ones.
+func checkFeature(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	value, keep := featureCheck(v, path, value)
+	feature, _ := value.(map[string]interface{})
+	prereqs, _ := feature["prereqs"].(map[string]interface{})
+	if _, ok := prereqs["configure"]; ok &&
+		v.report(pointer(pointer(path, "prereqs"), "configure"),
+			SeverityError, true,
+			"optional features can't have configure prereqs") {
+		delete(prereqs, "configure")
+	}
+	return value, keep
+}
+
+// checkRepository checks a repository, which should have a type if it has
+// a URL.
+func checkRepository(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	value, keep := repositoryCheck(v, path, value)
+	repo, _ := value.(map[string]interface{})
+	if !isMissing(repo["url"]) && isMissing(repo["type"]) {
+		v.report(pointer(path, "type"), SeverityWarning, false,
+			"repository has a URL but no type")
+	}
+	return value, keep
+}
+
+// checkSpec checks a whole document. A version with an underscore is a
+// development release, which can't be stable.
+func checkSpec(v *validator, path string,
+	value interface{}) (interface{}, bool) {
+	value, keep := specCheck(v, path, value)
+	doc, _ := value.(map[string]interface{})
+	ver, _ := doc["version"].(string)
+	if doc["release_status"] == "stable" && strings.Contains(ver, "_") &&
+		v.report(pointer(path, "release_status"), SeverityError, true,
+			"a version with an underscore can't be stable") {
+		doc["release_status"] = "testing"
+	}
+	return value, keep
+}
+
+// isMissing reports whether value is missing or empty, which required
+// fields mustn't be.
+func isMissing(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
+
+// pointer appends key to the JSON pointer path, escaping it.
+func pointer(path, key string) string {
+	return path + "/" + pointerEscaper.Replace(key)
+}
+
+func validModule(s string) bool {
+	return moduleRegexp.MatchString(s)
+}
+
+func validIdentifier(s string) bool {
+	return identifierRegexp.MatchString(s)
+}
+
+func validRange(s string) bool {
+	_, err := version.ParseRange(s)
+	return err == nil
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func validEmail(s string) bool {
+	_, err := mail.ParseAddress(s)
+	return err == nil
+}
+
+func validLicense(s string) bool {
+	_, err := NewLicense(s)
+	return err == nil
+}
+
+func validReleaseStatus(s string) bool {
+	_, err := NewReleaseStatus(s)
+	return err == nil
+}
+
+var (
+	moduleRegexp     = regexp.MustCompile(`^\w+(?:::\w+)*$`)
+	identifierRegexp = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+
+	stringCheck  = scalar{what: "string"}
+	versionCheck = scalar{
+		what:    "version",
+		numeric: true,
+		valid:   version.LooksValid,
+		fix:     "0",
+	}
+	rangeCheck = scalar{
+		what:    "version range",
+		numeric: true,
+		valid:   validRange,
+		fix:     "0",
+	}
+	urlCheck = scalar{what: "URL", valid: validURL, drop: true}
+
+	prereqsCheck = object(func() map[string]field {
+		modules := field{check: mapOf("module name", validModule,
+			rangeCheck.check)}
+		phase := field{check: object(map[string]field{
+			"requires":   modules,
+			"recommends": modules,
+			"suggests":   modules,
+			"conflicts":  modules,
+		})}
+		return map[string]field{
+			"configure": phase,
+			"build":     phase,
+			"test":      phase,
+			"runtime":   phase,
+			"develop":   phase,
+		}
+	}())
+	featureCheck = object(map[string]field{
+		"description": {check: stringCheck.check},
+		"prereqs":     {check: prereqsCheck},
+	})
+	repositoryCheck = object(map[string]field{
+		"type": {check: stringCheck.check},
+		"url":  {check: urlCheck.check},
+		"web":  {check: urlCheck.check},
+	})
+	specCheck = object(map[string]field{
+		"abstract": {
+			check:    stringCheck.check,
+			required: true,
+			fix:      "unknown",
+		},
+		"author": {
+			check:    listOf(stringCheck.check),
+			required: true,
+			fix:      []interface{}{"unknown"},
+		},
+		"dynamic_config": {
+			check:    checkBool,
+			required: true,
+			fix:      json.Number("1"),
+		},
+		"generated_by": {
+			check:    stringCheck.check,
+			required: true,
+			fix:      "github.com/cmburn/perlutils/cpanmeta",
+		},
+		"license": {
+			check: listOf(scalar{
+				what:  "license",
+				valid: validLicense,
+				fix:   "unknown",
+			}.check),
+			required: true,
+			fix:      []interface{}{"unknown"},
+		},
+		"meta-spec": {
+			check: object(map[string]field{
+				"version": {
+					check: scalar{
+						what:    "meta-spec version",
+						numeric: true,
+						valid: func(s string) bool {
+							return s == "2"
+						},
+					}.check,
+					required: true,
+					fix:      json.Number("2"),
+				},
+				"url": {check: urlCheck.check},
+			}),
+			required: true,
+			fix: map[string]interface{}{
+				"version": json.Number("2"),
+				"url":     specURL2,
+			},
+		},
+		"name": {check: checkName, required: true},
+		"release_status": {
+			check: scalar{
+				what:  "release status",
+				valid: validReleaseStatus,
+				fix:   "stable",
+			}.check,
+			required: true,
+			fix:      "stable",
+		},
+		"version": {
+			check:    versionCheck.check,
+			required: true,
+			fix:      "0",
+		},
+		"description": {check: stringCheck.check},
+		"keywords":    {check: listOf(stringCheck.check)},
+		"no_index": {check: object(func() map[string]field {
+			names := field{check: listOf(stringCheck.check)}
+			return map[string]field{
+				"file":      names,
+				"directory": names,
+				"package":   names,
+				"namespace": names,
+			}
+		}())},
+		"optional_features": {
+			check: mapOf("feature name", validIdentifier,
+				checkFeature),
+		},
+		"prereqs": {check: prereqsCheck},
+		"provides": {check: mapOf("package name", validModule,
+			object(map[string]field{
+				"file": {
+					check:    stringCheck.check,
+					required: true,
+				},
+				"version": {check: versionCheck.check},
+			}))},
+		"resources": {check: object(map[string]field{
+			"license":  {check: listOf(urlCheck.check)},
+			"homepage": {check: urlCheck.check},
+			"bugtracker": {check: object(map[string]field{
+				"web": {check: urlCheck.check},
+				"mailto": {check: scalar{
+					what:  "email address",
+					valid: validEmail,
+					drop:  true,
+				}.check},
+			})},
+			"repository": {check: checkRepository},
+		})},
+	})
+)
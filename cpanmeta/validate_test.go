@@ -0,0 +1,178 @@
+package cpanmeta
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const tInvalidMeta = `{
+	"abstract": "",
+	"author": "Jane Doe <jane@example.com>",
+	"dynamic_config": "1",
+	"license": ["perl_5", "wtfpl"],
+	"meta-spec": {"version": 2},
+	"name": "Foo::Bar",
+	"release_status": "stable",
+	"version": "1.0_01",
+	"provides": {"Foo::Bar": {"version": "1.0_01"}},
+	"prereqs": {
+		"runtime": {
+			"requires": {
+				"Moo": ">= 2, < 3",
+				"Bad Module": "0",
+				"Carp": "latest"
+			}
+		},
+		"x_phase": {}
+	},
+	"optional_features": {
+		"xs": {"prereqs": {"configure": {}}}
+	},
+	"resources": {
+		"homepage": "not a url",
+		"bugtracker": {
+			"mailto": "nobody",
+			"web": "https://example.com"
+		},
+		"repository": {"url": "https://example.com/foo.git"},
+		"irc": "irc://irc.perl.org/#foo"
+	},
+	"x~y/z": 1,
+	"extra": true
+}`
+
+var tInvalidProblems = []string{
+	"error: /abstract: missing required field",
+	"error: /author: expected a list",
+	"error: /dynamic_config: expected a boolean",
+	"error: /extra: unknown field; custom fields must start with x_",
+	"error: /generated_by: missing required field",
+	`error: /license/1: invalid license "wtfpl"`,
+	"warning: /name: distribution name contains \"::\"",
+	"error: /optional_features/xs/prereqs/configure: optional " +
+		"features can't have configure prereqs",
+	`error: /prereqs/runtime/requires/Bad Module: invalid module ` +
+		`name "Bad Module"`,
+	`error: /prereqs/runtime/requires/Carp: invalid version range ` +
+		`"latest"`,
+	"error: /provides/Foo::Bar/file: missing required field",
+	`error: /resources/bugtracker/mailto: invalid email address ` +
+		`"nobody"`,
+	`error: /resources/homepage: invalid URL "not a url"`,
+	"error: /resources/irc: unknown field; custom fields must start " +
+		"with x_",
+	"warning: /resources/repository/type: repository has a URL but " +
+		"no type",
+	"error: /x~0y~1z: unknown field; custom fields must start with x_",
+	"error: /release_status: a version with an underscore can't be " +
+		"stable",
+}
+
+func TestValidateJSON(t *testing.T) {
+	t.Parallel()
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, problems := ValidateJSON(data)
+		if s == nil || len(problems) != 0 {
+			t.Errorf("%s: unexpected problems %v", path, problems)
+		}
+	}
+	s, problems := ValidateJSON([]byte(tInvalidMeta))
+	if s != nil {
+		t.Error("expected no spec for an invalid document")
+	}
+	tCheckProblems(t, problems, false)
+	if _, problems = ValidateJSON([]byte("[")); len(problems) != 1 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
+
+func TestValidateJSON_fixUp(t *testing.T) {
+	t.Parallel()
+	s, problems := ValidateJSON([]byte(tInvalidMeta), WithFixUp(true))
+	tCheckProblems(t, problems, true)
+	if s != nil {
+		t.Fatal("expected no spec, as the provides file can't be fixed")
+	}
+	doc := strings.Replace(tInvalidMeta, `{"version": "1.0_01"}`,
+		`{"file": "lib/Foo/Bar.pm"}`, 1)
+	s, _ = ValidateJSON([]byte(doc), WithFixUp(true))
+	if s == nil {
+		t.Fatal("expected the document to be fixed")
+	}
+	if s.Name != "Foo-Bar" || s.Abstract != "unknown" ||
+		s.ReleaseStatus != ReleaseStatusTesting || !s.DynamicConfig ||
+		len(s.Author) != 1 || len(s.License) != 2 ||
+		s.License[1] != LicenseUnknown {
+		t.Errorf("unexpected spec %#v", s)
+	}
+	requires := s.Prereqs.Runtime.Requires
+	if len(requires) != 2 || requires["Carp"].Raw() != "0" ||
+		requires["Moo"].Raw() != ">= 2, < 3" {
+		t.Errorf("unexpected requires %v", requires)
+	}
+	if s.Resources.Homepage != "" || s.Resources.BugTracker.MailTo != "" ||
+		s.Resources.Custom["x_irc"] != "irc://irc.perl.org/#foo" {
+		t.Errorf("unexpected resources %#v", s.Resources)
+	}
+	if _, ok := s.Custom["x_extra"]; !ok {
+		t.Errorf("unexpected custom fields %v", s.Custom)
+	}
+	if problems = Validate(s); len(problems) != 1 ||
+		problems[0].Severity != SeverityWarning {
+		t.Errorf("unexpected problems after fixing %v", problems)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	s := &Spec{Custom: Custom{"foo": 1}}
+	problems := Validate(s)
+	var paths []string
+	for _, p := range problems {
+		paths = append(paths, p.Path)
+	}
+	expected := "/abstract /author /foo /generated_by " +
+		"/meta-spec/version /name /version"
+	if got := strings.Join(paths, " "); got != expected {
+		t.Errorf("expected problems at %s, got %s", expected, got)
+	}
+	s.Name = "Foo"
+	Validate(s, WithFixUp(true))
+	if s.Abstract != "unknown" || s.Version.Raw() != "0" ||
+		s.MetaSpec.Version.Raw() != "2" || s.Custom["x_foo"] == nil {
+		t.Errorf("unexpected spec %#v", s)
+	}
+	if problems = Validate(s); problems != nil {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
+
+func tCheckProblems(t *testing.T, problems []Problem, fixed bool) {
+	t.Helper()
+	if len(problems) != len(tInvalidProblems) {
+		t.Errorf("expected %d problems, got %d: %v",
+			len(tInvalidProblems), len(problems), problems)
+		return
+	}
+	for i, p := range problems {
+		if p.Error() != tInvalidProblems[i] {
+			t.Errorf("expected %q, got %q", tInvalidProblems[i],
+				p.Error())
+		}
+		canFix := p.Path != "/provides/Foo::Bar/file" &&
+			p.Path != "/resources/repository/type"
+		if p.Fixed != (fixed && canFix) {
+			t.Errorf("%s: unexpected Fixed %v", p, p.Fixed)
+		}
+	}
+}
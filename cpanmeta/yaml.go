@@ -0,0 +1,649 @@
+package cpanmeta
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The YAML here covers what META.yml files use, which is what YAML::Tiny
+// and CPAN::Meta::YAML support: block mappings and sequences, plain and
+// quoted scalars, block scalars, and flow collections on a single line.
+// Documents are decoded into map[string]interface{}, []interface{}, string
+// and nil, with no tags, anchors or type resolution beyond ~ and null.
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML parses the first document in src.
+func parseYAML(src string) (interface{}, error) {
+	p := &yamlParser{}
+	for i, text := range strings.Split(src, "\n") {
+		text = strings.TrimRight(text, "\r")
+		if strings.HasPrefix(text, "\t") {
+			return nil, &SyntaxError{i + 1, "tabs can't be used " +
+				"for indentation"}
+		}
+		trimmed := strings.TrimLeft(text, " ")
+		p.lines = append(p.lines, yamlLine{
+			num:    i + 1,
+			indent: len(text) - len(trimmed),
+			text:   trimmed,
+		})
+	}
+	p.skip()
+	if p.pos < len(p.lines) && p.lines[p.pos].indent == 0 &&
+		isYAMLMarker(p.lines[p.pos].text, "---") {
+		// anything after the marker is a scalar document or a
+		// comment
+		rest := strings.TrimSpace(p.lines[p.pos].text[3:])
+		p.pos++
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return p.inline(rest, p.lines[p.pos-1].num)
+		}
+	}
+	p.skip()
+	if p.done() {
+		return nil, nil
+	}
+	v, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.lines[p.pos].text)
+	}
+	return v, nil
+}
+
+// skip skips blank lines and comments.
+func (p *yamlParser) skip() {
+	for p.pos < len(p.lines) {
+		t := p.lines[p.pos].text
+		if t != "" && !strings.HasPrefix(t, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// done reports whether the document has ended.
+func (p *yamlParser) done() bool {
+	if p.pos >= len(p.lines) {
+		return true
+	}
+	l := p.lines[p.pos]
+	return l.indent == 0 && (isYAMLMarker(l.text, "---") ||
+		isYAMLMarker(l.text, "..."))
+}
+
+// node parses the block node at the current line, which must be indented
+// by at least indent.
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	l := p.lines[p.pos]
+	if l.indent < indent {
+		return nil, nil
+	}
+	if isYAMLItem(l.text) {
+		return p.sequence(l.indent)
+	}
+	if _, _, ok, err := splitYAMLKey(l.text); err != nil {
+		return nil, p.errorf("%s", err)
+	} else if ok {
+		return p.mapping(l.indent)
+	}
+	p.pos++
+	return p.inline(l.text, l.num)
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.skip(); !p.done(); p.skip() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, rest, ok, err := splitYAMLKey(l.text)
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+		if m[key], err = p.value(indent, rest, l.num); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	s := []interface{}{}
+	for p.skip(); !p.done(); p.skip() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if !isYAMLItem(l.text) {
+			if l.indent == indent {
+				// the next key of a mapping this sequence is
+				// at the same indentation as
+				break
+			}
+			return nil, p.errorf("expected a sequence item")
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		nested := rest != "" && !strings.HasPrefix(rest, "#") &&
+			(isYAMLItem(rest) || isYAMLKey(rest))
+		if nested {
+			// a compact collection, as in "- key: value",
+			// which continues on the lines indented to match
+			p.lines[p.pos].indent += len(l.text) - len(rest)
+			p.lines[p.pos].text = rest
+			v, err := p.node(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			continue
+		}
+		p.pos++
+		v, err := p.value(indent, rest, l.num)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// value parses the value of a mapping entry or sequence item at indent,
+// where rest is what follows the key or dash on its line.
+func (p *yamlParser) value(indent int, rest string, num int) (interface{},
+	error) {
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		p.skip()
+		if p.done() {
+			return nil, nil
+		}
+		l := p.lines[p.pos]
+		if l.indent > indent {
+			return p.node(indent + 1)
+		}
+		if l.indent == indent && isYAMLItem(l.text) && p.inMapping(
+			indent) {
+			// sequences can be at the same indentation as the
+			// key they belong to
+			return p.sequence(indent)
+		}
+		return nil, nil
+	}
+	if rest[0] == '|' || rest[0] == '>' {
+		return p.blockScalar(indent, rest, num)
+	}
+	return p.inline(rest, num)
+}
+
+// inMapping reports whether the line before the current one is a mapping
+// key at indent.
+func (p *yamlParser) inMapping(indent int) bool {
+	for i := p.pos - 1; i >= 0; i-- {
+		l := p.lines[i]
+		if l.text == "" || strings.HasPrefix(l.text, "#") {
+			continue
+		}
+		return l.indent == indent && !isYAMLItem(l.text)
+	}
+	return false
+}
+
+// blockScalar parses a literal (|) or folded (>) scalar.
+func (p *yamlParser) blockScalar(indent int, header string,
+	num int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(strings.TrimSpace(strings.SplitN(header[1:],
+		"#", 2)[0])) {
+		switch c {
+		case '-', '+':
+			chomp = c
+		default:
+			return nil, &SyntaxError{num, "unsupported block " +
+				"scalar header " + strconv.Quote(header)}
+		}
+	}
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if l.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if blockIndent < 0 {
+			if l.indent <= indent {
+				break
+			}
+			blockIndent = l.indent
+		}
+		if l.indent < blockIndent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", l.indent-blockIndent)+
+			l.text)
+	}
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			switch prev := lines[i-1]; {
+			case !folded || line == "" ||
+				strings.HasPrefix(line, " ") ||
+				strings.HasPrefix(prev, " "):
+				sb.WriteByte('\n')
+			case prev == "":
+				// the blank line was the line break
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	switch {
+	case len(lines) == 0 || chomp == '-':
+	case chomp == '+':
+		sb.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// inline parses a scalar or flow collection on a single line.
+func (p *yamlParser) inline(s string, num int) (interface{}, error) {
+	f := &yamlFlow{s: s}
+	v, err := f.value(false)
+	if err == nil {
+		f.space()
+		if f.pos < len(f.s) && f.s[f.pos] != '#' {
+			err = fmt.Errorf("unexpected %q", f.s[f.pos:])
+		}
+	}
+	if err != nil {
+		return nil, &SyntaxError{num, err.Error()}
+	}
+	return v, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		num = p.lines[len(p.lines)-1].num
+	}
+	return &SyntaxError{num, fmt.Sprintf(format, args...)}
+}
+
+// yamlFlow parses scalars and flow collections.
+type yamlFlow struct {
+	s   string
+	pos int
+}
+
+// value parses a value; inFlow is whether it's inside a flow collection,
+// where commas and closing brackets end plain scalars.
+func (f *yamlFlow) value(inFlow bool) (interface{}, error) {
+	f.space()
+	if f.pos >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.pos] {
+	case '[':
+		f.pos++
+		s := []interface{}{}
+		for {
+			if f.space(); f.consume(']') {
+				return s, nil
+			}
+			v, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			if f.space(); !f.consume(',') {
+				if !f.consume(']') {
+					return nil, f.expected("] or ,")
+				}
+				return s, nil
+			}
+		}
+	case '{':
+		f.pos++
+		m := make(map[string]interface{})
+		for {
+			if f.space(); f.consume('}') {
+				return m, nil
+			}
+			k, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if f.space(); !ok || !f.consume(':') {
+				return nil, f.expected("a key")
+			}
+			if m[key], err = f.value(true); err != nil {
+				return nil, err
+			}
+			if f.space(); !f.consume(',') {
+				if !f.consume('}') {
+					return nil, f.expected("} or ,")
+				}
+				return m, nil
+			}
+		}
+	case '\'', '"':
+		return f.quoted()
+	}
+	return f.plain(inFlow), nil
+}
+
+func (f *yamlFlow) quoted() (interface{}, error) {
+	q := f.s[f.pos]
+	var sb strings.Builder
+	for i := f.pos + 1; i < len(f.s); i++ {
+		c := f.s[i]
+		switch {
+		case c == q && q == '\'' && i+1 < len(f.s) && f.s[i+1] == '\'':
+			sb.WriteByte('\'')
+			i++
+		case c == q:
+			f.pos = i + 1
+			return sb.String(), nil
+		case c == '\\' && q == '"':
+			n, err := unescapeYAML(&sb, f.s[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+// plain parses a plain scalar, which ends at a comment, or in a flow
+// collection at a flow indicator.
+func (f *yamlFlow) plain(inFlow bool) interface{} {
+	start := f.pos
+	for ; f.pos < len(f.s); f.pos++ {
+		c := f.s[f.pos]
+		if c == '#' && f.pos > start && f.s[f.pos-1] == ' ' {
+			break
+		}
+		if inFlow && (c == ',' || c == ']' || c == '}' || c == ':' &&
+			(f.pos+1 == len(f.s) || f.s[f.pos+1] == ' ')) {
+			break
+		}
+	}
+	s := strings.TrimSpace(f.s[start:f.pos])
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil
+	}
+	return s
+}
+
+func (f *yamlFlow) space() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) consume(c byte) bool {
+	if f.pos < len(f.s) && f.s[f.pos] == c {
+		f.pos++
+		return true
+	}
+	return false
+}
+
+func (f *yamlFlow) expected(what string) error {
+	if f.pos >= len(f.s) {
+		return fmt.Errorf("expected %s, got end of line", what)
+	}
+	return fmt.Errorf("expected %s, got %q", what, f.s[f.pos:])
+}
+
+// unescapeYAML writes the escape sequence at the start of s, which follows
+// a backslash, to sb, returning its length.
+func unescapeYAML(sb *strings.Builder, s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("unterminated string")
+	}
+	simple := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", 'n': "\n",
+		'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ",
+		'"': `"`, '/': "/", '\\': `\`, 'N': "\u0085", '_': " ",
+		'L': " ", 'P': " ",
+	}
+	if r, ok := simple[s[0]]; ok {
+		sb.WriteString(r)
+		return 1, nil
+	}
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	if size == 0 || len(s) < size+1 {
+		return 0, fmt.Errorf("invalid escape \\%c", s[0])
+	}
+	n, err := strconv.ParseUint(s[1:size+1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape \\%s", s[:size+1])
+	}
+	sb.WriteRune(rune(n))
+	return size + 1, nil
+}
+
+// splitYAMLKey splits a mapping entry into its key and the rest of the
+// line, reporting whether s is a mapping entry at all.
+func splitYAMLKey(s string) (string, string, bool, error) {
+	if s[0] == '\'' || s[0] == '"' {
+		f := &yamlFlow{s: s}
+		k, err := f.quoted()
+		if err != nil {
+			return "", "", false, err
+		}
+		f.space()
+		if !f.consume(':') || f.pos < len(f.s) && f.s[f.pos] != ' ' {
+			return "", "", false, nil
+		}
+		return k.(string), strings.TrimSpace(f.s[f.pos:]), true, nil
+	}
+	if strings.ContainsRune("[{#", rune(s[0])) {
+		return "", "", false, nil
+	}
+	i := strings.Index(s, ": ")
+	if j := strings.Index(s, " #"); j >= 0 && (i < 0 || j < i) {
+		s = strings.TrimRight(s[:j], " ")
+		i = -1
+	}
+	if i < 0 {
+		if !strings.HasSuffix(s, ":") {
+			return "", "", false, nil
+		}
+		i = len(s) - 1
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true,
+		nil
+}
+
+func isYAMLKey(s string) bool {
+	_, _, ok, err := splitYAMLKey(s)
+	return ok && err == nil
+}
+
+func isYAMLItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+func isYAMLMarker(s, marker string) bool {
+	return strings.HasPrefix(s, marker) && (len(s) == len(marker) ||
+		s[len(marker)] == ' ')
+}
+
+// dumpYAML writes v as a YAML document in the style of CPAN::Meta::YAML,
+// with mapping keys sorted. Strings are quoted wherever they could be
+// mistaken for something else, including numbers; ints and bools aren't.
+func dumpYAML(v interface{}) string {
+	var sb strings.Builder
+	sb.WriteString("---")
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			sb.WriteByte('\n')
+			dumpYAMLMapping(&sb, v, "")
+			return sb.String()
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			sb.WriteByte('\n')
+			dumpYAMLSequence(&sb, v, "")
+			return sb.String()
+		}
+	}
+	sb.WriteString(" " + yamlScalar(v, false) + "\n")
+	return sb.String()
+}
+
+func dumpYAMLMapping(sb *strings.Builder, m map[string]interface{},
+	indent string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(indent + yamlScalar(k, true) + ":")
+		dumpYAMLValue(sb, m[k], indent)
+	}
+}
+
+func dumpYAMLSequence(sb *strings.Builder, s []interface{}, indent string) {
+	for _, v := range s {
+		sb.WriteString(indent + "-")
+		dumpYAMLValue(sb, v, indent)
+	}
+}
+
+// dumpYAMLValue writes v after a key or dash, with anything nested in it
+// indented by two more spaces than indent.
+func dumpYAMLValue(sb *strings.Builder, v interface{}, indent string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			sb.WriteByte('\n')
+			dumpYAMLMapping(sb, v, indent+"  ")
+			return
+		}
+		sb.WriteString(" {}\n")
+	case []interface{}:
+		if len(v) > 0 {
+			sb.WriteByte('\n')
+			dumpYAMLSequence(sb, v, indent+"  ")
+			return
+		}
+		sb.WriteString(" []\n")
+	default:
+		sb.WriteString(" " + yamlScalar(v, false) + "\n")
+	}
+}
+
+// yamlScalar quotes a scalar as CPAN::Meta::YAML does.
+func yamlScalar(v interface{}, isKey bool) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "~"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" {
+		return "''"
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return "'" + s + "'"
+	}
+	if strings.ContainsFunc(s, func(r rune) bool {
+		return r < 0x20 && r != '\t' || r == 0x7f || r == '\'' ||
+			r >= 0x80 && r <= 0x9f
+	}) {
+		var sb strings.Builder
+		sb.WriteByte('"')
+		for _, r := range s {
+			switch {
+			case r == '\\' || r == '"':
+				sb.WriteString(`\` + string(r))
+			case r == '\n':
+				sb.WriteString(`\n`)
+			case r < 0x20 || r >= 0x7f && r <= 0x9f:
+				sb.WriteString(fmt.Sprintf(`\x%02X`, r))
+			default:
+				sb.WriteRune(r)
+			}
+		}
+		sb.WriteByte('"')
+		return sb.String()
+	}
+	if strings.ContainsAny(s[:1], "~!@#%&*|>?:,'\"`{}[]") ||
+		strings.Trim(s, "-") == "" || strings.ContainsAny(s, " \t") ||
+		strings.HasSuffix(s, ":") || yamlReserved[s] {
+		return "'" + s + "'"
+	}
+	return s
+}
+
+// yamlReserved are the plain scalars that would be read as something other
+// than a string.
+var yamlReserved = map[string]bool{
+	"null": true, "Null": true, "NULL": true,
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"true": true, "True": true, "TRUE": true,
+	"false": true, "False": true, "FALSE": true,
+	"on": true, "On": true, "ON": true,
+	"off": true, "Off": true, "OFF": true,
+}
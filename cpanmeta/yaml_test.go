@@ -0,0 +1,160 @@
+package cpanmeta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type tYAMLMap = map[string]interface{}
+type tYAMLSeq = []interface{}
+
+func TestParseYAML(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"block mapping", "a: 1\nb:\n  c: d\n",
+			tYAMLMap{"a": "1", "b": tYAMLMap{"c": "d"}}},
+		{"block sequence", "a:\n  - x\n  - y\n",
+			tYAMLMap{"a": tYAMLSeq{"x", "y"}}},
+		{"sequence at key indentation", "a:\n- x\n- y\nb: 2\n",
+			tYAMLMap{"a": tYAMLSeq{"x", "y"}, "b": "2"}},
+		{"compact collections", "- a: 1\n  b: 2\n- c\n- - n1\n  - n2\n",
+			tYAMLSeq{tYAMLMap{"a": "1", "b": "2"}, "c",
+				tYAMLSeq{"n1", "n2"}}},
+		{"flow sequence", "a: [1, 'two', \"th\\\"ree\", [x], {k: v}]\n",
+			tYAMLMap{"a": tYAMLSeq{"1", "two", `th"ree`,
+				tYAMLSeq{"x"}, tYAMLMap{"k": "v"}}}},
+		{"flow mapping", "b: {x: 1, y: [2, 3], 'q: k': ~}\n",
+			tYAMLMap{"b": tYAMLMap{"x": "1",
+				"y": tYAMLSeq{"2", "3"}, "q: k": nil}}},
+		{"empty flow collections", "c: []\nd: {}\n",
+			tYAMLMap{"c": tYAMLSeq{}, "d": tYAMLMap{}}},
+		{"single quoted", "a: 'it''s'\nb: 'x # y'\n",
+			tYAMLMap{"a": "it's", "b": "x # y"}},
+		{"double quoted", `a: "tab\there \u00e9\x41\n"` + "\n",
+			tYAMLMap{"a": "tab\there \u00e9A\n"}},
+		{"quoted keys", "\"a key\": v\n'k2': w\n",
+			tYAMLMap{"a key": "v", "k2": "w"}},
+		{"plain scalars", "url: http://x/y\na:b: c\nd: e # comment\n",
+			tYAMLMap{"url": "http://x/y", "a:b": "c", "d": "e"}},
+		{"nulls", "a: ~\nb: null\nc:\n",
+			tYAMLMap{"a": nil, "b": nil, "c": nil}},
+		{"literal block scalar",
+			"a: |\n  one\n    two\n  three\nb: 1\n",
+			tYAMLMap{"a": "one\n  two\nthree\n", "b": "1"}},
+		{"folded block scalar", "a: >\n  one\n  two\n\n  three\n",
+			tYAMLMap{"a": "one two\nthree\n"}},
+		{"stripped block scalar", "a: |-\n  one\n\nb: 1\n",
+			tYAMLMap{"a": "one", "b": "1"}},
+		{"kept block scalar", "a: |+\n  one\n\nb: 1\n",
+			tYAMLMap{"a": "one\n\n", "b": "1"}},
+		{"comments", "# top\n---  # start\na: 1 # one\n\n# b\nb: 2\n",
+			tYAMLMap{"a": "1", "b": "2"}},
+		{"document end", "---\na: 1\n...\nb: 2\n", tYAMLMap{"a": "1"}},
+		{"first document only", "---\n- 1\n---\n- 2\n", tYAMLSeq{"1"}},
+		{"scalar document", "--- hello\n", "hello"},
+		{"empty document", "---\n", nil},
+		{"empty", "", nil},
+		{"CRLF", "a: 1\r\nb: 2\r\n", tYAMLMap{"a": "1", "b": "2"}},
+	}
+	for _, test := range tests {
+		v, err := parseYAML(test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name,
+				test.expected, v)
+		}
+	}
+}
+
+func TestParseYAML_errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"a: 1\n\tb: 2\n", 2},
+		{"\ta: 1\n", 1},
+		{"a: 1\na: 2\n", 2},
+		{"a: [1, 2\n", 1},
+		{"a: {x 1}\n", 1},
+		{"a: 'x\n", 1},
+		{"a: \"\\q\"\n", 1},
+		{"a: 1\n   b: 2\n", 2},
+		{"a:\n  - x\n  y: z\n", 3},
+		{"a: |x\n  t\n", 1},
+		{"a: 1\n- b\n", 2},
+	}
+	for _, test := range tests {
+		_, err := parseYAML(test.input)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected a SyntaxError, got %v",
+				test.input, err)
+		} else if se.Line != test.line {
+			t.Errorf("%q: expected an error on line %d, got %v",
+				test.input, test.line, err)
+		}
+	}
+}
+
+func TestDumpYAML(t *testing.T) {
+	t.Parallel()
+	doc := tYAMLMap{
+		"name":           "Foo-Bar",
+		"version":        "1.0",
+		"author":         tYAMLSeq{"Jane Doe <jane@example.com>"},
+		"dynamic_config": 0,
+		"empty":          "",
+		"null":           nil,
+		"yes":            "yes",
+		"colon":          "a: b",
+		"dash":           "-",
+		"line":           "one\ntwo",
+		"quote":          "it's",
+		"map":            tYAMLMap{},
+		"list":           tYAMLSeq{},
+		"nested": tYAMLMap{
+			"list": tYAMLSeq{tYAMLMap{"a": "b"}},
+		},
+	}
+	const expected = `---
+author:
+  - 'Jane Doe <jane@example.com>'
+colon: 'a: b'
+dash: '-'
+dynamic_config: 0
+empty: ''
+line: "one\ntwo"
+list: []
+map: {}
+name: Foo-Bar
+nested:
+  list:
+    -
+      a: b
+'null': ~
+quote: "it's"
+version: '1.0'
+'yes': 'yes'
+`
+	got := dumpYAML(doc)
+	if got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	parsed, err := parseYAML(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["dynamic_config"] = "0"
+	if !reflect.DeepEqual(parsed, doc) {
+		t.Errorf("round trip: expected %#v, got %#v", doc, parsed)
+	}
+}
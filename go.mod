@@ -1,6 +1,6 @@
 module github.com/cmburn/perlutils
 
-go 1.19
+go 1.23
 
 // I'm aware this is an outdated version, however in v7.14.0 they introduced a
 // "product check", which breaks CPAN.
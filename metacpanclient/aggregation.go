@@ -0,0 +1,306 @@
+package metacpanclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Aggregation is a summary Elasticsearch computes over everything a search
+// matches. Aggregations are requested by passing a map[string]Aggregation as
+// the "aggregations" argument to any search, and their results are read back
+// by name with ResultSet.Aggregation.
+//
+// For example, to count ETHER's releases per month:
+//
+//	rs, err := mc.ReleaseSearch(map[string]interface{}{
+//		"query": map[string]interface{}{"author": "ETHER"},
+//		"aggregations": map[string]Aggregation{
+//			"per_month": &DateHistogramAggregation{
+//				Field:            "date",
+//				CalendarInterval: "month",
+//			},
+//		},
+//	})
+//	for _, b := range rs.Aggregation("per_month").Buckets {
+//		fmt.Println(b.Time().Format("2006-01"), b.DocCount)
+//	}
+type Aggregation interface {
+	aggregation() (map[string]interface{}, error)
+}
+
+// TermsAggregation groups results by the distinct values of a field, giving
+// a bucket for each of the most common ones.
+type TermsAggregation struct {
+	// Field is the field to group by.
+	Field string
+
+	// Size is the number of buckets to return. Elasticsearch returns 10
+	// if it's zero.
+	Size int
+
+	// MinDocCount leaves out buckets with fewer results than this.
+	MinDocCount int
+
+	// OrderBy is what buckets are sorted by: "_count", "_key" or the name
+	// of one of Aggregations. Buckets are sorted by count, largest first,
+	// if it's empty.
+	OrderBy string
+
+	// Ascending sorts buckets smallest first when OrderBy is set.
+	Ascending bool
+
+	// Aggregations are computed over the results in each bucket.
+	Aggregations map[string]Aggregation
+}
+
+func (a *TermsAggregation) aggregation() (map[string]interface{}, error) {
+	if a.Field == "" {
+		return nil, errAggregationField
+	}
+	terms := map[string]interface{}{"field": a.Field}
+	if a.Size > 0 {
+		terms["size"] = a.Size
+	}
+	if a.MinDocCount > 0 {
+		terms["min_doc_count"] = a.MinDocCount
+	}
+	if a.OrderBy != "" {
+		terms["order"] = map[string]string{
+			a.OrderBy: order(a.Ascending),
+		}
+	}
+	return withSubAggregations("terms", terms, a.Aggregations)
+}
+
+// DateHistogramAggregation groups results into buckets covering equal
+// periods of time. Exactly one of CalendarInterval and FixedInterval must be
+// set.
+type DateHistogramAggregation struct {
+	// Field is the date field to group by.
+	Field string
+
+	// CalendarInterval is a calendar-aware bucket size: "minute", "hour",
+	// "day", "week", "month", "quarter" or "year".
+	CalendarInterval string
+
+	// FixedInterval is a fixed bucket size in Elasticsearch's time units,
+	// such as "90m" or "30d".
+	FixedInterval string
+
+	// Format is the date format used for each bucket's KeyAsString.
+	Format string
+
+	// MinDocCount leaves out buckets with fewer results than this. Empty
+	// buckets between the first and last are returned if it's zero.
+	MinDocCount int
+
+	// Aggregations are computed over the results in each bucket.
+	Aggregations map[string]Aggregation
+}
+
+func (a *DateHistogramAggregation) aggregation() (map[string]interface{},
+	error) {
+	if a.Field == "" {
+		return nil, errAggregationField
+	}
+	hist := map[string]interface{}{
+		"field":         a.Field,
+		"min_doc_count": a.MinDocCount,
+	}
+	switch {
+	case a.CalendarInterval != "" && a.FixedInterval == "":
+		hist["calendar_interval"] = a.CalendarInterval
+	case a.FixedInterval != "" && a.CalendarInterval == "":
+		hist["fixed_interval"] = a.FixedInterval
+	default:
+		return nil, errAggregationInterval
+	}
+	if a.Format != "" {
+		hist["format"] = a.Format
+	}
+	return withSubAggregations("date_histogram", hist, a.Aggregations)
+}
+
+// StatsAggregation computes the count, minimum, maximum, average and sum of
+// a numeric field.
+type StatsAggregation struct {
+	// Field is the field to summarize.
+	Field string
+}
+
+func (a *StatsAggregation) aggregation() (map[string]interface{}, error) {
+	if a.Field == "" {
+		return nil, errAggregationField
+	}
+	return map[string]interface{}{
+		"stats": map[string]interface{}{"field": a.Field},
+	}, nil
+}
+
+// AggregationResult is the result of an Aggregation. Bucket aggregations
+// (terms and date histograms) fill in Buckets, and stats aggregations fill
+// in Stats.
+type AggregationResult struct {
+	// Buckets are the groups results were split into.
+	Buckets []*Bucket
+
+	// SumOtherDocCount is the number of results in buckets that weren't
+	// returned, for a terms aggregation.
+	SumOtherDocCount int
+
+	// Stats is the result of a stats aggregation.
+	Stats *Stats
+}
+
+func (a *AggregationResult) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Buckets          []*Bucket `json:"buckets"`
+		SumOtherDocCount int       `json:"sum_other_doc_count"`
+		Count            *int      `json:"count"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	a.Buckets = v.Buckets
+	a.SumOtherDocCount = v.SumOtherDocCount
+	if v.Buckets == nil && v.Count != nil {
+		a.Stats = &Stats{}
+		if err := json.Unmarshal(data, a.Stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bucket is one group of results from a bucket aggregation.
+type Bucket struct {
+	// Key is the value the bucket is for: a string or a float64 for a
+	// terms aggregation, and the start of the period in milliseconds since
+	// the Unix epoch for a date histogram.
+	Key interface{}
+
+	// KeyAsString is Key formatted as a string, if Elasticsearch did so.
+	KeyAsString string
+
+	// DocCount is the number of results in the bucket.
+	DocCount int
+
+	// Aggregations are the results of the aggregation's sub-aggregations
+	// for this bucket.
+	Aggregations map[string]*AggregationResult
+}
+
+// String returns KeyAsString if it's set, and Key otherwise.
+func (b *Bucket) String() string {
+	if b.KeyAsString != "" {
+		return b.KeyAsString
+	}
+	return fmt.Sprint(b.Key)
+}
+
+// Time returns the start of a date histogram bucket's period, or the zero
+// time if Key isn't a number.
+func (b *Bucket) Time() time.Time {
+	ms, ok := b.Key.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms)).UTC()
+}
+
+func (b *Bucket) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for k, raw := range fields {
+		var err error
+		switch k {
+		case "key":
+			err = json.Unmarshal(raw, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(raw, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(raw, &b.DocCount)
+		default:
+			// anything else that's an object is a sub-aggregation
+			if len(raw) == 0 || raw[0] != '{' {
+				continue
+			}
+			var res AggregationResult
+			if err = json.Unmarshal(raw, &res); err != nil {
+				break
+			}
+			if b.Aggregations == nil {
+				b.Aggregations = make(
+					map[string]*AggregationResult)
+			}
+			b.Aggregations[k] = &res
+		}
+		if err != nil {
+			return fmt.Errorf("bucket %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Stats is the result of a StatsAggregation. Min, Max and Avg are zero if
+// Count is.
+type Stats struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Sum   float64 `json:"sum"`
+}
+
+// buildAggregations converts aggregations into the form Elasticsearch
+// expects.
+func buildAggregations(aggs map[string]Aggregation) (map[string]interface{},
+	error) {
+	if len(aggs) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]interface{}, len(aggs))
+	for name, agg := range aggs {
+		if agg == nil {
+			return nil, fmt.Errorf("aggregation %s: %w", name,
+				errNilAggregation)
+		}
+		v, err := agg.aggregation()
+		if err != nil {
+			return nil, fmt.Errorf("aggregation %s: %w", name, err)
+		}
+		m[name] = v
+	}
+	return m, nil
+}
+
+func withSubAggregations(typ string, body map[string]interface{},
+	subs map[string]Aggregation) (map[string]interface{}, error) {
+	m := map[string]interface{}{typ: body}
+	aggs, err := buildAggregations(subs)
+	if err != nil {
+		return nil, err
+	}
+	if aggs != nil {
+		m["aggs"] = aggs
+	}
+	return m, nil
+}
+
+func order(ascending bool) string {
+	if ascending {
+		return "asc"
+	}
+	return "desc"
+}
+
+var (
+	errNilAggregation      = errors.New("aggregation cannot be nil")
+	errAggregationField    = errors.New("aggregation field cannot be empty")
+	errAggregationInterval = errors.New("exactly one of CalendarInterval " +
+		"and FixedInterval must be set")
+)
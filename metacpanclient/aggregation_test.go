@@ -0,0 +1,128 @@
+package metacpanclient
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildAggregations(t *testing.T) {
+	t.Parallel()
+	got, err := buildAggregations(map[string]Aggregation{
+		"dists": &TermsAggregation{
+			Field:   "distribution",
+			Size:    5,
+			OrderBy: "_key",
+			Aggregations: map[string]Aggregation{
+				"versions": &StatsAggregation{
+					Field: "version_numified",
+				},
+			},
+		},
+		"per_month": &DateHistogramAggregation{
+			Field:            "date",
+			CalendarInterval: "month",
+			Format:           "yyyy-MM",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := tUnmarshal(`{
+		"dists": {
+			"terms": {
+				"field": "distribution",
+				"size": 5,
+				"order": {"_key": "desc"}
+			},
+			"aggs": {
+				"versions": {"stats": {"field": "version_numified"}}
+			}
+		},
+		"per_month": {
+			"date_histogram": {
+				"field": "date",
+				"calendar_interval": "month",
+				"format": "yyyy-MM",
+				"min_doc_count": 0
+			}
+		}
+	}`)
+	if got := tRemarshal(got); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	for _, tt := range []struct {
+		agg      Aggregation
+		expected error
+	}{
+		{nil, errNilAggregation},
+		{&TermsAggregation{}, errAggregationField},
+		{&StatsAggregation{}, errAggregationField},
+		{&DateHistogramAggregation{Field: "date"},
+			errAggregationInterval},
+		{&DateHistogramAggregation{Field: "date",
+			CalendarInterval: "day", FixedInterval: "1d"},
+			errAggregationInterval},
+	} {
+		_, err := buildAggregations(map[string]Aggregation{"a": tt.agg})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%#v: expected %v, got %v", tt.agg, tt.expected,
+				err)
+		}
+	}
+}
+
+func TestAggregationResult_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+	var res map[string]*AggregationResult
+	err := json.Unmarshal([]byte(`{
+		"per_year": {
+			"buckets": [{
+				"key_as_string": "2021",
+				"key": 1609459200000,
+				"doc_count": 2,
+				"dists": {
+					"doc_count_error_upper_bound": 0,
+					"sum_other_doc_count": 1,
+					"buckets": [{"key": "Moose", "doc_count": 1}]
+				}
+			}]
+		},
+		"versions": {
+			"count": 2, "min": 1, "max": 3, "avg": 2, "sum": 4
+		},
+		"none": {
+			"count": 0, "min": null, "max": null, "avg": null, "sum": 0
+		}
+	}`), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	years := res["per_year"]
+	if years.Stats != nil || len(years.Buckets) != 1 {
+		t.Fatalf("unexpected result %#v", years)
+	}
+	b := years.Buckets[0]
+	if b.String() != "2021" || b.DocCount != 2 ||
+		!b.Time().Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected bucket %#v", b)
+	}
+	dists := b.Aggregations["dists"]
+	if dists == nil || dists.SumOtherDocCount != 1 ||
+		len(dists.Buckets) != 1 || dists.Buckets[0].Key != "Moose" ||
+		dists.Buckets[0].String() != "Moose" {
+		t.Errorf("unexpected sub-aggregation %#v", dists)
+	}
+	if !dists.Buckets[0].Time().IsZero() {
+		t.Errorf("expected a zero time for a string key")
+	}
+	expected := Stats{Count: 2, Min: 1, Max: 3, Avg: 2, Sum: 4}
+	if s := res["versions"].Stats; s == nil || *s != expected {
+		t.Errorf("unexpected stats %#v", s)
+	}
+	if s := res["none"].Stats; s == nil || *s != (Stats{}) {
+		t.Errorf("unexpected stats %#v", s)
+	}
+}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
@@ -143,13 +144,19 @@ func (a *Author) MetaCPANURL() string {
 
 // Releases returns a ResultSet[*Release] of the author's releases.
 func (a *Author) Releases() (ResultSet[*Release], error) {
+	return a.ReleasesContext(context.Background())
+}
+
+// ReleasesContext is like Releases, but with a context.
+func (a *Author) ReleasesContext(ctx context.Context) (ResultSet[*Release],
+	error) {
 	if a.releases != nil {
 		return a.releases, nil
 	}
 	if a.mc == nil {
 		return nil, ErrNilClient
 	}
-	r, err := a.mc.ReleaseSearch(map[string]interface{}{
+	r, err := a.mc.ReleaseSearchContext(ctx, map[string]interface{}{
 		"author": a.PauseID,
 	})
 	if err != nil {
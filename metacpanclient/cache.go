@@ -0,0 +1,207 @@
+package metacpanclient
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores MetaCPAN responses so that repeated lookups don't have to go
+// over the network. Implementations must be safe for concurrent use. See
+// WithCache, NewMemoryCache and NewDiskCache.
+type Cache interface {
+	// Get returns the entry stored under key, if there is one.
+	Get(key string) (*CacheEntry, bool)
+
+	// Set stores e under key, replacing anything already there.
+	Set(key string, e *CacheEntry)
+
+	// Delete removes the entry stored under key, if there is one.
+	Delete(key string)
+}
+
+// CacheEntry is a single cached response body, along with what's needed to
+// decide whether it's still good and to revalidate it if it isn't.
+type CacheEntry struct {
+	// Body is the response body.
+	Body []byte `json:"body"`
+
+	// ETag is the response's ETag header, used for If-None-Match.
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the response's Last-Modified header, used for
+	// If-Modified-Since.
+	LastModified string `json:"last_modified,omitempty"`
+
+	// Expires is when the entry stops being fresh. If it's zero, the entry
+	// is revalidated every time it's used.
+	Expires time.Time `json:"expires,omitempty"`
+
+	// Immutable entries never expire.
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// fresh reports whether the entry can be used without asking the server.
+func (e *CacheEntry) fresh(now time.Time) bool {
+	return e.Immutable || (!e.Expires.IsZero() && now.Before(e.Expires))
+}
+
+// revalidatable reports whether the server can be asked if the entry is
+// still good.
+func (e *CacheEntry) revalidatable() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// update refreshes the entry's lifetime and validators from the headers of a
+// response, returning false if they say it shouldn't be stored at all.
+func (e *CacheEntry) update(h http.Header, now time.Time) bool {
+	if etag := h.Get("ETag"); etag != "" {
+		e.ETag = etag
+	}
+	if lm := h.Get("Last-Modified"); lm != "" {
+		e.LastModified = lm
+	}
+	if e.Immutable {
+		return true
+	}
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if _, ok := cc["immutable"]; ok {
+		e.Immutable = true
+		return true
+	}
+	e.Expires = time.Time{}
+	_, noCache := cc["no-cache"]
+	switch maxAge, ok := cc["max-age"]; {
+	case noCache:
+		// stored, but always revalidated
+	case ok:
+		if secs, err := strconv.Atoi(maxAge); err == nil && secs > 0 {
+			e.Expires = now.Add(time.Duration(secs) * time.Second)
+		}
+	default:
+		if t, err := http.ParseTime(h.Get("Expires")); err == nil {
+			e.Expires = t
+		}
+	}
+	return e.fresh(now) || e.revalidatable()
+}
+
+func parseCacheControl(s string) map[string]string {
+	cc := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(k))] =
+			strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return cc
+}
+
+// cacheKey identifies a request by its method, full URL and body.
+func cacheKey(method, url string, body []byte) string {
+	sb := strings.Builder{}
+	sb.WriteString(method)
+	sb.WriteRune(' ')
+	sb.WriteString(url)
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		sb.WriteRune(' ')
+		sb.WriteString(hex.EncodeToString(sum[:]))
+	}
+	return sb.String()
+}
+
+// ImmutablePath reports whether path refers to something on MetaCPAN that
+// can never change once published: a specific release (as opposed to the
+// latest release of a distribution), or a file, its source or the changes
+// file from a specific release. Pass it to WithImmutable to cache those
+// indefinitely.
+func ImmutablePath(path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.IndexRune(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	switch segments[0] {
+	case "release", "changes":
+		// release/AUTHOR/NAME rather than release/DIST
+		return len(segments) == 3
+	case "source", "file":
+		// source/AUTHOR/RELEASE/PATH...
+		return len(segments) >= 4
+	default:
+		return false
+	}
+}
+
+// memoryCache is a Cache holding a fixed number of entries in memory,
+// evicting the least recently used first.
+type memoryCache struct {
+	entries map[string]*list.Element
+	order   *list.List
+	max     int
+	mu      sync.Mutex
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache returns a Cache that keeps up to maxEntries responses in
+// memory, evicting the least recently used when it's full. If maxEntries is
+// zero or less, it's unbounded.
+func NewMemoryCache(maxEntries int) Cache {
+	return &memoryCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		max:     maxEntries,
+	}
+}
+
+func (c *memoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).entry, true
+}
+
+func (c *memoryCache) Set(key string, e *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoryCacheItem{key, e})
+	for c.max > 0 && c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
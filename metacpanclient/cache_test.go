@@ -0,0 +1,141 @@
+package metacpanclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	t.Parallel()
+	c := NewMemoryCache(2)
+	c.Set("a", &CacheEntry{Body: []byte("a")})
+	c.Set("b", &CacheEntry{Body: []byte("b")})
+	// touch a, so b is the least recently used
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a")
+	}
+	c.Set("c", &CacheEntry{Body: []byte("c")})
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if e, ok := c.Get(k); !ok || string(e.Body) != k {
+			t.Errorf("expected %s to be cached", k)
+		}
+	}
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to be deleted")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	t.Parallel()
+	c, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Set("GET /a", &CacheEntry{
+		Body:    []byte(`{"name":"a"}`),
+		ETag:    `"abc"`,
+		Expires: expires,
+	})
+	e, ok := c.Get("GET /a")
+	if !ok {
+		t.Fatal("expected entry")
+	}
+	if string(e.Body) != `{"name":"a"}` || e.ETag != `"abc"` ||
+		!e.Expires.Equal(expires) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if _, ok = c.Get("GET /b"); ok {
+		t.Errorf("unexpected entry")
+	}
+	c.Delete("GET /a")
+	if _, ok = c.Get("GET /a"); ok {
+		t.Errorf("expected entry to be deleted")
+	}
+}
+
+func TestClient_cache(t *testing.T) {
+	t.Parallel()
+	var calls, revalidated int32
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			switch r.URL.Path {
+			case "/distribution/Fresh":
+				w.Header().Set("Cache-Control", "max-age=3600")
+			case "/distribution/Tagged":
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					atomic.AddInt32(&revalidated, 1)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+			case "/distribution/Private":
+				w.Header().Set("Cache-Control", "no-store")
+			}
+			_, _ = w.Write([]byte(`{"name":"Mojolicious"}`))
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL), WithCache(NewMemoryCache(0)),
+		WithImmutable(func(path string) bool {
+			return path == "/distribution/Forever"
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	for _, tc := range []struct {
+		name     string
+		expected int32
+	}{
+		{"Fresh", 1},
+		{"Tagged", 2},
+		{"Private", 2},
+		{"Forever", 1},
+	} {
+		atomic.StoreInt32(&calls, 0)
+		for i := 0; i < 2; i++ {
+			d, err := mc.Distribution(tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Name != mojo {
+				t.Errorf("%s: unexpected name %q", tc.name,
+					d.Name)
+			}
+		}
+		if n := atomic.LoadInt32(&calls); n != tc.expected {
+			t.Errorf("%s: expected %d calls, got %d", tc.name,
+				tc.expected, n)
+		}
+	}
+	if n := atomic.LoadInt32(&revalidated); n != 1 {
+		t.Errorf("expected 1 revalidation, got %d", n)
+	}
+}
+
+func TestImmutablePath(t *testing.T) {
+	t.Parallel()
+	for path, expected := range map[string]bool{
+		"/release/Mojolicious":                            false,
+		"/release/SRI/Mojolicious-9.28":                   true,
+		"/source/SRI/Mojolicious-9.28/lib/Mojolicious.pm": true,
+		"/file/SRI/Mojolicious-9.28/lib/Mojolicious.pm":   true,
+		"/file/SRI/Mojolicious-9.28":                      false,
+		"changes/SRI/Mojolicious-9.28":                    true,
+		"/module/Mojolicious":                             false,
+		"/download_url/Mojolicious?version=9.28":          false,
+	} {
+		if got := ImmutablePath(path); got != expected {
+			t.Errorf("ImmutablePath(%q) = %v, expected %v", path,
+				got, expected)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package cassette provides an http.RoundTripper that records HTTP
+// interactions to a file and replays them later, so code talking to MetaCPAN
+// can be tested deterministically and without a network connection.
+//
+// Requests are matched on their method, path, query and body. Bodies that
+// are JSON (such as Elasticsearch search requests) are compared after
+// normalization, so key order and whitespace don't matter. Request headers
+// are neither recorded nor matched. When the same request is made more than
+// once, the recorded responses are replayed in the order they were recorded,
+// the last one being repeated once they run out; this is what makes scroll
+// IDs, which come back in one response and go out in the next request,
+// replay faithfully.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// Cassette is the on-disk form of a set of recorded interactions.
+type Cassette struct {
+	// Version is the format version of the file.
+	Version int `json:"version"`
+
+	// Interactions are the recorded request/response pairs, in the order
+	// they happened.
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the part of a recorded request used for matching.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   Body   `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is a request or response body. It's stored as a plain string when
+// it's valid UTF-8, so cassettes stay readable and diffable, and as base64
+// otherwise.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{
+		"base64": base64.StdEncoding.EncodeToString(b),
+	})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var v struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(v.Base64)
+	if err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+// key returns what the request is matched on.
+func (r *Request) key() string {
+	u, err := url.Parse(r.URL)
+	target := r.URL
+	if err == nil {
+		target = u.RequestURI()
+	}
+	return r.Method + " " + target + "\n" + string(normalizeBody(r.Body))
+}
+
+// normalizeBody re-encodes JSON bodies so that semantically equal bodies
+// compare equal. Anything else is returned as is.
+func normalizeBody(b []byte) []byte {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return b
+	}
+	// json.Marshal sorts map keys, which is all the normalizing needed
+	out, err := json.Marshal(v)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// Load reads a cassette from path.
+func Load(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if c.Version != formatVersion {
+		return nil, ErrVersion
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating any missing directories.
+func (c *Cassette) Save(path string) error {
+	c.Version = formatVersion
+	b, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+const (
+	formatVersion = 1
+)
+
+var (
+	// ErrVersion is returned by Load for cassettes written in a format
+	// this package doesn't understand.
+	ErrVersion = errors.New("cassette: unsupported format version")
+)
@@ -0,0 +1,203 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Mode says whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay answers every request from the cassette, and fails any
+	// request that wasn't recorded. The network is never touched.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends every request over the network and records it,
+	// replacing whatever the cassette held before.
+	ModeRecord
+
+	// ModeRecordMissing answers requests from the cassette where it can,
+	// and records the ones it can't.
+	ModeRecordMissing
+)
+
+// Recorder is an http.RoundTripper that records to and replays from a
+// cassette file. It's safe for concurrent use.
+type Recorder struct {
+	path     string
+	mode     Mode
+	next     http.RoundTripper
+	cassette *Cassette
+	// replay holds the recorded interactions for each request key, and
+	// used how many of them have been replayed so far.
+	replay  map[string][]*Interaction
+	used    map[string]int
+	changed bool
+	mu      sync.Mutex
+}
+
+// New returns a Recorder backed by the cassette at path. In ModeReplay the
+// cassette must already exist; check for os.ErrNotExist to tell a missing
+// cassette apart from a broken one. next is where requests are sent when
+// recording; if it's nil, http.DefaultTransport is used.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{
+		path:   path,
+		mode:   mode,
+		next:   next,
+		replay: make(map[string][]*Interaction),
+		used:   make(map[string]int),
+	}
+	switch mode {
+	case ModeRecord:
+		r.cassette = &Cassette{}
+		return r, nil
+	case ModeReplay, ModeRecordMissing:
+		c, err := Load(path)
+		switch {
+		case err == nil:
+			r.cassette = c
+		case mode == ModeRecordMissing && errors.Is(err, os.ErrNotExist):
+			r.cassette = &Cassette{}
+		default:
+			return nil, err
+		}
+	default:
+		return nil, ErrMode
+	}
+	for _, in := range r.cassette.Interactions {
+		k := in.Request.key()
+		r.replay[k] = append(r.replay[k], in)
+	}
+	return r, nil
+}
+
+// Mode returns the Recorder's mode.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	cr := Request{Method: req.Method, URL: req.URL.String(), Body: body}
+	if r.mode != ModeRecord {
+		if in, ok := r.lookup(cr.key()); ok {
+			return in.Response.toHTTP(req), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded,
+				req.Method, req.URL.RequestURI())
+		}
+	}
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := r.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	in := &Interaction{
+		Request: cr,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       respBody,
+		},
+	}
+	r.record(in)
+	return in.Response.toHTTP(req), nil
+}
+
+// Save writes any newly recorded interactions to the cassette file. It's a
+// no-op when nothing was recorded.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.changed {
+		return nil
+	}
+	if err := r.cassette.Save(r.path); err != nil {
+		return err
+	}
+	r.changed = false
+	return nil
+}
+
+func (r *Recorder) lookup(key string) (*Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ins := r.replay[key]
+	if len(ins) == 0 {
+		return nil, false
+	}
+	i := r.used[key]
+	if i >= len(ins) {
+		// replay the last response for as long as it keeps being asked
+		// for
+		return ins[len(ins)-1], true
+	}
+	r.used[key] = i + 1
+	return ins[i], true
+}
+
+func (r *Recorder) record(in *Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	k := in.Request.key()
+	r.replay[k] = append(r.replay[k], in)
+	r.used[k] = len(r.replay[k])
+	r.changed = true
+}
+
+func (r *Response) toHTTP(req *http.Request) *http.Response {
+	h := r.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set("Content-Length", strconv.Itoa(len(r.Body)))
+	return &http.Response{
+		Status: strconv.Itoa(r.StatusCode) + " " +
+			http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+var (
+	// ErrNotRecorded is returned in ModeReplay for requests that aren't
+	// in the cassette.
+	ErrNotRecorded = errors.New("cassette: no recorded interaction")
+
+	// ErrMode is returned by New for an unknown Mode.
+	ErrMode = errors.New("cassette: invalid mode")
+)
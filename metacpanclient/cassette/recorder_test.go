@@ -0,0 +1,132 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Call", string(rune('0'+n)))
+			_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " +
+				string(body)))
+		}))
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+	rec, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := &http.Client{Transport: rec}
+	tGet(t, hc, srv.URL+"/a")
+	tGet(t, hc, srv.URL+"/a")
+	tPost(t, hc, srv.URL+"/_search", `{"b": 1, "a": [1, 2]}`)
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	rec, err = New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc = &http.Client{Transport: rec}
+	// repeated requests come back in order, then the last one sticks
+	for _, expected := range []string{"1", "2", "2"} {
+		resp, err := hc.Get("http://elsewhere.invalid/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if got := resp.Header.Get("X-Call"); got != expected {
+			t.Errorf("expected call %s, got %s", expected, got)
+		}
+	}
+	// key order and whitespace in JSON bodies don't matter
+	body := tPost(t, hc, "http://elsewhere.invalid/_search",
+		`{"a":[1,2],"b":1}`)
+	if !strings.HasPrefix(body, "POST /_search ") {
+		t.Errorf("unexpected body %q", body)
+	}
+	_, err = hc.Get("http://elsewhere.invalid/missing")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded, got %v", err)
+	}
+}
+
+func TestNew_missing(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := New(path, ModeReplay, nil); !errors.Is(err,
+		os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+	rec, err := New(path, ModeRecordMissing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nothing recorded, so nothing written
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no cassette to be written")
+	}
+	if _, err = New(path, Mode(42), nil); err != ErrMode {
+		t.Errorf("expected ErrMode, got %v", err)
+	}
+}
+
+func TestBody(t *testing.T) {
+	t.Parallel()
+	for _, b := range []Body{Body("plain text"), Body{0xff, 0xfe, 0x00}} {
+		data, err := b.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Body
+		if err = got.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(b) {
+			t.Errorf("round trip of %q gave %q", b, got)
+		}
+	}
+}
+
+func tGet(t *testing.T, hc *http.Client, url string) string {
+	resp, err := hc.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func tPost(t *testing.T, hc *http.Client, url, body string) string {
+	resp, err := hc.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -5,11 +5,9 @@ package metacpanclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 	"sync"
@@ -30,61 +28,109 @@ Client is the main interface to the MetaCPAN API. It is equivalent to
 [MetaCPAN::Client]: https://metacpan.org/pod/MetaCPAN::Client
 */
 type Client struct {
-	auto         *transport
-	domains      []string
-	es           *elasticsearch.Client
-	post         *transport
-	scrollCh     chan string
-	scrolls      map[string]mortal
-	scrollsMu    sync.Mutex
-	stopCh       chan struct{}
-	stopScrollCh chan struct{}
-	timeout      time.Duration
-	userAgent    string
-	debug        bool
-	size         uint16
+	cache          Cache
+	cancel         context.CancelFunc
+	ctx            context.Context
+	domains        []string
+	es             map[string]*elasticsearch.Client
+	hc             *http.Client
+	health         *domainHealth
+	immutable      func(path string) bool
+	limiter        *limiter
+	logger         Logger
+	retry          RetryPolicy
+	scrollCh       chan string
+	scrolls        map[string]mortal
+	scrollsMu      sync.Mutex
+	stopCh         chan struct{}
+	stopScrollCh   chan struct{}
+	timeout        time.Duration
+	requestTimeout time.Duration
+	userAgent      string
+	debug          bool
+	size           uint16
 }
 
 func (mc *Client) AllAuthors() (ResultSet[*Author], error) {
-	return rsSearch[*Author](mc, map[string]interface{}{
+	return mc.AllAuthorsContext(context.Background())
+}
+
+func (mc *Client) AllAuthorsContext(ctx context.Context) (ResultSet[*Author],
+	error) {
+	return rsSearch[*Author](ctx, mc, map[string]interface{}{
 		"match_all": map[string]interface{}{},
 	})
 }
 
 func (mc *Client) AllDistributions() (ResultSet[*Distribution], error) {
-	return rsSearch[*Distribution](mc, map[string]interface{}{
+	return mc.AllDistributionsContext(context.Background())
+}
+
+func (mc *Client) AllDistributionsContext(ctx context.Context) (ResultSet[*Distribution],
+	error) {
+	return rsSearch[*Distribution](ctx, mc, map[string]interface{}{
 		"match_all": map[string]interface{}{},
 	})
 }
 
 func (mc *Client) AllFavorites() (ResultSet[*Favorite], error) {
-	return rsSearch[*Favorite](mc, map[string]interface{}{
+	return mc.AllFavoritesContext(context.Background())
+}
+
+func (mc *Client) AllFavoritesContext(ctx context.Context) (ResultSet[*Favorite],
+	error) {
+	return rsSearch[*Favorite](ctx, mc, map[string]interface{}{
 		"match_all": map[string]interface{}{},
 	})
 }
 
 func (mc *Client) AllModules() (ResultSet[*Module], error) {
-	return rsSearch[*Module](mc, map[string]interface{}{
+	return mc.AllModulesContext(context.Background())
+}
+
+func (mc *Client) AllModulesContext(ctx context.Context) (ResultSet[*Module],
+	error) {
+	return rsSearch[*Module](ctx, mc, map[string]interface{}{
 		"match_all": map[string]interface{}{},
 	})
 }
 
 func (mc *Client) AllReleases() (ResultSet[*Release], error) {
-	return rsSearch[*Release](mc, map[string]interface{}{
+	return mc.AllReleasesContext(context.Background())
+}
+
+func (mc *Client) AllReleasesContext(ctx context.Context) (ResultSet[*Release],
+	error) {
+	return rsSearch[*Release](ctx, mc, map[string]interface{}{
 		"match_all": map[string]interface{}{},
 	})
 }
 
 func (mc *Client) Author(s string) (*Author, error) {
-	return getResult[*Author](mc, "/author", s)
+	return mc.AuthorContext(context.Background(), s)
+}
+
+func (mc *Client) AuthorContext(ctx context.Context, s string) (*Author,
+	error) {
+	return getResult[*Author](ctx, mc, "/author", s)
 }
 
 func (mc *Client) AuthorSearch(args map[string]interface{}) (
 	ResultSet[*Author], error) {
-	return rsSearch[*Author](mc, args)
+	return mc.AuthorSearchContext(context.Background(), args)
+}
+
+func (mc *Client) AuthorSearchContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Author], error) {
+	return rsSearch[*Author](ctx, mc, args)
 }
 
 func (mc *Client) Autocomplete(s string) ([]*File, error) {
+	return mc.AutocompleteContext(context.Background(), s)
+}
+
+func (mc *Client) AutocompleteContext(ctx context.Context, s string) ([]*File,
+	error) {
 	sb := strings.Builder{}
 	sb.WriteString("q=")
 	sb.WriteString(s)
@@ -95,7 +141,7 @@ func (mc *Client) Autocomplete(s string) ([]*File, error) {
 				Fields *File `json:"fields"`
 			} `json:"hits"`
 		}
-	}](mc, "/search/autocomplete", "", sb.String())
+	}](ctx, mc, "/search/autocomplete", "", sb.String())
 	if err != nil {
 		return nil, err
 	}
@@ -112,38 +158,66 @@ func (mc *Client) Autocomplete(s string) ([]*File, error) {
 }
 
 func (mc *Client) AutocompleteSuggest(s string) ([]*File, error) {
+	return mc.AutocompleteSuggestContext(context.Background(), s)
+}
+
+func (mc *Client) AutocompleteSuggestContext(ctx context.Context,
+	s string) ([]*File, error) {
 	sb := strings.Builder{}
 	sb.WriteString("q=")
 	sb.WriteString(s)
 	req, err := query[struct {
 		Suggestions []*File `json:"suggestions"`
-	}](mc, "/search/autocomplete/suggest", "", sb.String())
+	}](ctx, mc, "/search/autocomplete/suggest", "", sb.String())
 	if err != nil {
 		return nil, err
 	}
 	return req.Suggestions, nil
 }
 
+// Close stops the client's background work: scrolls still open are cleared,
+// and sliced result sets stop reading their slices.
 func (mc *Client) Close() error {
+	mc.cancel()
 	mc.stopCh <- struct{}{}
 	return nil
 }
 
 func (mc *Client) Cover(s string) (*Cover, error) {
-	return get[*Cover](mc, "/cover", s)
+	return mc.CoverContext(context.Background(), s)
+}
+
+func (mc *Client) CoverContext(ctx context.Context, s string) (*Cover,
+	error) {
+	return get[*Cover](ctx, mc, "/cover", s)
 }
 
 func (mc *Client) Distribution(s string) (*Distribution, error) {
-	return getResult[*Distribution](mc, "/distribution", s)
+	return mc.DistributionContext(context.Background(), s)
+}
+
+func (mc *Client) DistributionContext(ctx context.Context, s string) (*Distribution,
+	error) {
+	return getResult[*Distribution](ctx, mc, "/distribution", s)
 }
 
 func (mc *Client) DistributionSearch(args map[string]interface{}) (
 	ResultSet[*Distribution], error) {
-	return rsSearch[*Distribution](mc, args)
+	return mc.DistributionSearchContext(context.Background(), args)
+}
+
+func (mc *Client) DistributionSearchContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Distribution], error) {
+	return rsSearch[*Distribution](ctx, mc, args)
 }
 
 func (mc *Client) DownloadURL(release string, r *version.Range,
 	dev bool) (*DownloadURL, error) {
+	return mc.DownloadURLContext(context.Background(), release, r, dev)
+}
+
+func (mc *Client) DownloadURLContext(ctx context.Context, release string,
+	r *version.Range, dev bool) (*DownloadURL, error) {
 	qb := strings.Builder{}
 	haveVersion := r != nil
 	if haveVersion {
@@ -155,41 +229,80 @@ func (mc *Client) DownloadURL(release string, r *version.Range,
 	} else if dev {
 		qb.WriteString("dev=1")
 	}
-	return query[*DownloadURL](mc, "/download_url", release, qb.String())
+	return query[*DownloadURL](ctx, mc, "/download_url", release, qb.String())
 }
 
 func (mc *Client) Favorite(args map[string]interface{}) (ResultSet[*Favorite],
 	error) {
-	return rsSearch[*Favorite](mc, args)
+	return mc.FavoriteContext(context.Background(), args)
+}
+
+func (mc *Client) FavoriteContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Favorite], error) {
+	return rsSearch[*Favorite](ctx, mc, args)
 }
 
 func (mc *Client) File(s string) (*File, error) {
-	return getResult[*File](mc, "/file", s)
+	return mc.FileContext(context.Background(), s)
+}
+
+func (mc *Client) FileContext(ctx context.Context, s string) (*File,
+	error) {
+	return getResult[*File](ctx, mc, "/file", s)
 }
 
 func (mc *Client) Mirror(s string) (*Mirror, error) {
-	return get[*Mirror](mc, "/mirror", s)
+	return mc.MirrorContext(context.Background(), s)
+}
+
+func (mc *Client) MirrorContext(ctx context.Context, s string) (*Mirror,
+	error) {
+	return get[*Mirror](ctx, mc, "/mirror", s)
 }
 
 func (mc *Client) Module(s string) (*Module, error) {
-	return getResult[*Module](mc, "/module", s)
+	return mc.ModuleContext(context.Background(), s)
+}
+
+func (mc *Client) ModuleContext(ctx context.Context, s string) (*Module,
+	error) {
+	return getResult[*Module](ctx, mc, "/module", s)
 }
 
 func (mc *Client) ModuleSearch(args map[string]interface{}) (
 	ResultSet[*Module], error) {
-	return rsSearch[*Module](mc, args)
+	return mc.ModuleSearchContext(context.Background(), args)
+}
+
+func (mc *Client) ModuleSearchContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Module], error) {
+	return rsSearch[*Module](ctx, mc, args)
 }
 
 func (mc *Client) Package(s string) (*Package, error) {
-	return get[*Package](mc, "/package", s)
+	return mc.PackageContext(context.Background(), s)
+}
+
+func (mc *Client) PackageContext(ctx context.Context, s string) (*Package,
+	error) {
+	return get[*Package](ctx, mc, "/package", s)
 }
 
 func (mc *Client) Permission(s string) (*Permission, error) {
-	return get[*Permission](mc, "/permission", s)
+	return mc.PermissionContext(context.Background(), s)
+}
+
+func (mc *Client) PermissionContext(ctx context.Context, s string) (*Permission,
+	error) {
+	return get[*Permission](ctx, mc, "/permission", s)
 }
 
 func (mc *Client) Pod(s string) (*Pod, error) {
-	pod, err := NewPod(s, "", mc)
+	return mc.PodContext(context.Background(), s)
+}
+
+func (mc *Client) PodContext(ctx context.Context, s string) (*Pod, error) {
+	pod, err := NewPodContext(ctx, s, "", mc)
 	if err != nil {
 		return nil, err
 	}
@@ -198,11 +311,21 @@ func (mc *Client) Pod(s string) (*Pod, error) {
 
 func (mc *Client) Rating(args map[string]interface{}) (ResultSet[*Rating],
 	error) {
-	return rsSearch[*Rating](mc, args)
+	return mc.RatingContext(context.Background(), args)
+}
+
+func (mc *Client) RatingContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Rating], error) {
+	return rsSearch[*Rating](ctx, mc, args)
 }
 
 func (mc *Client) Recent(count uint16) (ResultSet[*Release], error) {
-	return mc.recent(&searchConfig{
+	return mc.RecentContext(context.Background(), count)
+}
+
+func (mc *Client) RecentContext(ctx context.Context, count uint16) (
+	ResultSet[*Release], error) {
+	return mc.recent(ctx, &searchConfig{
 		query: map[string]interface{}{
 			"match_all": map[string]interface{}{},
 		},
@@ -218,16 +341,31 @@ func (mc *Client) Recent(count uint16) (ResultSet[*Release], error) {
 }
 
 func (mc *Client) Release(s string) (*Release, error) {
-	return getResult[*Release](mc, "/release", s)
+	return mc.ReleaseContext(context.Background(), s)
+}
+
+func (mc *Client) ReleaseContext(ctx context.Context, s string) (*Release,
+	error) {
+	return getResult[*Release](ctx, mc, "/release", s)
 }
 
 func (mc *Client) ReleaseSearch(args map[string]interface{}) (
 	ResultSet[*Release], error) {
-	return rsSearch[*Release](mc, args)
+	return mc.ReleaseSearchContext(context.Background(), args)
+}
+
+func (mc *Client) ReleaseSearchContext(ctx context.Context,
+	args map[string]interface{}) (ResultSet[*Release], error) {
+	return rsSearch[*Release](ctx, mc, args)
 }
 
 func (mc *Client) ReleasedToday() (ResultSet[*Release], error) {
-	return mc.recent(&searchConfig{
+	return mc.ReleasedTodayContext(context.Background())
+}
+
+func (mc *Client) ReleasedTodayContext(ctx context.Context) (
+	ResultSet[*Release], error) {
+	return mc.recent(ctx, &searchConfig{
 		query: map[string]interface{}{
 			"match_all": map[string]interface{}{},
 		},
@@ -249,6 +387,11 @@ func (mc *Client) ReleasedToday() (ResultSet[*Release], error) {
 }
 
 func (mc *Client) ReverseDependencies(s string) (ResultSet[*Release], error) {
+	return mc.ReverseDependenciesContext(context.Background(), s)
+}
+
+func (mc *Client) ReverseDependenciesContext(ctx context.Context, s string) (
+	ResultSet[*Release], error) {
 	params := map[string]interface{}{
 		"size": mc.size,
 		"query": map[string]interface{}{
@@ -275,7 +418,7 @@ func (mc *Client) ReverseDependencies(s string) (ResultSet[*Release], error) {
 	}
 	s = strings.ReplaceAll(s, "::", "-")
 	url := buildRequestURL("/reverse_dependencies/dist", s, "")
-	rc, err := mc.request("", url, body)
+	rc, err := mc.request(ctx, "", url, body)
 	defer pui.CloseBody(rc)
 	if err != nil {
 		return nil, err
@@ -291,22 +434,8 @@ func (mc *Client) ReverseDependencies(s string) (ResultSet[*Release], error) {
 
 // private methods
 
-func (mc *Client) doRequest(domain, path string, body []byte,
-	rc *io.ReadCloser, eb *strings.Builder) bool {
-	var err error
-	var resp *http.Response
-	defer func() {
-		if err != nil {
-			eb.WriteString("request to domain '")
-			eb.WriteString(domain)
-			eb.WriteString("' failed: ")
-			eb.WriteString(err.Error())
-			eb.WriteRune('\n')
-			if resp != nil {
-				pui.CloseBody(resp.Body)
-			}
-		}
-	}()
+func (mc *Client) doRequest(ctx context.Context, domain, path string,
+	body []byte) (io.ReadCloser, error) {
 	b := (io.Reader)(nil)
 	if body != nil {
 		b = bytes.NewReader(body)
@@ -316,55 +445,164 @@ func (mc *Client) doRequest(domain, path string, body []byte,
 	if !strings.HasSuffix(domain, "/") {
 		ub.WriteRune('/')
 	}
-	ub.WriteString(path)
-	var req *http.Request
-	if b == nil {
-		req, err = http.NewRequest(http.MethodGet, ub.String(), b)
-	} else {
-		req, err = http.NewRequest(http.MethodPost, ub.String(), b)
+	ub.WriteString(strings.TrimPrefix(path, "/"))
+	method := http.MethodGet
+	if b != nil {
+		method = http.MethodPost
 	}
+	req, err := http.NewRequestWithContext(ctx, method, ub.String(), b)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	if resp, err = mc.auto.RoundTrip(req); err != nil {
-		return false
+	var key string
+	var cached *CacheEntry
+	if mc.cache != nil {
+		key = cacheKey(method, ub.String(), body)
+		if e, ok := mc.cache.Get(key); ok {
+			if e.fresh(time.Now()) {
+				return io.NopCloser(bytes.NewReader(e.Body)), nil
+			}
+			cached = e
+			if e.ETag != "" {
+				req.Header.Set("If-None-Match", e.ETag)
+			}
+			if e.LastModified != "" {
+				req.Header.Set("If-Modified-Since",
+					e.LastModified)
+			}
+		}
 	}
-	if resp.StatusCode < 300 {
-		*rc = resp.Body
-		return true
+	resp, err := mc.hc.Do(req)
+	if err != nil {
+		return nil, err
 	}
-	if err == nil {
-		err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		pui.CloseBody(resp.Body)
+		// copied, as other requests may be holding the same entry
+		e := *cached
+		mc.storeCached(key, &e, resp.Header)
+		return io.NopCloser(bytes.NewReader(e.Body)), nil
+	case resp.StatusCode == http.StatusOK && mc.cache != nil:
+		defer pui.CloseBody(resp.Body)
+		buf, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		mc.storeCached(key, &CacheEntry{
+			Body:      buf,
+			Immutable: mc.immutable != nil && mc.immutable(path),
+		}, resp.Header)
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	case resp.StatusCode < 300:
+		return resp.Body, nil
+	}
+	defer pui.CloseBody(resp.Body)
+	return nil, newAPIError(domain, path, resp.StatusCode, resp.Header,
+		resp.Body)
+}
+
+// storeCached updates e from a response's headers and caches it, or drops it
+// from the cache if the headers say it can't be kept.
+func (mc *Client) storeCached(key string, e *CacheEntry, h http.Header) {
+	if e.update(h, time.Now()) {
+		mc.cache.Set(key, e)
+	} else {
+		mc.cache.Delete(key)
+	}
+}
+
+// retryDomain calls do against domain until it succeeds or the Client's
+// RetryPolicy gives up, keeping the domain's health up to date.
+func retryDomain[T any](ctx context.Context, mc *Client, domain string,
+	do func(ctx context.Context, domain string) (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		v, err := do(ctx, domain)
+		if err == nil {
+			mc.health.success(domain)
+			return v, nil
+		}
+		d, retry := mc.retry.wait(attempt, err)
+		if !retry {
+			if mc.retry.transient(err) {
+				mc.health.failure(domain)
+			}
+			var null T
+			return null, err
+		}
+		if ctxErr := sleepContext(ctx, d); ctxErr != nil {
+			var null T
+			return null, ctxErr
+		}
+	}
+}
+
+// eachDomain calls do against each of the Client's domains in turn, those
+// the circuit breaker hasn't tripped first, retrying each as the
+// RetryPolicy says, until one succeeds. If domain isn't empty, it's tried
+// before the rest. If they all fail, the error is a *RequestError.
+func eachDomain[T any](ctx context.Context, mc *Client, domain, path string,
+	do func(ctx context.Context, domain string) (T, error)) (T, error) {
+	var null T
+	domains := mc.health.order(mc.domains)
+	if domain != "" {
+		domains = append([]string{domain}, domains...)
+	}
+	re := &RequestError{}
+	for _, domain := range domains {
+		// no sense trying the next domain if the caller has given up
+		if err := ctx.Err(); err != nil {
+			return null, err
+		}
+		v, err := retryDomain(ctx, mc, domain, do)
+		if err == nil {
+			return v, nil
+		}
+		re.Attempts = append(re.Attempts, &DomainError{
+			Domain: domain,
+			Path:   path,
+			Err:    err,
+		})
+	}
+	if err := ctx.Err(); err != nil {
+		return null, err
 	}
-	return false
+	return null, re
 }
 
 func (mc *Client) killScroll(id string, early bool) {
 	mc.scrollsMu.Lock()
-	defer mc.scrollsMu.Unlock()
-	if scroll, ok := mc.scrolls[id]; ok {
-		switch dead, err := scroll.kill(early); {
-		case err != nil:
-			log.Println(err)
-			fallthrough
-		case dead:
-			delete(mc.scrolls, id)
-		default:
-			// scroll is still alive, so we'll try again later
-			if !early {
-				mc.registerScroll(scroll)
-			}
+	scroll, ok := mc.scrolls[id]
+	mc.scrollsMu.Unlock()
+	if !ok {
+		return
+	}
+	// not holding scrollsMu here, as registerScroll needs it and kill may
+	// have to wait on a scroll that's in the middle of registering itself
+	switch dead, err := scroll.kill(early); {
+	case err != nil:
+		mc.logger.Printf("%s", err)
+		fallthrough
+	case dead:
+		mc.scrollsMu.Lock()
+		delete(mc.scrolls, id)
+		mc.scrollsMu.Unlock()
+	default:
+		// scroll is still alive, so we'll try again later
+		if !early {
+			mc.registerScroll(scroll)
 		}
 	}
 }
 
-func (mc *Client) recent(sc *searchConfig) (*resultSetFetch[*Release], error) {
+func (mc *Client) recent(ctx context.Context,
+	sc *searchConfig) (*resultSetFetch[*Release], error) {
 	sc.mc = mc
 	s, err := newSearch[*Release](sc)
 	if err != nil {
 		return nil, err
 	}
-	rs, err := s.do()
+	rs, err := s.do(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -393,19 +631,34 @@ func (mc *Client) registerScroll(scroll mortal) {
 	}()
 }
 
-func (mc *Client) request(domain, path string, body []byte) (io.ReadCloser,
-	error) {
-	eb := strings.Builder{}
-	var rc io.ReadCloser
-	if domain != "" && mc.doRequest(domain, path, body, &rc, &eb) {
+func (mc *Client) request(ctx context.Context, domain, path string,
+	body []byte) (io.ReadCloser, error) {
+	return eachDomain(ctx, mc, domain, path,
+		func(ctx context.Context, domain string) (io.ReadCloser, error) {
+			return mc.doRequest(ctx, domain, path, body)
+		})
+}
+
+// requestDomain is like request, but only tries domain. Continuing a
+// scroll has to go to the domain that opened it, as the scroll's ID only
+// exists on that cluster.
+func (mc *Client) requestDomain(ctx context.Context, domain, path string,
+	body []byte) (io.ReadCloser, error) {
+	rc, err := retryDomain(ctx, mc, domain,
+		func(ctx context.Context, domain string) (io.ReadCloser, error) {
+			return mc.doRequest(ctx, domain, path, body)
+		})
+	if err == nil {
 		return rc, nil
 	}
-	for _, domain := range mc.domains {
-		if mc.doRequest(domain, path, body, &rc, &eb) {
-			return rc, nil
-		}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
 	}
-	return nil, errors.New(strings.Trim(eb.String(), "\n"))
+	return nil, &RequestError{Attempts: []*DomainError{{
+		Domain: domain,
+		Path:   path,
+		Err:    err,
+	}}}
 }
 
 func (mc *Client) scrollKiller() {
@@ -430,7 +683,7 @@ func (mc *Client) stopper() {
 		go func(v mortal) {
 			_, err := v.kill(true)
 			if err != nil {
-				log.Println(err)
+				mc.logger.Printf("%s", err)
 			}
 		}(v)
 	}
@@ -452,60 +705,106 @@ func (mc *Client) UserAgent() string {
 	return mc.userAgent
 }
 
-// NewClient returns a new MetaCPAN client. If domain is empty, the default
-// domain is used ("https://fastapi.metacpan.org").
-func NewClient(debug bool, scrollSize uint16, scrollTime time.Duration,
-	userAgent string, domains ...string) (*Client, error) {
-	const (
-		defaultUserAgent = "perl_utils.metacpan.client/" +
-			pui.PackageVersion
-		defaultScrollSize = 100
-		defaultScrollTime = 5 * time.Minute
-	)
-	if scrollSize == 0 {
-		scrollSize = defaultScrollSize
-	}
-	if scrollTime == 0 {
-		scrollTime = defaultScrollTime
-	}
-	if userAgent == "" {
-		userAgent = defaultUserAgent
-	}
-	if domains == nil {
-		domains = []string{"https://fastapi.metacpan.org/v1"}
+// LimiterStats returns how long requests have spent waiting on the rate
+// limit and in-flight cap so far.
+func (mc *Client) LimiterStats() LimiterStats {
+	return mc.limiter.snapshot()
+}
+
+// New returns a new MetaCPAN client configured by opts. With no options, it
+// talks to MetaCPANURL using http.DefaultTransport.
+func New(opts ...Option) (*Client, error) {
+	conf := newClientConfig(opts)
+	if err := conf.validate(); err != nil {
+		return nil, err
 	}
-	for i, domain := range domains {
+	domains := make([]string, len(conf.domains))
+	for i, domain := range conf.domains {
 		d := strings.TrimSuffix(domain, "/")
 		// d = strings.TrimSuffix(d, APIVersion)
 		domains[i] = strings.TrimSuffix(d, "/")
 	}
-	auto := newTransport(userAgent, false, debug)
-	post := newTransport(userAgent, true, debug)
-	esConf := elasticsearch.Config{Addresses: domains, Transport: post}
-	es, err := elasticsearch.NewClient(esConf)
-	if err != nil {
-		return nil, err
+	base := conf.baseTransport()
+	lim := newLimiter(conf.rateLimit, conf.rateBurst, conf.maxInFlight)
+	auto := newTransport(base, lim, conf.logger, conf.userAgent, false,
+		conf.debug)
+	post := newTransport(base, lim, conf.logger, conf.userAgent, true,
+		conf.debug)
+	hc := &http.Client{}
+	if conf.httpClient != nil {
+		*hc = *conf.httpClient
+	}
+	hc.Transport = auto
+	if conf.timeout > 0 {
+		hc.Timeout = conf.timeout
+	}
+	// one per domain, so that searches fail over and retry the way other
+	// requests do, rather than by Elasticsearch's own rules
+	es := make(map[string]*elasticsearch.Client, len(domains))
+	for _, domain := range domains {
+		c, err := elasticsearch.NewClient(elasticsearch.Config{
+			Addresses:    []string{domain},
+			Transport:    post,
+			DisableRetry: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		es[domain] = c
 	}
 	killCh := make(chan struct{})
 	killScrollCh := make(chan struct{})
 	scrollCh := make(chan string)
+	// cancelled by Close, for anything still working on the client's
+	// behalf once its caller has moved on
+	ctx, cancel := context.WithCancel(context.Background())
 	mc := &Client{
-		domains:      domains,
-		debug:        debug,
-		size:         scrollSize,
-		timeout:      scrollTime,
-		es:           es,
-		auto:         auto,
-		post:         post,
-		stopCh:       killCh,
-		stopScrollCh: killScrollCh,
-		scrollCh:     scrollCh,
+		cache:          conf.cache,
+		cancel:         cancel,
+		ctx:            ctx,
+		domains:        domains,
+		debug:          conf.debug,
+		size:           conf.scrollSize,
+		timeout:        conf.scrollTime,
+		requestTimeout: conf.timeout,
+		userAgent:      conf.userAgent,
+		es:             es,
+		hc:             hc,
+		health:         newDomainHealth(&conf.retry),
+		immutable:      conf.immutable,
+		limiter:        lim,
+		logger:         conf.logger,
+		retry:          conf.retry,
+		stopCh:         killCh,
+		stopScrollCh:   killScrollCh,
+		scrollCh:       scrollCh,
 	}
 	go mc.scrollKiller()
 	go mc.stopper()
 	return mc, nil
 }
 
+// NewClient returns a new MetaCPAN client. If domain is empty, the default
+// domain is used ("https://fastapi.metacpan.org"). It's equivalent to calling
+// New with WithDebug, WithScrollSize, WithScrollTime, WithUserAgent and
+// WithDomains.
+func NewClient(debug bool, scrollSize uint16, scrollTime time.Duration,
+	userAgent string, domains ...string) (*Client, error) {
+	return New(
+		WithDebug(debug),
+		WithScrollSize(scrollSize),
+		WithScrollTime(scrollTime),
+		WithUserAgent(userAgent),
+		WithDomains(domains...),
+	)
+}
+
 const (
 	MetaCPANURL = "https://fastapi.metacpan.org/v1"
 )
+
+const (
+	defaultUserAgent  = "perl_utils.metacpan.client/" + pui.PackageVersion
+	defaultScrollSize = 100
+	defaultScrollTime = 5 * time.Minute
+)
@@ -1,12 +1,19 @@
 package metacpanclient
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	// local
+	"github.com/cmburn/perlutils/metacpanclient/cassette"
 	"github.com/cmburn/perlutils/version"
 
 	// external
@@ -232,6 +239,32 @@ func TestClient_Author(t *testing.T) {
 	}
 }
 
+func TestClient_AuthorContext(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request for %s", r.URL)
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = mc.AuthorContext(ctx, "SRI")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	_, err = mc.AuthorSearchContext(ctx, map[string]interface{}{
+		"name": "Sebastian Riedel",
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 func TestClient_AuthorSearch(t *testing.T) {
 	t.Parallel()
 	mc := tNewClient(t)
@@ -378,7 +411,7 @@ func TestClient_DownloadURL(t *testing.T) {
 	if !strings.HasPrefix(d.DownloadURL, mojoPrefix) {
 		t.Errorf("unexpected download URL")
 	}
-	vr := version.MustParseRange("9.27")
+	vr := version.MustParseRange("== 9.27")
 	// Test with a specific version
 	d, err = mc.DownloadURL(mojo, vr, false)
 	if err != nil {
@@ -593,14 +626,61 @@ func tCloseClient(mc *Client, t *testing.T) {
 	}
 }
 
+// tNewClient returns a client whose requests are answered from the test's
+// cassette under testdata/cassettes. A test without a cassette is skipped,
+// unless METACPAN_CASSETTE is "replay", when it fails. Set it to "record"
+// to re-record cassettes against the live API, "missing" to record only
+// requests that aren't in them yet, or "live" to bypass them altogether.
 func tNewClient(t *testing.T) *Client {
-	mc, err := NewClient(debugClient, 0, 0, "", tDomains...)
+	opts := []Option{WithDebug(debugClient), WithDomains(tDomains...)}
+	if tCassetteMode != "live" {
+		rec := tNewRecorder(t)
+		opts = append(opts, WithRoundTripper(rec))
+		if rec.Mode() == cassette.ModeReplay {
+			// replayed failures won't go any differently the second time
+			opts = append(opts, WithRetryPolicy(RetryPolicy{}))
+		}
+	}
+	mc, err := New(opts...)
 	if err != nil {
 		t.Fatal(err)
 	}
 	return mc
 }
 
+func tNewRecorder(t *testing.T) *cassette.Recorder {
+	mode := cassette.ModeReplay
+	switch tCassetteMode {
+	case "", "replay":
+	case "record":
+		mode = cassette.ModeRecord
+	case "missing":
+		mode = cassette.ModeRecordMissing
+	default:
+		t.Fatalf("unknown METACPAN_CASSETTE mode %q", tCassetteMode)
+	}
+	name := strings.ReplaceAll(t.Name(), "/", "_")
+	path := filepath.Join("testdata", "cassettes", name+".json")
+	rec, err := cassette.New(path, mode, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("no cassette at %s; record it with "+
+			"METACPAN_CASSETTE=record", path)
+		if tCassetteMode == "replay" {
+			t.Fatal(msg)
+		}
+		t.Skip(msg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return rec
+}
+
 func init() {
 	sri, err := iso8601.ParseString("2016-03-27T18:27:34")
 	if err != nil {
@@ -610,11 +690,13 @@ func init() {
 	if v, ok := os.LookupEnv("METACPAN_DOMAINS"); ok {
 		tDomains = strings.Split(v, ",")
 	}
+	tCassetteMode = os.Getenv("METACPAN_CASSETTE")
 }
 
 var (
-	sriUpdated time.Time
-	tDomains   []string
+	sriUpdated    time.Time
+	tDomains      []string
+	tCassetteMode string
 )
 
 const (
@@ -0,0 +1,338 @@
+// Package depgraph resolves the dependencies of CPAN distributions
+// transitively, producing a graph of the releases needed and an order to
+// install them in.
+//
+// Modules and releases are looked up through a Source, which can be
+// MetaCPAN (through metacpanclient, and so also a fakeserver.Server), or
+// anything else that can answer the same questions, such as a local copy of
+// the package index:
+//
+//	mc, err := metacpanclient.New()
+//	...
+//	g, err := depgraph.New(depgraph.MetaCPAN(mc)).Resolve("Moose")
+//	...
+//	for _, n := range g.InstallOrder() {
+//		fmt.Println(n.Release.Name)
+//	}
+//
+// Resolution fails with a *CycleError if releases depend on each other, and
+// with a *ConflictError if a dependency can't be met or a release conflicts
+// with another in the graph. Modules that come with perl itself are not
+// followed.
+package depgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	// local
+	mcc "github.com/cmburn/perlutils/metacpanclient"
+	"github.com/cmburn/perlutils/version"
+)
+
+// Resolver resolves dependency graphs. It caches what it looks up, so
+// reusing one for several resolutions saves lookups, but it isn't safe for
+// concurrent use.
+type Resolver struct {
+	source        Source
+	phases        map[mcc.PhaseKind]bool
+	relationships map[mcc.RelationshipKind]bool
+	modules       map[string]*Module
+	releases      map[string]*Release
+}
+
+// Option configures a Resolver. See New.
+type Option func(*Resolver)
+
+// WithPhases sets which phases' dependencies are followed. The default is
+// configure, build, test and runtime.
+func WithPhases(phases ...mcc.PhaseKind) Option {
+	return func(r *Resolver) {
+		r.phases = make(map[mcc.PhaseKind]bool, len(phases))
+		for _, p := range phases {
+			r.phases[p] = true
+		}
+	}
+}
+
+// WithRelationships sets which relationships are followed. The default is
+// just requires. Conflicts are always checked, for the phases being
+// followed, whether or not they're given.
+func WithRelationships(rels ...mcc.RelationshipKind) Option {
+	return func(r *Resolver) {
+		r.relationships = make(map[mcc.RelationshipKind]bool,
+			len(rels))
+		for _, rel := range rels {
+			r.relationships[rel] = true
+		}
+	}
+}
+
+// New returns a Resolver that looks things up in src.
+func New(src Source, opts ...Option) *Resolver {
+	r := &Resolver{
+		source:   src,
+		modules:  make(map[string]*Module),
+		releases: make(map[string]*Release),
+	}
+	WithPhases(mcc.PhaseKindConfigure, mcc.PhaseKindBuild,
+		mcc.PhaseKindTest, mcc.PhaseKindRuntime)(r)
+	WithRelationships(mcc.RelationshipKindRequires)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Resolve resolves the dependencies of each of names, which can be either
+// modules or distributions.
+func (r *Resolver) Resolve(names ...string) (*Graph, error) {
+	return r.ResolveContext(context.Background(), names...)
+}
+
+// ResolveContext is like Resolve, but with a context.
+func (r *Resolver) ResolveContext(ctx context.Context, names ...string) (
+	*Graph, error) {
+	if r.source == nil {
+		return nil, errNilSource
+	}
+	rs := &resolution{
+		Resolver: r,
+		ctx:      ctx,
+		graph:    &Graph{nodes: make(map[string]*Node)},
+		provided: make(map[string]*Module),
+		visiting: make(map[string]bool),
+	}
+	for _, name := range names {
+		dist, err := rs.distribution(name)
+		if err != nil {
+			return nil, err
+		}
+		n, err := rs.visit(dist)
+		if err != nil {
+			return nil, err
+		}
+		rs.graph.Roots = append(rs.graph.Roots, n)
+	}
+	if err := rs.checkConflicts(); err != nil {
+		return nil, err
+	}
+	return rs.graph, nil
+}
+
+// resolution is the state of a single call to ResolveContext.
+type resolution struct {
+	*Resolver
+	ctx   context.Context
+	graph *Graph
+
+	// provided are the modules that dependencies have been resolved to
+	provided map[string]*Module
+
+	// visiting and stack are the distributions currently being resolved
+	visiting map[string]bool
+	stack    []string
+}
+
+// distribution returns the distribution for a name given to Resolve.
+func (rs *resolution) distribution(name string) (string, error) {
+	m, err := rs.module(name)
+	switch {
+	case err == nil:
+		return m.Distribution, nil
+	case errors.Is(err, mcc.ErrNotFound):
+		return strings.ReplaceAll(name, "::", "-"), nil
+	default:
+		return "", err
+	}
+}
+
+// visit resolves dist and everything it depends on, returning its node.
+func (rs *resolution) visit(dist string) (*Node, error) {
+	if n, ok := rs.graph.nodes[dist]; ok {
+		return n, nil
+	}
+	rs.visiting[dist] = true
+	rs.stack = append(rs.stack, dist)
+	rel, err := rs.release(dist)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{Release: rel}
+	for _, dep := range rel.Dependencies {
+		if !rs.follows(dep) {
+			continue
+		}
+		m, err := rs.module(dep.Module)
+		if err != nil {
+			return nil, fmt.Errorf("%s requires %s: %w", rel.Name,
+				dep.Module, err)
+		}
+		if isCore(m) {
+			continue
+		}
+		if dep.Range != nil && !dep.Range.Contains(versionOf(m)) {
+			return nil, &ConflictError{
+				Release: rel.Name,
+				Module:  m.Name,
+				Range:   dep.Range,
+				Version: *versionOf(m),
+			}
+		}
+		rs.provided[m.Name] = m
+		if m.Distribution == dist {
+			// provided by the release itself
+			continue
+		}
+		if rs.visiting[m.Distribution] {
+			return nil, rs.cycle(m.Distribution)
+		}
+		to, err := rs.visit(m.Distribution)
+		if err != nil {
+			return nil, err
+		}
+		n.Edges = append(n.Edges, &Edge{Dependency: dep, Module: m,
+			To: to})
+	}
+	rs.stack = rs.stack[:len(rs.stack)-1]
+	delete(rs.visiting, dist)
+	rs.graph.nodes[dist] = n
+	// everything n depends on has been added by now
+	rs.graph.order = append(rs.graph.order, n)
+	return n, nil
+}
+
+// follows reports whether dep should be followed.
+func (rs *resolution) follows(dep Dependency) bool {
+	if dep.Relationship == mcc.RelationshipKindConflicts {
+		return false
+	}
+	return rs.phases[dep.Phase] && rs.relationships[dep.Relationship]
+}
+
+// cycle returns the error for a dependency on dist, which is already being
+// resolved.
+func (rs *resolution) cycle(dist string) error {
+	i := len(rs.stack) - 1
+	for i > 0 && rs.stack[i] != dist {
+		i--
+	}
+	path := append([]string(nil), rs.stack[i:]...)
+	return &CycleError{Path: append(path, dist)}
+}
+
+// checkConflicts checks the conflicts declared by every release in the
+// graph against the modules the graph provides.
+func (rs *resolution) checkConflicts() error {
+	for _, n := range rs.graph.order {
+		for _, dep := range n.Release.Dependencies {
+			if !rs.phases[dep.Phase] || dep.Relationship !=
+				mcc.RelationshipKindConflicts {
+				continue
+			}
+			m, ok := rs.provided[dep.Module]
+			if !ok {
+				continue
+			}
+			v := versionOf(m)
+			if dep.Range == nil || dep.Range.Contains(v) {
+				return &ConflictError{
+					Release:   n.Release.Name,
+					Module:    m.Name,
+					Range:     dep.Range,
+					Version:   *v,
+					Conflicts: true,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (rs *resolution) module(name string) (*Module, error) {
+	if m, ok := rs.modules[name]; ok {
+		return m, nil
+	}
+	m, err := rs.source.Module(rs.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rs.modules[name] = m
+	return m, nil
+}
+
+func (rs *resolution) release(dist string) (*Release, error) {
+	if rel, ok := rs.releases[dist]; ok {
+		return rel, nil
+	}
+	rel, err := rs.source.Release(rs.ctx, dist)
+	if err != nil {
+		return nil, fmt.Errorf("release of %s: %w", dist, err)
+	}
+	rs.releases[dist] = rel
+	return rel, nil
+}
+
+// isCore reports whether m comes with perl, and so needn't be installed.
+func isCore(m *Module) bool {
+	return m.Name == "perl" || m.Distribution == "perl"
+}
+
+// versionOf returns the version of m, treating a module without a version
+// as version 0.
+func versionOf(m *Module) *version.Version {
+	if m.Version.Raw() == "" {
+		v := version.Undef()
+		return &v
+	}
+	return &m.Version
+}
+
+// CycleError is returned when releases depend on each other.
+type CycleError struct {
+	// Path is the chain of distributions that depend on each other,
+	// starting and ending with the same one.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// ConflictError is returned when a dependency can't be met by the indexed
+// version of a module, or when a release conflicts with a module in the
+// graph.
+type ConflictError struct {
+	// Release is the name of the release with the dependency.
+	Release string
+
+	// Module is the module depended on.
+	Module string
+
+	// Range is the versions of the module required, or if Conflicts is
+	// set, the versions conflicted with. It's nil if every version
+	// conflicts.
+	Range *version.Range
+
+	// Version is the version of the module that would be installed.
+	Version version.Version
+
+	// Conflicts is set if the release declared a conflict with the
+	// module, rather than requiring it.
+	Conflicts bool
+}
+
+func (e *ConflictError) Error() string {
+	if e.Conflicts {
+		return fmt.Sprintf("%s conflicts with %s %s", e.Release,
+			e.Module, e.Version.Stringify())
+	}
+	return fmt.Sprintf("%s requires %s %s, but only %s is available",
+		e.Release, e.Module, e.Range, e.Version.Stringify())
+}
+
+var (
+	errNilSource = errors.New("source cannot be nil")
+)
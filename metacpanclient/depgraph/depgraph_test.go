@@ -0,0 +1,280 @@
+package depgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	// local
+	mcc "github.com/cmburn/perlutils/metacpanclient"
+	"github.com/cmburn/perlutils/metacpanclient/fakeserver"
+	"github.com/cmburn/perlutils/version"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Parallel()
+	src := tSource{
+		"App-Foo 1.0": {"Moo >= 2", "Try::Tiny", "strict",
+			"test:Test::More", "develop:Dist::Zilla"},
+		"Moo 2.004":       {"Role::Tiny >= 2", "Sub::Quote"},
+		"Sub-Quote 2.006": {"Role::Tiny"},
+		"Role-Tiny 2.002": nil,
+		"Try-Tiny 0.31":   nil,
+		"Test-Simple 1.3": nil,
+	}
+	g, err := New(src).Resolve("App::Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tNames(g.InstallOrder())
+	expected := "Role-Tiny-2.002 Sub-Quote-2.006 Moo-2.004 Try-Tiny-0.31 " +
+		"Test-Simple-1.3 App-Foo-1.0"
+	if got != expected {
+		t.Errorf("expected install order %s, got %s", expected, got)
+	}
+	if len(g.Roots) != 1 || g.Roots[0] != g.Node("App-Foo") {
+		t.Errorf("unexpected roots %v", g.Roots)
+	}
+	if n := g.Node("Moo"); n == nil || len(n.Edges) != 2 ||
+		n.Edges[0].To != g.Node("Role-Tiny") ||
+		n.Edges[1].Module.Name != "Sub::Quote" {
+		t.Errorf("unexpected node %#v", n)
+	}
+	if len(g.Nodes()) != 6 || g.Nodes()[0].Release.Distribution !=
+		"App-Foo" {
+		t.Errorf("unexpected nodes %s", tNames(g.Nodes()))
+	}
+	g, err = New(src, WithPhases(mcc.PhaseKindRuntime)).Resolve("Moo",
+		"Try-Tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = tNames(g.InstallOrder())
+	expected = "Role-Tiny-2.002 Sub-Quote-2.006 Moo-2.004 Try-Tiny-0.31"
+	if got != expected {
+		t.Errorf("expected install order %s, got %s", expected, got)
+	}
+	_, err = New(src).Resolve("No::Such::Module")
+	if !errors.Is(err, mcc.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestResolver_Resolve_cycle(t *testing.T) {
+	t.Parallel()
+	src := tSource{
+		"A 1": {"B"},
+		"B 1": {"C"},
+		"C 1": {"test:A"},
+	}
+	_, err := New(src).Resolve("A")
+	var ce *CycleError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+	if got := strings.Join(ce.Path, " "); got != "A B C A" {
+		t.Errorf("unexpected cycle %s", got)
+	}
+	// without the test phase there's no cycle
+	g, err := New(src, WithPhases(mcc.PhaseKindRuntime)).Resolve("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tNames(g.InstallOrder()); got != "C-1 B-1 A-1" {
+		t.Errorf("unexpected install order %s", got)
+	}
+}
+
+func TestResolver_Resolve_conflict(t *testing.T) {
+	t.Parallel()
+	src := tSource{
+		"A 1":   {"B >= 2"},
+		"B 1.5": nil,
+		"C 1":   {"B", "D"},
+		"D 1":   {"conflicts:B < 2"},
+		"E 1":   {"recommends:A", "B"},
+	}
+	var ce *ConflictError
+	_, err := New(src).Resolve("A")
+	if !errors.As(err, &ce) || ce.Conflicts || ce.Module != "B" ||
+		ce.Release != "A-1" {
+		t.Errorf("expected a ConflictError, got %v", err)
+	}
+	_, err = New(src).Resolve("C")
+	if !errors.As(err, &ce) || !ce.Conflicts || ce.Release != "D-1" {
+		t.Errorf("expected a ConflictError, got %v", err)
+	}
+	// recommends aren't followed unless asked for
+	if _, err = New(src).Resolve("E"); err != nil {
+		t.Error(err)
+	}
+	_, err = New(src, WithRelationships(mcc.RelationshipKindRequires,
+		mcc.RelationshipKindRecommends)).Resolve("E")
+	if !errors.As(err, &ce) {
+		t.Errorf("expected a ConflictError, got %v", err)
+	}
+}
+
+func TestMetaCPAN(t *testing.T) {
+	t.Parallel()
+	role := &mcc.Module{}
+	role.Documentation = "Moose::Role"
+	role.Distribution = "Moose"
+	role.Release = "Moose-2.2200"
+	role.Version = version.JSON{Version: version.MustParse("2.2200")}
+	role.Maturity = mcc.Maturity{Kind: mcc.MaturityKindReleased}
+	role.Status = mcc.ReleaseStatus{Kind: mcc.ReleaseStatusKindLatest}
+	foo := &mcc.Module{}
+	foo.Documentation = "MooseX::Foo"
+	foo.Distribution = "MooseX-Foo"
+	foo.Release = "MooseX-Foo-0.01"
+	foo.Version = version.JSON{Version: version.MustParse("0.01")}
+	foo.Maturity = role.Maturity
+	foo.Status = role.Status
+	s, err := fakeserver.New(&fakeserver.Dataset{
+		Modules: []*mcc.Module{role, foo},
+		Releases: []*mcc.Release{
+			tRelease("Moose", "2.2200"),
+			tRelease("MooseX-Foo", "0.01", "Moose::Role"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	mc, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mc.Close() }()
+	g, err := New(MetaCPAN(mc)).Resolve("MooseX::Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tNames(g.InstallOrder()); got !=
+		"Moose-2.2200 MooseX-Foo-0.01" {
+		t.Errorf("unexpected install order %s", got)
+	}
+	e := g.Node("MooseX-Foo").Edges
+	if len(e) != 1 || e[0].Dependency.Range.String() != "2.2" {
+		t.Errorf("unexpected edges %#v", e)
+	}
+	_, err = New(MetaCPAN(mc)).Resolve("No::Such")
+	if !errors.Is(err, mcc.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// tSource is a Source built from releases written as "DIST VERSION",
+// mapped to their dependencies. Each release provides a module named after
+// its distribution. Dependencies are written as
+// "[phase:][relationship:]MODULE[ RANGE]", and default to runtime requires;
+// a dependency on strict is a dependency on perl.
+type tSource map[string][]string
+
+func (s tSource) Module(_ context.Context, name string) (*Module, error) {
+	if name == "strict" {
+		return &Module{Name: name, Distribution: "perl"}, nil
+	}
+	if name == "Test::More" {
+		return &Module{Name: name, Version: version.MustParse("1.3"),
+			Distribution: "Test-Simple"}, nil
+	}
+	dist := strings.ReplaceAll(name, "::", "-")
+	for k := range s {
+		d, v, _ := strings.Cut(k, " ")
+		if d == dist {
+			return &Module{
+				Name:         name,
+				Version:      version.MustParse(v),
+				Distribution: d,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("module %s: %w", name, mcc.ErrNotFound)
+}
+
+func (s tSource) Release(_ context.Context, dist string) (*Release, error) {
+	for k, deps := range s {
+		d, v, _ := strings.Cut(k, " ")
+		if d != dist {
+			continue
+		}
+		rel := &Release{Name: d + "-" + v, Distribution: d,
+			Version: version.MustParse(v)}
+		for _, dep := range deps {
+			rel.Dependencies = append(rel.Dependencies,
+				tDependency(dep))
+		}
+		return rel, nil
+	}
+	return nil, fmt.Errorf("release %s: %w", dist, mcc.ErrNotFound)
+}
+
+func tDependency(s string) Dependency {
+	d := Dependency{
+		Phase:        mcc.PhaseKindRuntime,
+		Relationship: mcc.RelationshipKindRequires,
+	}
+	for {
+		prefix, rest, ok := strings.Cut(s, ":")
+		if !ok || strings.HasPrefix(rest, ":") {
+			break
+		}
+		if p, err := d.Phase.Parse(prefix); err == nil {
+			d.Phase = p
+		} else if r, err := d.Relationship.Parse(prefix); err == nil {
+			d.Relationship = r
+		} else {
+			break
+		}
+		s = rest
+	}
+	name, rng, ok := strings.Cut(s, " ")
+	d.Module = name
+	if ok {
+		d.Range = version.MustParseRange(rng)
+	}
+	return d
+}
+
+func tRelease(dist, ver string, deps ...string) *mcc.Release {
+	r := &mcc.Release{
+		Author:       "ETHER",
+		Distribution: dist,
+		Name:         dist + "-" + ver,
+		Version:      version.JSON{Version: version.MustParse(ver)},
+		Maturity:     mcc.Maturity{Kind: mcc.MaturityKindReleased},
+		Status: mcc.ReleaseStatus{
+			Kind: mcc.ReleaseStatusKindLatest,
+		},
+	}
+	for _, d := range deps {
+		r.Dependency = append(r.Dependency, struct {
+			Phase        mcc.Phase        `json:"phase"`
+			Relationship mcc.Relationship `json:"relationship"`
+			Module       string           `json:"module"`
+			Version      version.JSON     `json:"version"`
+		}{
+			Phase: mcc.Phase{Kind: mcc.PhaseKindRuntime},
+			Relationship: mcc.Relationship{
+				Kind: mcc.RelationshipKindRequires,
+			},
+			Module: d,
+			Version: version.JSON{
+				Version: version.MustParse("2.2"),
+			},
+		})
+	}
+	return r
+}
+
+func tNames(nodes []*Node) string {
+	names := make([]string, len(nodes))
+	for i, n := range nodes {
+		names[i] = n.Release.Name
+	}
+	return strings.Join(names, " ")
+}
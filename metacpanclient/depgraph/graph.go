@@ -0,0 +1,62 @@
+package depgraph
+
+import (
+	"sort"
+)
+
+// Graph is a resolved dependency graph. It never has cycles.
+type Graph struct {
+	// Roots are the nodes for what was asked to be resolved, in the order
+	// asked for.
+	Roots []*Node
+
+	nodes map[string]*Node
+	order []*Node
+}
+
+// Node is a release in a Graph.
+type Node struct {
+	// Release is the release to be installed.
+	Release *Release
+
+	// Edges are the release's dependencies that were followed, in the
+	// order the release lists them.
+	Edges []*Edge
+}
+
+// Edge is a dependency from one release on a module in another.
+type Edge struct {
+	// Dependency is the dependency as the release lists it.
+	Dependency Dependency
+
+	// Module is the module that satisfies the dependency.
+	Module *Module
+
+	// To is the node for the release the module is in.
+	To *Node
+}
+
+// Node returns the node for a distribution, or nil if it isn't in the
+// graph.
+func (g *Graph) Node(dist string) *Node {
+	return g.nodes[dist]
+}
+
+// Nodes returns every node in the graph, sorted by distribution.
+func (g *Graph) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Release.Distribution <
+			nodes[j].Release.Distribution
+	})
+	return nodes
+}
+
+// InstallOrder returns every node in the graph in an order they can be
+// installed in, with each release coming after everything it depends on.
+func (g *Graph) InstallOrder() []*Node {
+	return append([]*Node(nil), g.order...)
+}
@@ -0,0 +1,127 @@
+package depgraph
+
+import (
+	"context"
+
+	// local
+	mcc "github.com/cmburn/perlutils/metacpanclient"
+	"github.com/cmburn/perlutils/version"
+)
+
+// Source is where a Resolver looks up modules and releases. Lookups for
+// things that don't exist should fail with an error matching
+// metacpanclient.ErrNotFound.
+type Source interface {
+	// Module returns the indexed version of a module, and the
+	// distribution it's in.
+	Module(ctx context.Context, name string) (*Module, error)
+
+	// Release returns the release of a distribution that should be
+	// installed, which is normally the latest.
+	Release(ctx context.Context, dist string) (*Release, error)
+}
+
+// Module is a module as listed in the package index.
+type Module struct {
+	// Name is the name of the module.
+	Name string
+
+	// Version is the indexed version of the module. It's the zero
+	// Version if the module doesn't have one.
+	Version version.Version
+
+	// Distribution is the name of the distribution the module is in.
+	Distribution string
+}
+
+// Release is a single release of a distribution.
+type Release struct {
+	// Name is the name of the release, such as "Moose-2.2200".
+	Name string
+
+	// Distribution is the name of the distribution, such as "Moose".
+	Distribution string
+
+	// Version is the version of the release.
+	Version version.Version
+
+	// Dependencies are the modules the release depends on, in every phase
+	// and relationship.
+	Dependencies []Dependency
+}
+
+// Dependency is a single prerequisite of a release.
+type Dependency struct {
+	// Module is the name of the module depended on.
+	Module string
+
+	// Phase is when the module is needed.
+	Phase mcc.PhaseKind
+
+	// Relationship is how strongly the module is needed. For
+	// RelationshipKindConflicts, Range is the versions that can't be
+	// installed alongside the release.
+	Relationship mcc.RelationshipKind
+
+	// Range is the versions of the module that will do, or nil for any
+	// version.
+	Range *version.Range
+}
+
+// MetaCPAN returns a Source that looks modules and releases up with mc.
+// Modules are looked up through their package index entries, and releases
+// are always the latest.
+func MetaCPAN(mc *mcc.Client) Source {
+	return &metaCPAN{mc: mc}
+}
+
+type metaCPAN struct {
+	mc *mcc.Client
+}
+
+func (s *metaCPAN) Module(ctx context.Context, name string) (*Module,
+	error) {
+	m, err := s.mc.ModuleContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := m.PackageContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Module{
+		Name:         name,
+		Version:      pkg.Version.Version,
+		Distribution: pkg.Distribution,
+	}, nil
+}
+
+func (s *metaCPAN) Release(ctx context.Context, dist string) (*Release,
+	error) {
+	r, err := s.mc.ReleaseContext(ctx, dist)
+	if err != nil {
+		return nil, err
+	}
+	rel := &Release{
+		Name:         r.Name,
+		Distribution: r.Distribution,
+		Version:      r.Version.Version,
+		Dependencies: make([]Dependency, 0, len(r.Dependency)),
+	}
+	for _, d := range r.Dependency {
+		var rng *version.Range
+		// a version of zero will do for any version, so it's nil
+		if v := &d.Version.Version; v.Raw() != "" && v.Numify() != 0 {
+			if rng, err = version.ParseRange(v.Raw()); err != nil {
+				return nil, err
+			}
+		}
+		rel.Dependencies = append(rel.Dependencies, Dependency{
+			Module:       d.Module,
+			Phase:        d.Phase.Kind,
+			Relationship: d.Relationship.Kind,
+			Range:        rng,
+		})
+	}
+	return rel, nil
+}
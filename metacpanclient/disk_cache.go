@@ -0,0 +1,79 @@
+package metacpanclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// diskCache is a Cache that keeps one file per entry in a directory. Nothing
+// is ever evicted; clear out the directory to reclaim space.
+type diskCache struct {
+	dir string
+}
+
+type diskCacheFile struct {
+	Key   string      `json:"key"`
+	Entry *CacheEntry `json:"entry"`
+}
+
+// NewDiskCache returns a Cache that stores responses as files under dir,
+// creating it if needed. It's safe to share a directory between processes.
+func NewDiskCache(dir string) (Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	// fan out a little, so no single directory gets too big
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+func (c *diskCache) Get(key string) (*CacheEntry, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var f diskCacheFile
+	if err = json.Unmarshal(b, &f); err != nil || f.Key != key ||
+		f.Entry == nil {
+		return nil, false
+	}
+	return f.Entry, true
+}
+
+func (c *diskCache) Set(key string, e *CacheEntry) {
+	b, err := json.Marshal(&diskCacheFile{Key: key, Entry: e})
+	if err != nil {
+		return
+	}
+	p := c.path(key)
+	if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return
+	}
+	// write to a temporary file first, so readers never see half an entry
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+func (c *diskCache) Delete(key string) {
+	_ = os.Remove(c.path(key))
+}
@@ -0,0 +1,190 @@
+package metacpanclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is returned when MetaCPAN answers a request with a non-success
+// status code. It matches ErrNotFound, ErrRateLimited and ErrServerError with
+// errors.Is, as appropriate for its StatusCode.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Domain is the MetaCPAN domain the request was sent to. It's empty
+	// for searches, since those are routed by the Elasticsearch client.
+	Domain string
+
+	// Path is the path (and query, if any) that was requested.
+	Path string
+
+	// Message is the error message MetaCPAN gave, if the body could be
+	// decoded.
+	Message string
+
+	// Body is the raw response body, truncated to maxErrorBodySize.
+	Body []byte
+
+	// RetryAfter is how long the server asked us to wait before trying
+	// again, if it sent a Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("unexpected status code %d", e.StatusCode))
+	if e.Path != "" {
+		sb.WriteString(" for ")
+		sb.WriteString(e.Path)
+	}
+	if e.Message != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Message)
+	}
+	return sb.String()
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= 500
+	default:
+		return false
+	}
+}
+
+// DomainError records a single failed attempt to reach a MetaCPAN domain.
+// Err is either an *APIError or whatever the underlying transport returned.
+type DomainError struct {
+	Domain string
+	Path   string
+	Err    error
+}
+
+func (e *DomainError) Error() string {
+	return "request to domain '" + e.Domain + "' failed: " + e.Err.Error()
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// RequestError is returned when a request failed against every domain the
+// Client tried. Attempts holds one entry per domain, in the order they were
+// tried. errors.Is and errors.As look through every attempt.
+type RequestError struct {
+	Attempts []*DomainError
+}
+
+func (e *RequestError) Error() string {
+	msgs := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		msgs[i] = a.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *RequestError) Is(target error) bool {
+	for _, a := range e.Attempts {
+		if errors.Is(a, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *RequestError) As(target interface{}) bool {
+	for _, a := range e.Attempts {
+		if errors.As(a, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError builds an *APIError from a response's status code and body,
+// consuming (but not closing) the body.
+func newAPIError(domain, path string, status int, header http.Header,
+	rd io.Reader) *APIError {
+	e := &APIError{
+		StatusCode: status,
+		Domain:     domain,
+		Path:       path,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"),
+			time.Now()),
+	}
+	if rd == nil {
+		return e
+	}
+	body, err := io.ReadAll(io.LimitReader(rd, maxErrorBodySize))
+	if err != nil {
+		return e
+	}
+	e.Body = body
+	e.Message = decodeErrorMessage(body)
+	return e
+}
+
+// decodeErrorMessage pulls a message out of an error body. MetaCPAN itself
+// answers with {"code": ..., "message": ...}, while errors passed through
+// from Elasticsearch look like {"error": {"reason": ...}, "status": ...}, or
+// in older versions {"error": "...", "status": ...}.
+func decodeErrorMessage(body []byte) string {
+	var v struct {
+		Message string          `json:"message"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return ""
+	}
+	if v.Message != "" {
+		return v.Message
+	}
+	if len(v.Error) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(v.Error, &s); err == nil {
+		return s
+	}
+	var obj struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(v.Error, &obj); err != nil {
+		return ""
+	}
+	if obj.Type != "" && obj.Reason != "" {
+		return obj.Type + ": " + obj.Reason
+	}
+	return obj.Type + obj.Reason
+}
+
+const (
+	maxErrorBodySize = 64 * 1024
+)
+
+var (
+	// ErrNotFound matches any *APIError with a 404 status code.
+	ErrNotFound = errors.New("not found")
+
+	// ErrRateLimited matches any *APIError with a 429 status code.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrServerError matches any *APIError with a 5xx status code.
+	ErrServerError = errors.New("server error")
+
+	// ErrScrollExpired is returned by Scroll.Next when the scroll has
+	// outlived its keepalive time.
+	ErrScrollExpired = errors.New("scroll timed out")
+)
@@ -0,0 +1,88 @@
+package metacpanclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestError(t *testing.T) {
+	t.Parallel()
+	down := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer down.Close()
+	missing := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":404,"message":"Not found"}`))
+		}))
+	defer missing.Close()
+	mc, err := New(WithDomains(down.URL, missing.URL),
+		WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	_, err = mc.Module("Not::A::Module")
+	var re *RequestError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected *RequestError, got %T", err)
+	}
+	if len(re.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(re.Attempts))
+	}
+	if re.Attempts[0].Domain != down.URL ||
+		re.Attempts[1].Domain != missing.URL {
+		t.Errorf("unexpected attempt order")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound")
+	}
+	if !errors.Is(err, ErrServerError) {
+		t.Errorf("expected ErrServerError")
+	}
+	if errors.Is(err, ErrRateLimited) {
+		t.Errorf("unexpected ErrRateLimited")
+	}
+	var ae *APIError
+	if !errors.As(re.Attempts[1], &ae) {
+		t.Fatalf("expected *APIError, got %T", re.Attempts[1].Err)
+	}
+	if ae.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status code %d", ae.StatusCode)
+	}
+	if ae.Message != "Not found" {
+		t.Errorf("unexpected message %q", ae.Message)
+	}
+	if ae.Path != "/module/Not::A::Module" {
+		t.Errorf("unexpected path %q", ae.Path)
+	}
+}
+
+func TestDecodeErrorMessage(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		body     string
+		expected string
+	}{
+		{`{"code":404,"message":"Not found"}`, "Not found"},
+		{`{"error":"IndexMissingException[[cpan] missing]","status":404}`,
+			"IndexMissingException[[cpan] missing]"},
+		{`{"error":{"type":"search_phase_execution_exception",` +
+			`"reason":"all shards failed"},"status":400}`,
+			"search_phase_execution_exception: all shards failed"},
+		{`{"error":{"reason":"all shards failed"}}`,
+			"all shards failed"},
+		{`<html>Bad Gateway</html>`, ""},
+		{``, ""},
+	} {
+		if got := decodeErrorMessage([]byte(tc.body)); got !=
+			tc.expected {
+			t.Errorf("decodeErrorMessage(%q) = %q, expected %q",
+				tc.body, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	// external
+	"github.com/relvacode/iso8601"
+)
+
+// aggregation is a single aggregation from a search body. Only one of the
+// aggregation types is expected to be set.
+type aggregation struct {
+	Terms *struct {
+		Field       string            `json:"field"`
+		Size        *int              `json:"size"`
+		MinDocCount *int              `json:"min_doc_count"`
+		Order       map[string]string `json:"order"`
+	} `json:"terms"`
+	DateHistogram *struct {
+		Field            string `json:"field"`
+		CalendarInterval string `json:"calendar_interval"`
+		FixedInterval    string `json:"fixed_interval"`
+		Interval         string `json:"interval"`
+		Format           string `json:"format"`
+		MinDocCount      int    `json:"min_doc_count"`
+	} `json:"date_histogram"`
+	Stats *struct {
+		Field string `json:"field"`
+	} `json:"stats"`
+	Aggs         map[string]json.RawMessage `json:"aggs"`
+	Aggregations map[string]json.RawMessage `json:"aggregations"`
+}
+
+// bucket is a group of documents from a bucket aggregation.
+type bucket struct {
+	key  interface{}
+	docs []*document
+}
+
+// aggregate computes aggs over docs.
+func aggregate(aggs map[string]json.RawMessage,
+	docs []*document) (map[string]interface{}, error) {
+	if len(aggs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(aggs))
+	for name, raw := range aggs {
+		var a aggregation
+		err := json.Unmarshal(raw, &a)
+		var res map[string]interface{}
+		if err == nil {
+			res, err = a.run(docs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("aggregation [%s]: %w", name,
+				err)
+		}
+		out[name] = res
+	}
+	return out, nil
+}
+
+func (a *aggregation) run(docs []*document) (map[string]interface{},
+	error) {
+	subs := a.Aggs
+	if subs == nil {
+		subs = a.Aggregations
+	}
+	switch {
+	case a.Terms != nil:
+		return a.runTerms(docs, subs)
+	case a.DateHistogram != nil:
+		return a.runDateHistogram(docs, subs)
+	case a.Stats != nil:
+		return stats(docs, a.Stats.Field), nil
+	default:
+		return nil, errMalformedQuery
+	}
+}
+
+func (a *aggregation) runTerms(docs []*document,
+	subs map[string]json.RawMessage) (map[string]interface{}, error) {
+	t := a.Terms
+	size, minDocCount := 10, 1
+	if t.Size != nil {
+		size = *t.Size
+	}
+	if t.MinDocCount != nil {
+		minDocCount = *t.MinDocCount
+	}
+	var buckets []*bucket
+	byKey := make(map[interface{}]*bucket)
+	for _, d := range docs {
+		seen := make(map[interface{}]bool)
+		for _, v := range lookup(d.source, t.Field) {
+			switch v.(type) {
+			case string, float64, bool:
+			default:
+				continue
+			}
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			b, ok := byKey[v]
+			if !ok {
+				b = &bucket{key: v}
+				byKey[v] = b
+				buckets = append(buckets, b)
+			}
+			b.docs = append(b.docs, d)
+		}
+	}
+	orderBy, order := "_count", "desc"
+	for k, v := range t.Order {
+		orderBy, order = k, v
+	}
+	if orderBy != "_count" && orderBy != "_key" {
+		return nil, fmt.Errorf("unsupported order [%s]", orderBy)
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		ni, nj := len(buckets[i].docs), len(buckets[j].docs)
+		if orderBy == "_count" && ni != nj {
+			if order == "asc" {
+				return ni < nj
+			}
+			return ni > nj
+		}
+		c, _ := compare(buckets[i].key, buckets[j].key)
+		if orderBy == "_key" && order == "desc" {
+			return c > 0
+		}
+		return c < 0
+	})
+	kept := make([]*bucket, 0, len(buckets))
+	other := 0
+	for _, b := range buckets {
+		switch {
+		case len(b.docs) < minDocCount:
+		case len(kept) < size:
+			kept = append(kept, b)
+		default:
+			other += len(b.docs)
+		}
+	}
+	res, err := writeBuckets(kept, subs, nil)
+	if err != nil {
+		return nil, err
+	}
+	res["doc_count_error_upper_bound"] = 0
+	res["sum_other_doc_count"] = other
+	return res, nil
+}
+
+func (a *aggregation) runDateHistogram(docs []*document,
+	subs map[string]json.RawMessage) (map[string]interface{}, error) {
+	h := a.DateHistogram
+	interval := h.CalendarInterval
+	if interval == "" {
+		interval = h.Interval
+	}
+	var floor func(time.Time) time.Time
+	var next func(time.Time) time.Time
+	switch {
+	case interval != "":
+		var ok bool
+		if floor, next, ok = calendarInterval(interval); !ok {
+			d, err := parseKeepAlive(interval)
+			if err != nil || d < time.Millisecond ||
+				h.CalendarInterval != "" {
+				return nil, fmt.Errorf("invalid interval [%s]",
+					interval)
+			}
+			floor, next = fixedInterval(d)
+		}
+	case h.FixedInterval != "":
+		d, err := parseKeepAlive(h.FixedInterval)
+		if err != nil || d < time.Millisecond {
+			return nil, fmt.Errorf("invalid interval [%s]",
+				h.FixedInterval)
+		}
+		floor, next = fixedInterval(d)
+	default:
+		return nil, fmt.Errorf("no interval given")
+	}
+	byKey := make(map[time.Time]*bucket)
+	var first, last time.Time
+	for _, d := range docs {
+		for _, v := range lookup(d.source, h.Field) {
+			t, ok := parseTime(v)
+			if !ok {
+				continue
+			}
+			k := floor(t)
+			b, ok := byKey[k]
+			if !ok {
+				b = &bucket{key: k}
+				byKey[k] = b
+			}
+			b.docs = append(b.docs, d)
+			if first.IsZero() || k.Before(first) {
+				first = k
+			}
+			if last.IsZero() || k.After(last) {
+				last = k
+			}
+			break
+		}
+	}
+	var buckets []*bucket
+	if len(byKey) > 0 {
+		for k := first; !k.After(last); k = next(k) {
+			b, ok := byKey[k]
+			if !ok {
+				b = &bucket{key: k}
+			}
+			if len(b.docs) >= h.MinDocCount {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+	layout := dateLayout(h.Format)
+	return writeBuckets(buckets, subs, func(b *bucket,
+		m map[string]interface{}) {
+		t := b.key.(time.Time)
+		m["key"] = t.UnixMilli()
+		m["key_as_string"] = t.Format(layout)
+	})
+}
+
+// writeBuckets writes the buckets of a bucket aggregation, along with their
+// sub-aggregations. If set isn't nil, it can change how each bucket is
+// written.
+func writeBuckets(buckets []*bucket, subs map[string]json.RawMessage,
+	set func(*bucket, map[string]interface{})) (map[string]interface{},
+	error) {
+	out := make([]interface{}, 0, len(buckets))
+	for _, b := range buckets {
+		m := map[string]interface{}{
+			"key":       b.key,
+			"doc_count": len(b.docs),
+		}
+		if set != nil {
+			set(b, m)
+		}
+		sub, err := aggregate(subs, b.docs)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range sub {
+			m[k] = v
+		}
+		out = append(out, m)
+	}
+	return map[string]interface{}{"buckets": out}, nil
+}
+
+func stats(docs []*document, f string) map[string]interface{} {
+	count := 0
+	var sum float64
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, d := range docs {
+		for _, v := range lookup(d.source, f) {
+			n, ok := v.(float64)
+			if !ok {
+				continue
+			}
+			count++
+			sum += n
+			min = math.Min(min, n)
+			max = math.Max(max, n)
+		}
+	}
+	if count == 0 {
+		return map[string]interface{}{
+			"count": 0,
+			"min":   nil,
+			"max":   nil,
+			"avg":   nil,
+			"sum":   0,
+		}
+	}
+	return map[string]interface{}{
+		"count": count,
+		"min":   min,
+		"max":   max,
+		"avg":   sum / float64(count),
+		"sum":   sum,
+	}
+}
+
+// calendarInterval returns functions to find the start of the calendar
+// interval a time is in, and the start of the one after it.
+func calendarInterval(interval string) (floor, next func(time.Time) time.Time,
+	ok bool) {
+	switch strings.TrimPrefix(interval, "1") {
+	case "minute", "m":
+		return func(t time.Time) time.Time {
+			return t.Truncate(time.Minute)
+		}, addDuration(time.Minute), true
+	case "hour", "h":
+		return func(t time.Time) time.Time {
+			return t.Truncate(time.Hour)
+		}, addDuration(time.Hour), true
+	case "day", "d":
+		return func(t time.Time) time.Time {
+			return date(t.Year(), t.Month(), t.Day())
+		}, addDate(0, 0, 1), true
+	case "week", "w":
+		return func(t time.Time) time.Time {
+			// weeks start on Monday
+			off := (int(t.Weekday()) + 6) % 7
+			return date(t.Year(), t.Month(), t.Day()-off)
+		}, addDate(0, 0, 7), true
+	case "month", "M":
+		return func(t time.Time) time.Time {
+			return date(t.Year(), t.Month(), 1)
+		}, addDate(0, 1, 0), true
+	case "quarter", "q":
+		return func(t time.Time) time.Time {
+			m := (t.Month()-1)/3*3 + 1
+			return date(t.Year(), m, 1)
+		}, addDate(0, 3, 0), true
+	case "year", "y":
+		return func(t time.Time) time.Time {
+			return date(t.Year(), time.January, 1)
+		}, addDate(1, 0, 0), true
+	default:
+		return nil, nil, false
+	}
+}
+
+func fixedInterval(d time.Duration) (floor, next func(time.Time) time.Time) {
+	return func(t time.Time) time.Time {
+		ms := d.Milliseconds()
+		return time.UnixMilli(t.UnixMilli() / ms * ms).UTC()
+	}, addDuration(d)
+}
+
+func addDuration(d time.Duration) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		return t.Add(d)
+	}
+}
+
+func addDate(years, months, days int) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		return t.AddDate(years, months, days)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// parseTime parses a date field, which is either an ISO 8601 string or a
+// number of milliseconds since the epoch.
+func parseTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case string:
+		t, err := iso8601.ParseString(x)
+		return t.UTC(), err == nil
+	case float64:
+		return time.UnixMilli(int64(x)).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// dateLayout converts the common parts of an Elasticsearch date format into
+// a Go time layout. An empty format gives Elasticsearch's default.
+func dateLayout(format string) string {
+	if format == "" {
+		return "2006-01-02T15:04:05.000Z"
+	}
+	return strings.NewReplacer("yyyy", "2006", "MM", "01", "dd", "02",
+		"HH", "15", "mm", "04", "ss", "05", "SSS", "000").Replace(
+		format)
+}
@@ -0,0 +1,325 @@
+// Package fakeserver provides an in-process stand-in for the MetaCPAN API,
+// so code built on metacpanclient can be tested without a network
+// connection.
+//
+// A Server is seeded with a Dataset of the same values metacpanclient
+// returns, and answers the lookup endpoints (/author, /release, /module,
+// /package, /file, /pod, /download_url and /reverse_dependencies/dist) as
+// well as Elasticsearch searches and scrolls against the author,
+// distribution, favorite, file, module, rating and release indices. Searches
+// understand the bool, term, terms, wildcard, prefix, range, exists and
+// match_all queries, which covers everything the either/all/not syntax
+// accepted by metacpanclient is translated into. Sliced scrolls are
+// supported too, with documents dealt out to slices in the order they were
+// seeded, as are terms, date_histogram and stats aggregations.
+//
+// Documents are served as the dataset's values marshal to JSON, less any
+// empty fields, so enum fields such as Maturity and Status have to be set for
+// a value to be accepted.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	// local
+	mcc "github.com/cmburn/perlutils/metacpanclient"
+)
+
+// Dataset is what a Server serves.
+type Dataset struct {
+	Authors       []*mcc.Author
+	Distributions []*mcc.Distribution
+	Favorites     []*mcc.Favorite
+	Files         []*mcc.File
+	Modules       []*mcc.Module
+	Ratings       []*mcc.Rating
+	Releases      []*mcc.Release
+
+	// Pods are served from /pod, by name.
+	Pods []*mcc.Pod
+}
+
+// Server is a fake MetaCPAN API listening on a local address. Close it
+// when done.
+type Server struct {
+	*httptest.Server
+	indices map[string][]*document
+	pods    map[string]*mcc.Pod
+	scrolls map[string]*scroll
+	nextID  int
+	mu      sync.Mutex
+}
+
+// document is a single seeded value, kept as decoded JSON so that queries
+// can be run against it the way Elasticsearch would.
+type document struct {
+	id     string
+	index  string
+	source map[string]interface{}
+}
+
+// New starts a Server serving ds. It fails if any of the values in ds can't
+// be marshalled to JSON.
+func New(ds *Dataset) (*Server, error) {
+	s := &Server{
+		indices: make(map[string][]*document),
+		pods:    make(map[string]*mcc.Pod),
+		scrolls: make(map[string]*scroll),
+	}
+	if ds == nil {
+		ds = &Dataset{}
+	}
+	for _, idx := range []struct {
+		name   string
+		values []interface{}
+	}{
+		{"author", values(ds.Authors)},
+		{"distribution", values(ds.Distributions)},
+		{"favorite", values(ds.Favorites)},
+		{"file", values(ds.Files)},
+		{"module", values(ds.Modules)},
+		{"rating", values(ds.Ratings)},
+		{"release", values(ds.Releases)},
+	} {
+		for i, v := range idx.values {
+			if err := s.add(idx.name, v); err != nil {
+				return nil, fmt.Errorf("%s %d: %w", idx.name, i,
+					err)
+			}
+		}
+	}
+	for _, p := range ds.Pods {
+		s.pods[p.Name] = p
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// Client returns a metacpanclient.Client talking to the Server. Any options
+// given are applied after the one pointing it at the Server.
+func (s *Server) Client(opts ...mcc.Option) (*mcc.Client, error) {
+	return mcc.New(append([]mcc.Option{mcc.WithDomains(s.URL)},
+		opts...)...)
+}
+
+func (s *Server) add(index string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var src map[string]interface{}
+	if err = json.Unmarshal(b, &src); err != nil {
+		return err
+	}
+	prune(src)
+	s.indices[index] = append(s.indices[index], &document{
+		id:     strconv.Itoa(len(s.indices[index]) + 1),
+		index:  index,
+		source: src,
+	})
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	endpoint, rest, _ := strings.Cut(path, "/")
+	switch {
+	case endpoint == "_search" && strings.HasPrefix(rest, "scroll"):
+		s.serveScroll(w, r, strings.TrimPrefix(
+			strings.TrimPrefix(rest, "scroll"), "/"))
+	case rest == "_search":
+		s.serveSearch(w, r, endpoint)
+	case endpoint == "author":
+		s.serveFind(w, "author", func(d *document) bool {
+			return field(d.source, "pauseid") == rest
+		})
+	case endpoint == "distribution":
+		s.serveFind(w, "distribution", func(d *document) bool {
+			return field(d.source, "name") == rest
+		})
+	case endpoint == "release":
+		s.serveRelease(w, rest)
+	case endpoint == "module":
+		s.serveFind(w, "module", func(d *document) bool {
+			return providesModule(d, rest)
+		})
+	case endpoint == "package":
+		s.servePackage(w, rest)
+	case endpoint == "file":
+		s.serveFile(w, rest)
+	case endpoint == "pod":
+		s.servePod(w, r, rest)
+	case endpoint == "download_url":
+		s.serveDownloadURL(w, r, rest)
+	case endpoint == "reverse_dependencies" &&
+		strings.HasPrefix(rest, "dist/"):
+		s.serveReverseDependencies(w, strings.TrimPrefix(rest, "dist/"))
+	default:
+		writeNotFound(w)
+	}
+}
+
+// serveFind serves the first document in index matching match.
+func (s *Server) serveFind(w http.ResponseWriter, index string,
+	match func(*document) bool) {
+	for _, d := range s.indices[index] {
+		if match(d) {
+			writeJSON(w, http.StatusOK, d.source)
+			return
+		}
+	}
+	writeNotFound(w)
+}
+
+// serveRelease serves either release/AUTHOR/NAME, or release/DIST for the
+// latest release of a distribution.
+func (s *Server) serveRelease(w http.ResponseWriter, rest string) {
+	if author, name, ok := strings.Cut(rest, "/"); ok {
+		s.serveFind(w, "release", func(d *document) bool {
+			return field(d.source, "author") == author &&
+				field(d.source, "name") == name
+		})
+		return
+	}
+	if d := s.latestRelease(rest); d != nil {
+		writeJSON(w, http.StatusOK, d.source)
+		return
+	}
+	writeNotFound(w)
+}
+
+// serveFile serves file/AUTHOR/RELEASE/PATH, looking through modules as
+// well as files, since on MetaCPAN every module is also a file.
+func (s *Server) serveFile(w http.ResponseWriter, rest string) {
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) != 3 {
+		writeNotFound(w)
+		return
+	}
+	match := func(d *document) bool {
+		return field(d.source, "author") == parts[0] &&
+			field(d.source, "release") == parts[1] &&
+			field(d.source, "path") == parts[2]
+	}
+	for _, index := range []string{"file", "module"} {
+		for _, d := range s.indices[index] {
+			if match(d) {
+				writeJSON(w, http.StatusOK, d.source)
+				return
+			}
+		}
+	}
+	writeNotFound(w)
+}
+
+func (s *Server) servePod(w http.ResponseWriter, r *http.Request,
+	name string) {
+	p, ok := s.pods[name]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	ct := r.URL.Query().Get("content-type")
+	var body string
+	// metacpanclient asks for the underscored variants, MetaCPAN documents
+	// the hyphenated ones
+	switch ct {
+	case "text/plain":
+		body = p.Plain
+	case "text/x_markdown", "text/x-markdown":
+		body = p.XMarkdown
+	case "text/x_pod", "text/x-pod":
+		body = p.XPod
+	default:
+		ct = "text/html"
+		body = p.HTML
+	}
+	w.Header().Set("Content-Type", ct+"; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+// latestRelease returns the release of dist whose status is latest, or
+// failing that the last one seeded.
+func (s *Server) latestRelease(dist string) *document {
+	var last *document
+	for _, d := range s.indices["release"] {
+		if field(d.source, "distribution") != dist {
+			continue
+		}
+		if field(d.source, "status") == "latest" {
+			return d
+		}
+		last = d
+	}
+	return last
+}
+
+// prune removes empty strings, nulls, and objects and arrays left empty by
+// that, from a document. MetaCPAN leaves out fields it has no value for,
+// rather than sending them empty, and some types (versions in particular)
+// don't accept an empty string.
+func prune(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case map[string]interface{}:
+		for k, el := range x {
+			if prune(el) {
+				delete(x, k)
+			}
+		}
+		return len(x) == 0
+	case []interface{}:
+		n := 0
+		for _, el := range x {
+			if !prune(el) {
+				n++
+			}
+		}
+		// arrays are kept as they are unless there's nothing left, as
+		// positions can matter
+		return n == 0
+	default:
+		return false
+	}
+}
+
+func values[T any](s []T) []interface{} {
+	v := make([]interface{}, len(s))
+	for i, x := range s {
+		v[i] = x
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]interface{}{
+		"code":    http.StatusNotFound,
+		"message": "Not found",
+	})
+}
+
+// writeESError writes an error the way Elasticsearch reports them.
+func writeESError(w http.ResponseWriter, status int, typ, reason string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   typ,
+			"reason": reason,
+		},
+		"status": status,
+	})
+}
@@ -0,0 +1,614 @@
+package fakeserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	// local
+	mcc "github.com/cmburn/perlutils/metacpanclient"
+	"github.com/cmburn/perlutils/metacpanclient/q"
+	"github.com/cmburn/perlutils/version"
+)
+
+func TestServer_lookups(t *testing.T) {
+	t.Parallel()
+	mc := tNewClient(t)
+	a, err := mc.Author("ETHER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "Karen Etheridge" {
+		t.Errorf("unexpected author %q", a.Name)
+	}
+	r, err := mc.Release("Moose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "Moose-2.2200" {
+		t.Errorf("expected latest release, got %s", r.Name)
+	}
+	if r, err = mc.Release("ETHER/Moose-2.2100"); err != nil {
+		t.Fatal(err)
+	} else if r.Version.Raw() != "2.2100" {
+		t.Errorf("unexpected version %s", r.Version.Raw())
+	}
+	m, err := mc.Module("Moose::Role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Path != "lib/Moose/Role.pm" {
+		t.Errorf("unexpected module path %s", m.Path)
+	}
+	f, err := mc.File("ETHER/Moose-2.2200/lib/Moose/Role.pm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Documentation != "Moose::Role" {
+		t.Errorf("unexpected file %s", f.Documentation)
+	}
+	p, err := mc.Pod("Moose::Role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Plain != "Moose::Role - The Moose Role" {
+		t.Errorf("unexpected plain pod %q", p.Plain)
+	}
+	if p.XPod != "=head1 NAME" {
+		t.Errorf("unexpected raw pod %q", p.XPod)
+	}
+	_, err = mc.Author("NOBODY")
+	if !errors.Is(err, mcc.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServer_downloadURL(t *testing.T) {
+	t.Parallel()
+	mc := tNewClient(t)
+	for _, tt := range []struct {
+		rng      string
+		dev      bool
+		expected string
+	}{
+		{"", false, "2.2200"},
+		{"", true, "2.2300_01"},
+		{"< 2.2200", false, "2.2100"},
+		{"== 2.2100", false, "2.2100"},
+		{"2.2100", false, "2.2200"},
+	} {
+		var r *version.Range
+		if tt.rng != "" {
+			r = version.MustParseRange(tt.rng)
+		}
+		u, err := mc.DownloadURL("Moose::Role", r, tt.dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Version.Raw() != tt.expected {
+			t.Errorf("DownloadURL(%q, %v): expected %s, got %s", tt.rng,
+				tt.dev, tt.expected, u.Version.Raw())
+		}
+	}
+}
+
+func TestServer_reverseDependencies(t *testing.T) {
+	t.Parallel()
+	mc := tNewClient(t)
+	rs, err := mc.ReverseDependencies("Moose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Total() != 1 {
+		t.Fatalf("expected 1 reverse dependency, got %d", rs.Total())
+	}
+	r, err := rs.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "MooseX-Foo-0.01" {
+		t.Errorf("unexpected reverse dependency %s", r.Name)
+	}
+}
+
+func TestServer_search(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name     string
+		query    map[string]interface{}
+		expected []string
+	}{
+		{"term", map[string]interface{}{
+			"author": "ETHER",
+		}, []string{"Moose-2.2100", "Moose-2.2200", "Moose-2.2300_01"}},
+		{"wildcard", map[string]interface{}{
+			"name": "MooseX-*",
+		}, []string{"MooseX-Foo-0.01"}},
+		{"either", map[string]interface{}{
+			"either": []interface{}{
+				map[string]interface{}{"name": "Moose-2.2100"},
+				map[string]interface{}{"author": "FOO"},
+			},
+		}, []string{"Moose-2.2100", "MooseX-Foo-0.01"}},
+		{"all", map[string]interface{}{
+			"all": []interface{}{
+				map[string]interface{}{"author": "ETHER"},
+				map[string]interface{}{"maturity": "released"},
+			},
+		}, []string{"Moose-2.2100", "Moose-2.2200"}},
+		{"not", map[string]interface{}{
+			"all": []interface{}{
+				map[string]interface{}{"author": "ETHER"},
+			},
+			"not": []interface{}{
+				map[string]interface{}{"status": "latest"},
+			},
+		}, []string{"Moose-2.2100", "Moose-2.2300_01"}},
+		{"q", q.All(
+			q.Range("date", q.Gte("2022"), q.Lt("2024")),
+			q.Prefix("name", "Moose-"),
+		), []string{"Moose-2.2200", "Moose-2.2300_01"}},
+		{"q sorted", q.NewSearch(q.Exists("author")).
+			Sort(q.Asc("author"), q.Desc("date")), []string{
+			"Moose-2.2300_01", "Moose-2.2200", "Moose-2.2100",
+			"MooseX-Foo-0.01",
+		}},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// a scroll size of one makes every result its own page
+			mc := tNewClient(t, mcc.WithScrollSize(1))
+			rs, err := mc.ReleaseSearch(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for r, err := rs.Next(); r != nil || err != nil; r,
+				err = rs.Next() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, r.Name)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected,
+						got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestServer_recent(t *testing.T) {
+	t.Parallel()
+	mc := tNewClient(t)
+	rs, err := mc.Recent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"MooseX-Foo-0.01",
+		"Moose-2.2300_01"} {
+		r, err := rs.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Name != expected {
+			t.Errorf("expected %s, got %s", expected, r.Name)
+		}
+	}
+}
+
+func TestCompileQuery(t *testing.T) {
+	t.Parallel()
+	d := &document{source: tDecode(t, `{
+		"name": "Moose",
+		"version_numified": 2.22,
+		"module": [{"name": "Moose"}, {"name": "Moose::Role"}],
+		"date": "2021-01-02T03:04:05",
+		"abstract": null
+	}`)}
+	for _, tt := range []struct {
+		query    string
+		expected bool
+	}{
+		{`{"match_all": {}}`, true},
+		{`{"term": {"name": "Moose"}}`, true},
+		{`{"term": {"name": {"value": "moose"}}}`, false},
+		{`{"terms": {"module.name": ["Foo", "Moose::Role"]}}`, true},
+		{`{"wildcard": {"module.name": "Moose::*"}}`, true},
+		{`{"wildcard": {"name": "M?ose"}}`, true},
+		{`{"prefix": {"name": "Moo"}}`, true},
+		{`{"range": {"version_numified": {"gte": 2, "lt": 3}}}`, true},
+		{`{"range": {"version_numified": {"gt": 2.22}}}`, false},
+		{`{"range": {"date": {"from": "2021-01-01"}}}`, true},
+		{`{"exists": {"field": "module"}}`, true},
+		{`{"exists": {"field": "abstract"}}`, false},
+		{`{"bool": {"should": [{"term": {"name": "x"}}]}}`, false},
+		{`{"bool": {"must": {"term": {"name": "Moose"}},
+			"should": [{"term": {"name": "x"}}]}}`, true},
+		{`{"bool": {"must_not": [{"term": {"name": "Moose"}}]}}`, false},
+		{`{"bool": {"should": [{"term": {"name": "x"}},
+			{"term": {"name": "Moose"}}],
+			"minimum_should_match": 1}}`, true},
+	} {
+		q, err := compileQuery(tDecode(t, tt.query))
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if got := q(d); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.expected,
+				got)
+		}
+	}
+	if _, err := compileQuery(tDecode(t, `{"fuzzy": {"a": "b"}}`)); err ==
+		nil {
+		t.Errorf("expected an error for an unknown query")
+	}
+}
+
+func tNewClient(t *testing.T, opts ...mcc.Option) *mcc.Client {
+	t.Helper()
+	s, err := New(tDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	mc, err := s.Client(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mc.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return mc
+}
+
+func tDecode(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func tRelease(author, dist, ver, date string, status mcc.ReleaseStatusKind,
+	deps ...string) *mcc.Release {
+	r := &mcc.Release{
+		Author:       author,
+		Distribution: dist,
+		Name:         dist + "-" + ver,
+		Date:         date,
+		Version:      version.JSON{Version: version.MustParse(ver)},
+		Maturity:     mcc.Maturity{Kind: mcc.MaturityKindReleased},
+		Status:       mcc.ReleaseStatus{Kind: status},
+		DownloadURL: "https://cpan.metacpan.org/authors/id/" + author +
+			"/" + dist + "-" + ver + ".tar.gz",
+	}
+	if r.Version.IsAlpha() {
+		r.Maturity.Kind = mcc.MaturityKindDeveloper
+	}
+	for _, d := range deps {
+		r.Dependency = append(r.Dependency, struct {
+			Phase        mcc.Phase        `json:"phase"`
+			Relationship mcc.Relationship `json:"relationship"`
+			Module       string           `json:"module"`
+			Version      version.JSON     `json:"version"`
+		}{
+			Phase: mcc.Phase{Kind: mcc.PhaseKindRuntime},
+			Relationship: mcc.Relationship{
+				Kind: mcc.RelationshipKindRequires,
+			},
+			Module:  d,
+			Version: version.JSON{Version: version.MustParse("0")},
+		})
+	}
+	return r
+}
+
+func tDataset() *Dataset {
+	role := &mcc.Module{}
+	role.Author = "ETHER"
+	role.Release = "Moose-2.2200"
+	role.Distribution = "Moose"
+	role.Documentation = "Moose::Role"
+	role.Path = "lib/Moose/Role.pm"
+	role.Version = version.JSON{Version: version.MustParse("2.2200")}
+	role.Maturity = mcc.Maturity{Kind: mcc.MaturityKindReleased}
+	role.Status = mcc.ReleaseStatus{Kind: mcc.ReleaseStatusKindLatest}
+	return &Dataset{
+		Authors: []*mcc.Author{
+			{PauseID: "ETHER", Name: "Karen Etheridge"},
+			{PauseID: "FOO", Name: "Foo"},
+		},
+		Releases: []*mcc.Release{
+			tRelease("ETHER", "Moose", "2.2100", "2021-01-01T00:00:00",
+				mcc.ReleaseStatusKindCPAN),
+			tRelease("ETHER", "Moose", "2.2200", "2022-01-01T00:00:00",
+				mcc.ReleaseStatusKindLatest),
+			tRelease("ETHER", "Moose", "2.2300_01",
+				"2023-01-01T00:00:00", mcc.ReleaseStatusKindCPAN),
+			tRelease("FOO", "MooseX-Foo", "0.01", "2024-01-01T00:00:00",
+				mcc.ReleaseStatusKindLatest, "Moose::Role"),
+		},
+		Modules: []*mcc.Module{role},
+		Pods: []*mcc.Pod{{
+			Name:      "Moose::Role",
+			HTML:      "<h1>NAME</h1>",
+			Plain:     "Moose::Role - The Moose Role",
+			XMarkdown: "# NAME",
+			XPod:      "=head1 NAME",
+		}},
+	}
+}
+
+func TestServer_scrollAll(t *testing.T) {
+	t.Parallel()
+	s, err := New(tDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	mc, err := s.Client(mcc.WithScrollSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mc.Close() }()
+	rs, err := mc.ReleaseSearch(q.Term("author", "ETHER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for r, err := range rs.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Author != "ETHER" {
+			t.Errorf("unexpected author %s", r.Author)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	s.mu.Lock()
+	open := len(s.scrolls)
+	s.mu.Unlock()
+	if open != 0 {
+		t.Errorf("expected the scroll to be cleared, %d still open", open)
+	}
+	rs, err = mc.ReleaseSearch(q.MatchAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := rs.Collect(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Errorf("expected 4 releases, got %d", len(items))
+	}
+}
+
+func TestServer_sliced(t *testing.T) {
+	t.Parallel()
+	s, err := New(tDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	mc, err := s.Client(mcc.WithScrollSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mc.Close() }()
+	rs, err := mc.ReleaseSearch(q.NewSearch(q.MatchAll()).Slices(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range rs.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	s.mu.Lock()
+	open := len(s.scrolls)
+	s.mu.Unlock()
+	if open != 0 {
+		t.Errorf("expected the scrolls to be cleared, %d still open", open)
+	}
+	rs, err = mc.ReleaseSearch(q.NewSearch(q.MatchAll()).Slices(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Total() != 4 {
+		t.Errorf("expected a total of 4, got %d", rs.Total())
+	}
+	seen := make(map[string]bool)
+	for r, err := range rs.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[r.Name] {
+			t.Errorf("%s returned twice", r.Name)
+		}
+		seen[r.Name] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 releases, got %d", len(seen))
+	}
+	for _, n := range []int{0, 1025} {
+		_, err = mc.ReleaseSearch(q.NewSearch(q.MatchAll()).Slices(n))
+		if err == nil {
+			t.Errorf("expected an error for %d slices", n)
+		}
+	}
+}
+
+func TestServer_aggregations(t *testing.T) {
+	t.Parallel()
+	ds := tDataset()
+	for _, r := range ds.Releases {
+		r.VersionNumified = r.Version.Numify()
+	}
+	s, err := New(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	mc, err := s.Client(mcc.WithScrollSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = mc.Close() }()
+	rs, err := mc.ReleaseSearch(map[string]interface{}{
+		"query": map[string]interface{}{"author": "ETHER"},
+		"aggregations": map[string]mcc.Aggregation{
+			"per_year": &mcc.DateHistogramAggregation{
+				Field:            "date",
+				CalendarInterval: "year",
+				Format:           "yyyy",
+			},
+			"versions": &mcc.StatsAggregation{
+				Field: "version_numified",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var years []string
+	for _, b := range rs.Aggregation("per_year").Buckets {
+		if b.DocCount != 1 {
+			t.Errorf("%s: expected 1 release, got %d", b,
+				b.DocCount)
+		}
+		years = append(years, b.String())
+	}
+	if len(years) != 3 || years[0] != "2021" || years[2] != "2023" {
+		t.Errorf("unexpected years %v", years)
+	}
+	st := rs.Aggregation("versions").Stats
+	if st == nil || st.Count != 3 || st.Min != 2.21 || st.Max <= 2.23 {
+		t.Errorf("unexpected stats %#v", st)
+	}
+	if _, ok := rs.Aggregations()["per_year"]; !ok {
+		t.Errorf("expected the raw aggregations to be kept")
+	}
+	if rs.Aggregation("missing") != nil {
+		t.Errorf("expected no result for an unknown aggregation")
+	}
+	rs, err = mc.ReleaseSearch(map[string]interface{}{
+		"query": map[string]interface{}(q.MatchAll()),
+		"aggregations": map[string]mcc.Aggregation{
+			"dists": &mcc.TermsAggregation{
+				Field: "distribution",
+				Size:  1,
+				Aggregations: map[string]mcc.Aggregation{
+					"per_author": &mcc.TermsAggregation{
+						Field: "author",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dists := rs.Aggregation("dists")
+	if len(dists.Buckets) != 1 || dists.Buckets[0].Key != "Moose" ||
+		dists.Buckets[0].DocCount != 3 || dists.SumOtherDocCount != 1 {
+		t.Fatalf("unexpected buckets %#v", dists)
+	}
+	authors := dists.Buckets[0].Aggregations["per_author"]
+	if authors == nil || len(authors.Buckets) != 1 ||
+		authors.Buckets[0].Key != "ETHER" {
+		t.Errorf("unexpected sub-aggregation %#v", authors)
+	}
+	_, err = mc.ReleaseSearch(map[string]interface{}{
+		"query":  map[string]interface{}{"author": "ETHER"},
+		"slices": 2,
+		"aggregations": map[string]mcc.Aggregation{
+			"authors": &mcc.TermsAggregation{Field: "author"},
+		},
+	})
+	if err == nil {
+		t.Errorf("expected an error aggregating a sliced search")
+	}
+}
+
+// TestServer_cancelled checks that a cancelled context reaches each kind of
+// request the client makes, rather than being dropped along the way.
+func TestServer_cancelled(t *testing.T) {
+	t.Parallel()
+	mc := tNewClient(t, mcc.WithScrollSize(1))
+	r, err := mc.Release("Moose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := mc.File("ETHER/Moose-2.2200/lib/Moose/Role.pm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := mc.ReleaseSearch(q.Term("author", "ETHER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first page has one release, so the next needs another request
+	if _, err = rs.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		name string
+		call func() error
+	}{
+		{"getResult", func() error {
+			_, err := mc.AuthorContext(ctx, "ETHER")
+			return err
+		}},
+		{"query", func() error {
+			_, err := mc.DownloadURLContext(ctx, "Moose", nil,
+				false)
+			return err
+		}},
+		{"search", func() error {
+			_, err := mc.AuthorSearchContext(ctx,
+				q.Term("pauseid", "ETHER"))
+			return err
+		}},
+		{"scroll", func() error {
+			_, err := rs.NextContext(ctx)
+			return err
+		}},
+		{"changes", func() error {
+			_, err := r.ChangesContext(ctx)
+			return err
+		}},
+		{"source", func() error {
+			_, err := f.SourceContext(ctx)
+			return err
+		}},
+		{"pod", func() error {
+			_, err := mcc.NewPodContext(ctx, "Moose::Role", "", mc)
+			return err
+		}},
+	} {
+		if err := test.call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v",
+				test.name, err)
+		}
+	}
+}
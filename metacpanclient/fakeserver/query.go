@@ -0,0 +1,379 @@
+package fakeserver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// query is a compiled Elasticsearch query.
+type query func(*document) bool
+
+func compileQuery(v interface{}) (query, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, errMalformedQuery
+	}
+	for typ, body := range m {
+		switch typ {
+		case "match_all":
+			return func(*document) bool { return true }, nil
+		case "bool":
+			return compileBool(body)
+		case "term", "wildcard", "prefix":
+			return compileLeaf(typ, body)
+		case "terms":
+			return compileTerms(body)
+		case "range":
+			return compileRange(body)
+		case "exists":
+			return compileExists(body)
+		default:
+			return nil, fmt.Errorf("unknown query [%s]", typ)
+		}
+	}
+	panic("unreachable")
+}
+
+func compileBool(body interface{}) (query, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, errMalformedQuery
+	}
+	clauses := make(map[string][]query)
+	for _, occur := range []string{"must", "filter", "should",
+		"must_not"} {
+		v, ok := m[occur]
+		if !ok {
+			continue
+		}
+		// a single clause doesn't have to be wrapped in an array
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		for _, c := range list {
+			q, err := compileQuery(c)
+			if err != nil {
+				return nil, err
+			}
+			clauses[occur] = append(clauses[occur], q)
+		}
+	}
+	msm := 0
+	if len(clauses["must"]) == 0 && len(clauses["filter"]) == 0 {
+		msm = 1
+	}
+	if v, ok := m["minimum_should_match"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, errMalformedQuery
+		}
+		msm = int(n)
+	}
+	if len(clauses["should"]) == 0 {
+		msm = 0
+	}
+	// scoring isn't a thing here, so filter is the same as must
+	must := append(clauses["must"], clauses["filter"]...)
+	return func(d *document) bool {
+		for _, q := range must {
+			if !q(d) {
+				return false
+			}
+		}
+		for _, q := range clauses["must_not"] {
+			if q(d) {
+				return false
+			}
+		}
+		n := 0
+		for _, q := range clauses["should"] {
+			if q(d) {
+				n++
+			}
+		}
+		return n >= msm
+	}, nil
+}
+
+// fieldQuery splits {"field": value} or {"field": {"value": value}} into
+// its parts.
+func fieldQuery(body interface{}, key string) (string, interface{},
+	error) {
+	m, ok := body.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", nil, errMalformedQuery
+	}
+	for f, v := range m {
+		if vm, ok := v.(map[string]interface{}); ok {
+			if v, ok = vm[key]; !ok {
+				return "", nil, errMalformedQuery
+			}
+		}
+		return f, v, nil
+	}
+	panic("unreachable")
+}
+
+func compileLeaf(typ string, body interface{}) (query, error) {
+	f, v, err := fieldQuery(body, "value")
+	if err != nil {
+		return nil, err
+	}
+	if typ == "term" {
+		return func(d *document) bool {
+			return anyValue(d, f, func(x interface{}) bool {
+				return equal(x, v)
+			})
+		}, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, errMalformedQuery
+	}
+	if typ == "prefix" {
+		return func(d *document) bool {
+			return anyValue(d, f, func(x interface{}) bool {
+				xs, ok := x.(string)
+				return ok && strings.HasPrefix(xs, s)
+			})
+		}, nil
+	}
+	re := compileWildcard(s)
+	return func(d *document) bool {
+		return anyValue(d, f, func(x interface{}) bool {
+			xs, ok := x.(string)
+			return ok && re.MatchString(xs)
+		})
+	}, nil
+}
+
+func compileWildcard(s string) *regexp.Regexp {
+	sb := strings.Builder{}
+	sb.WriteString("^(?s:")
+	for _, r := range s {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteRune('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(")$")
+	return regexp.MustCompile(sb.String())
+}
+
+func compileTerms(body interface{}) (query, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, errMalformedQuery
+	}
+	for f, v := range m {
+		terms, ok := v.([]interface{})
+		if !ok {
+			return nil, errMalformedQuery
+		}
+		return func(d *document) bool {
+			return anyValue(d, f, func(x interface{}) bool {
+				for _, t := range terms {
+					if equal(x, t) {
+						return true
+					}
+				}
+				return false
+			})
+		}, nil
+	}
+	panic("unreachable")
+}
+
+func compileRange(body interface{}) (query, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, errMalformedQuery
+	}
+	for f, v := range m {
+		bounds, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errMalformedQuery
+		}
+		checks, err := rangeChecks(bounds)
+		if err != nil {
+			return nil, err
+		}
+		return func(d *document) bool {
+			return anyValue(d, f, func(x interface{}) bool {
+				for _, c := range checks {
+					if !c(x) {
+						return false
+					}
+				}
+				return true
+			})
+		}, nil
+	}
+	panic("unreachable")
+}
+
+// rangeChecks returns a check for each of the bounds of a range query.
+func rangeChecks(bounds map[string]interface{}) ([]func(interface{}) bool,
+	error) {
+	// the old from/to form is inclusive unless told otherwise
+	inclLower, inclUpper := true, true
+	if b, ok := bounds["include_lower"].(bool); ok {
+		inclLower = b
+	}
+	if b, ok := bounds["include_upper"].(bool); ok {
+		inclUpper = b
+	}
+	var checks []func(interface{}) bool
+	for op, bound := range bounds {
+		var ok func(int) bool
+		switch op {
+		case "gt":
+			ok = func(c int) bool { return c > 0 }
+		case "gte":
+			ok = func(c int) bool { return c >= 0 }
+		case "lt":
+			ok = func(c int) bool { return c < 0 }
+		case "lte":
+			ok = func(c int) bool { return c <= 0 }
+		case "from":
+			ok = func(c int) bool {
+				return c > 0 || inclLower && c == 0
+			}
+		case "to":
+			ok = func(c int) bool {
+				return c < 0 || inclUpper && c == 0
+			}
+		case "include_lower", "include_upper", "format",
+			"time_zone":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown range parameter [%s]", op)
+		}
+		if bound == nil {
+			// unbounded
+			continue
+		}
+		bound := bound
+		checks = append(checks, func(x interface{}) bool {
+			c, comparable := compare(x, bound)
+			return comparable && ok(c)
+		})
+	}
+	return checks, nil
+}
+
+func compileExists(body interface{}) (query, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, errMalformedQuery
+	}
+	f, ok := m["field"].(string)
+	if !ok {
+		return nil, errMalformedQuery
+	}
+	return func(d *document) bool {
+		return anyValue(d, f, func(interface{}) bool { return true })
+	}, nil
+}
+
+// anyValue reports whether any of the values of a field satisfy ok. Dotted
+// field names descend into objects, and arrays anywhere along the way are
+// searched element by element, as Elasticsearch flattens them. Nulls don't
+// count as values.
+func anyValue(d *document, f string, ok func(interface{}) bool) bool {
+	for _, v := range lookup(d.source, f) {
+		if v != nil && ok(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookup(v interface{}, f string) []interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, el := range x {
+			out = append(out, lookup(el, f)...)
+		}
+		return out
+	case map[string]interface{}:
+		if f == "" {
+			return []interface{}{x}
+		}
+		head, rest, _ := strings.Cut(f, ".")
+		child, ok := x[head]
+		if !ok {
+			// some metacpanclient types have oddly-cased tags,
+			// which encoding/json tolerates, so do the same
+			for k, c := range x {
+				if strings.EqualFold(k, head) {
+					child, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil
+		}
+		return lookup(child, rest)
+	default:
+		if f != "" {
+			return nil
+		}
+		return []interface{}{x}
+	}
+}
+
+// field returns the first value of a field as a string.
+func field(src map[string]interface{}, f string) string {
+	for _, v := range lookup(src, f) {
+		if v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func equal(a, b interface{}) bool {
+	c, ok := compare(a, b)
+	return ok && c == 0
+}
+
+// compare orders two JSON scalars, returning false if they can't be
+// compared. Numbers compare numerically and everything else by its string
+// form, which is also how ISO 8601 dates sort.
+func compare(a, b interface{}) (int, bool) {
+	if af, ok := a.(float64); ok {
+		if bf, ok := b.(float64); ok {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+	switch a.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return 0, false
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return 0, false
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+}
+
+var (
+	errMalformedQuery = errors.New("malformed query")
+)
@@ -0,0 +1,174 @@
+package fakeserver
+
+import (
+	"net/http"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// serveDownloadURL serves the download URL for the newest release of a
+// module or distribution, optionally restricted to a version range and
+// including developer releases.
+func (s *Server) serveDownloadURL(w http.ResponseWriter, r *http.Request,
+	name string) {
+	params := r.URL.Query()
+	var rng *version.Range
+	if v := params.Get("version"); v != "" {
+		var err error
+		if rng, err = version.ParseRange(v); err != nil {
+			writeJSON(w, http.StatusBadRequest,
+				map[string]interface{}{
+					"code": http.StatusBadRequest,
+					"message": "Invalid version range: " +
+						v,
+				})
+			return
+		}
+	}
+	dev := params.Get("dev") == "1"
+	dist := s.distributionOf(name)
+	var best *document
+	var bestVersion version.Version
+	for _, d := range s.indices["release"] {
+		if field(d.source, "distribution") != dist {
+			continue
+		}
+		if !dev && field(d.source, "maturity") == "developer" {
+			continue
+		}
+		v, err := version.Parse(field(d.source, "version"))
+		if err != nil {
+			continue
+		}
+		if rng != nil && !rng.Contains(&v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(&bestVersion) {
+			best, bestVersion = d, v
+		}
+	}
+	if best == nil {
+		writeNotFound(w)
+		return
+	}
+	res := make(map[string]interface{})
+	for _, f := range []string{"checksum_md5", "checksum_sha256", "date",
+		"download_url", "status", "version"} {
+		if v := lookup(best.source, f); len(v) > 0 {
+			res[f] = v[0]
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// serveReverseDependencies serves the latest releases depending on any of
+// the modules in the latest release of dist.
+func (s *Server) serveReverseDependencies(w http.ResponseWriter,
+	dist string) {
+	rel := s.latestRelease(dist)
+	if rel == nil {
+		writeNotFound(w)
+		return
+	}
+	provided := make(map[string]bool)
+	for _, v := range lookup(rel.source, "provides") {
+		if m, ok := v.(string); ok {
+			provided[m] = true
+		}
+	}
+	for _, d := range s.indices["module"] {
+		if field(d.source, "release") != field(rel.source, "name") {
+			continue
+		}
+		for _, f := range []string{"documentation", "module.name"} {
+			for _, v := range lookup(d.source, f) {
+				if m, ok := v.(string); ok {
+					provided[m] = true
+				}
+			}
+		}
+	}
+	data := make([]interface{}, 0)
+	for _, d := range s.indices["release"] {
+		if field(d.source, "status") != "latest" ||
+			field(d.source, "distribution") == dist {
+			continue
+		}
+		if anyValue(d, "dependency.module", func(v interface{}) bool {
+			m, ok := v.(string)
+			return ok && provided[m]
+		}) {
+			data = append(data, d.source)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"total": len(data),
+	})
+}
+
+// servePackage serves the package index entry for a module, which gives the
+// module's version and the distribution it's in.
+func (s *Server) servePackage(w http.ResponseWriter, name string) {
+	for _, d := range s.indices["module"] {
+		if !providesModule(d, name) {
+			continue
+		}
+		res := map[string]interface{}{
+			"module_name":  name,
+			"author":       field(d.source, "author"),
+			"distribution": field(d.source, "distribution"),
+		}
+		if v := moduleVersion(d, name); v != "" {
+			res["version"] = v
+		}
+		rel := s.latestRelease(field(d.source, "distribution"))
+		if rel != nil && field(rel.source, "version") != "" {
+			res["dist_version"] = field(rel.source, "version")
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	writeNotFound(w)
+}
+
+// moduleVersion returns the version of the named package in a module
+// document, or failing that the version of the file.
+func moduleVersion(d *document, name string) string {
+	for _, m := range lookup(d.source, "module") {
+		mod, ok := m.(map[string]interface{})
+		if !ok || mod["name"] != name {
+			continue
+		}
+		if v := field(mod, "version"); v != "" {
+			return v
+		}
+	}
+	return field(d.source, "version")
+}
+
+// distributionOf returns the distribution a module belongs to, or name
+// itself turned into a distribution name if there's no such module.
+func (s *Server) distributionOf(name string) string {
+	for _, d := range s.indices["module"] {
+		if providesModule(d, name) {
+			if dist := field(d.source, "distribution"); dist != "" {
+				return dist
+			}
+		}
+	}
+	return strings.ReplaceAll(name, "::", "-")
+}
+
+// providesModule reports whether a module document is for the named
+// module, either as its documentation or one of the packages it indexes.
+func providesModule(d *document, name string) bool {
+	if field(d.source, "documentation") == name {
+		return true
+	}
+	return anyValue(d, "module.name", func(v interface{}) bool {
+		return v == name
+	})
+}
@@ -0,0 +1,319 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scroll is an open scroll context.
+type scroll struct {
+	docs    []*document
+	offset  int
+	size    int
+	source  []string
+	expires time.Time
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request,
+	index string) {
+	docs, ok := s.indices[index]
+	if !ok && !isIndex(index) {
+		writeESError(w, http.StatusNotFound,
+			"index_not_found_exception",
+			"no such index ["+index+"]")
+		return
+	}
+	var body struct {
+		Query interface{} `json:"query"`
+		Slice *struct {
+			ID  int `json:"id"`
+			Max int `json:"max"`
+		} `json:"slice"`
+		Aggs         map[string]json.RawMessage `json:"aggs"`
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	b, err := io.ReadAll(r.Body)
+	if err == nil && len(strings.TrimSpace(string(b))) > 0 {
+		err = json.Unmarshal(b, &body)
+	}
+	if err != nil {
+		writeESError(w, http.StatusBadRequest, "parse_exception",
+			err.Error())
+		return
+	}
+	match := query(func(*document) bool { return true })
+	if body.Query != nil {
+		if match, err = compileQuery(body.Query); err != nil {
+			writeESError(w, http.StatusBadRequest,
+				"parsing_exception", err.Error())
+			return
+		}
+	}
+	params := r.URL.Query()
+	size := 10
+	if v := params.Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < 0 {
+			writeESError(w, http.StatusBadRequest,
+				"illegal_argument_exception",
+				"invalid size ["+v+"]")
+			return
+		}
+	}
+	if sl := body.Slice; sl != nil && (sl.Max < 1 || sl.ID < 0 ||
+		sl.ID >= sl.Max) {
+		writeESError(w, http.StatusBadRequest,
+			"illegal_argument_exception", "invalid slice")
+		return
+	}
+	var hits []*document
+	for i, d := range docs {
+		// documents are dealt out to slices round-robin
+		if body.Slice != nil && i%body.Slice.Max != body.Slice.ID {
+			continue
+		}
+		if match(d) {
+			hits = append(hits, d)
+		}
+	}
+	if body.Aggs == nil {
+		body.Aggs = body.Aggregations
+	}
+	aggs, err := aggregate(body.Aggs, hits)
+	if err != nil {
+		writeESError(w, http.StatusBadRequest, "parsing_exception",
+			err.Error())
+		return
+	}
+	if v := params.Get("sort"); v != "" {
+		sortDocuments(hits, strings.Split(v, ","))
+	}
+	var source []string
+	if v := params.Get("_source"); v != "" {
+		source = strings.Split(v, ",")
+	}
+	sc := &scroll{docs: hits, size: size, source: source}
+	var id string
+	if v := params.Get("scroll"); v != "" {
+		keepAlive, err := parseKeepAlive(v)
+		if err != nil {
+			writeESError(w, http.StatusBadRequest,
+				"illegal_argument_exception", err.Error())
+			return
+		}
+		sc.expires = time.Now().Add(keepAlive)
+		s.mu.Lock()
+		s.nextID++
+		id = "fake-scroll-" + strconv.Itoa(s.nextID)
+		s.scrolls[id] = sc
+		s.mu.Unlock()
+	}
+	s.writeHits(w, id, sc, aggs)
+}
+
+// serveScroll serves the next page of a scroll, or clears scrolls. The ID
+// can be given in the path, a scroll_id parameter or the body, which may be
+// either JSON or the bare ID.
+func (s *Server) serveScroll(w http.ResponseWriter, r *http.Request,
+	id string) {
+	var keepAlive string
+	b, _ := io.ReadAll(r.Body)
+	if id == "" {
+		id = r.URL.Query().Get("scroll_id")
+	}
+	if id == "" {
+		var v struct {
+			ScrollID interface{} `json:"scroll_id"`
+			Scroll   string      `json:"scroll"`
+		}
+		if err := json.Unmarshal(b, &v); err == nil {
+			keepAlive = v.Scroll
+			switch x := v.ScrollID.(type) {
+			case string:
+				id = x
+			case []interface{}:
+				if len(x) > 0 {
+					id, _ = x[0].(string)
+				}
+			}
+		} else {
+			id = strings.TrimSpace(string(b))
+		}
+	}
+	if keepAlive == "" {
+		keepAlive = r.URL.Query().Get("scroll")
+	}
+	s.mu.Lock()
+	sc, ok := s.scrolls[id]
+	if ok && time.Now().After(sc.expires) {
+		delete(s.scrolls, id)
+		ok = false
+	}
+	if ok && r.Method == http.MethodDelete {
+		delete(s.scrolls, id)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeESError(w, http.StatusNotFound,
+			"search_context_missing_exception",
+			"No search context found for id ["+id+"]")
+		return
+	}
+	if r.Method == http.MethodDelete {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"succeeded": true,
+			"num_freed": 1,
+		})
+		return
+	}
+	if keepAlive != "" {
+		d, err := parseKeepAlive(keepAlive)
+		if err != nil {
+			writeESError(w, http.StatusBadRequest,
+				"illegal_argument_exception", err.Error())
+			return
+		}
+		s.mu.Lock()
+		sc.expires = time.Now().Add(d)
+		s.mu.Unlock()
+	}
+	s.writeHits(w, id, sc, nil)
+}
+
+// writeHits writes the next page of sc, along with aggs if there are any.
+func (s *Server) writeHits(w http.ResponseWriter, id string, sc *scroll,
+	aggs map[string]interface{}) {
+	s.mu.Lock()
+	start := sc.offset
+	end := start + sc.size
+	if end > len(sc.docs) {
+		end = len(sc.docs)
+	}
+	sc.offset = end
+	s.mu.Unlock()
+	hits := make([]interface{}, 0, end-start)
+	for _, d := range sc.docs[start:end] {
+		hits = append(hits, map[string]interface{}{
+			"_index":  d.index,
+			"_type":   docType(d.index),
+			"_id":     d.id,
+			"_score":  1,
+			"_source": filterSource(d.source, sc.source),
+		})
+	}
+	res := map[string]interface{}{
+		"took":      0,
+		"timed_out": false,
+		"hits": map[string]interface{}{
+			"total":     len(sc.docs),
+			"max_score": 1,
+			"hits":      hits,
+		},
+	}
+	if aggs != nil {
+		res["aggregations"] = aggs
+	}
+	if id != "" {
+		res["_scroll_id"] = id
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func isIndex(index string) bool {
+	switch index {
+	case "author", "distribution", "favorite", "file", "module", "rating",
+		"release":
+		return true
+	default:
+		return false
+	}
+}
+
+// docType returns the _type MetaCPAN reports for documents in an index.
+// The module index is really a view of the file index, so its documents
+// are files.
+func docType(index string) string {
+	if index == "module" {
+		return "file"
+	}
+	return index
+}
+
+// sortDocuments sorts docs by fields of the form "name" or "name:order".
+// Documents missing a field sort after those that have it.
+func sortDocuments(docs []*document, fields []string) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, f := range fields {
+			name, order, _ := strings.Cut(f, ":")
+			a := lookup(docs[i].source, name)
+			b := lookup(docs[j].source, name)
+			switch {
+			case len(a) == 0 && len(b) == 0:
+				continue
+			case len(a) == 0:
+				return false
+			case len(b) == 0:
+				return true
+			}
+			c, ok := compare(a[0], b[0])
+			if !ok || c == 0 {
+				continue
+			}
+			if order == "desc" {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// filterSource returns only the given fields of src, or all of it if there
+// are none.
+func filterSource(src map[string]interface{},
+	fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return src
+	}
+	out := make(map[string]interface{})
+	for _, f := range fields {
+		copyField(out, src, f)
+	}
+	return out
+}
+
+func copyField(dst, src map[string]interface{}, f string) {
+	head, rest, nested := strings.Cut(f, ".")
+	v, ok := src[head]
+	if !ok {
+		return
+	}
+	sub, isMap := v.(map[string]interface{})
+	if !nested || !isMap {
+		dst[head] = v
+		return
+	}
+	d, ok := dst[head].(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{})
+		dst[head] = d
+	}
+	copyField(d, sub, rest)
+}
+
+// parseKeepAlive parses an Elasticsearch time unit such as "5m" or "30s".
+func parseKeepAlive(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"errors"
 	"io"
 	"strings"
@@ -141,6 +142,10 @@ func (f *File) MetaCPANURL() string {
 }
 
 func (f *File) Pod(kind PodKind) (string, error) {
+	return f.PodContext(context.Background(), kind)
+}
+
+func (f *File) PodContext(ctx context.Context, kind PodKind) (string, error) {
 	if f.pod == nil {
 		if f.mc == nil {
 			return "", ErrNilClient
@@ -148,7 +153,7 @@ func (f *File) Pod(kind PodKind) (string, error) {
 		podName := f.Distribution
 		podName = strings.ReplaceAll(podName, "-", "::")
 		var err error
-		if f.pod, err = NewPod(podName, "", f.mc); err != nil {
+		if f.pod, err = NewPodContext(ctx, podName, "", f.mc); err != nil {
 			return "", err
 		}
 	}
@@ -156,6 +161,10 @@ func (f *File) Pod(kind PodKind) (string, error) {
 }
 
 func (f *File) Source() (string, error) {
+	return f.SourceContext(context.Background())
+}
+
+func (f *File) SourceContext(ctx context.Context) (string, error) {
 	if f.src != "" {
 		return f.src, nil
 	}
@@ -169,7 +178,7 @@ func (f *File) Source() (string, error) {
 	sb.WriteString(f.Release)
 	sb.WriteRune('/')
 	sb.WriteString(f.Path)
-	rc, err := f.mc.request("", sb.String(), nil)
+	rc, err := f.mc.request(ctx, "", sb.String(), nil)
 	defer pui.CloseBody(rc)
 	if err != nil {
 		return "", err
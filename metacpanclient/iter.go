@@ -0,0 +1,152 @@
+package metacpanclient
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the remaining results. Iteration stops after
+// the first error, which is yielded along with a zero T.
+func (r *resultSetFetch[T]) All() iter.Seq2[T, error] {
+	return r.AllContext(context.Background())
+}
+
+// AllContext is like All, but with a context.
+func (r *resultSetFetch[T]) AllContext(
+	ctx context.Context) iter.Seq2[T, error] {
+	return seq(ctx, r.ReadContext, nil)
+}
+
+// Collect returns up to limit of the remaining results, or all of them if
+// limit is zero or less.
+func (r *resultSetFetch[T]) Collect(ctx context.Context, limit int) ([]T,
+	error) {
+	return collect(r.AllContext(ctx), limit)
+}
+
+// Read returns the next result, or io.EOF once there are no more.
+func (r *resultSetFetch[T]) Read() (T, error) {
+	return r.ReadContext(context.Background())
+}
+
+// ReadContext is like Read, but with a context.
+func (r *resultSetFetch[T]) ReadContext(ctx context.Context) (T, error) {
+	var null T
+	if err := ctx.Err(); err != nil {
+		return null, err
+	}
+	if r.itemIndex >= len(r.items) {
+		return null, io.EOF
+	}
+	v := r.items[r.itemIndex]
+	r.itemIndex++
+	return v, nil
+}
+
+func (r *resultSetScroll[T]) All() iter.Seq2[T, error] {
+	return r.scroller.All()
+}
+
+func (r *resultSetScroll[T]) AllContext(
+	ctx context.Context) iter.Seq2[T, error] {
+	return r.scroller.AllContext(ctx)
+}
+
+func (r *resultSetScroll[T]) Collect(ctx context.Context, limit int) ([]T,
+	error) {
+	return r.scroller.Collect(ctx, limit)
+}
+
+func (r *resultSetScroll[T]) Read() (T, error) {
+	return r.scroller.Read()
+}
+
+func (r *resultSetScroll[T]) ReadContext(ctx context.Context) (T, error) {
+	return r.scroller.ReadContext(ctx)
+}
+
+// All returns an iterator over the remaining results, fetching more pages as
+// needed. Iteration stops after the first error, which is yielded along with
+// a zero T. Breaking out of the loop early clears the scroll on the server,
+// after which it can't be used any more.
+func (s *Scroll[T]) All() iter.Seq2[T, error] {
+	return s.AllContext(context.Background())
+}
+
+// AllContext is like All, but with a context.
+func (s *Scroll[T]) AllContext(ctx context.Context) iter.Seq2[T, error] {
+	return seq(ctx, s.ReadContext, s.release)
+}
+
+// Collect returns up to limit of the remaining results, or all of them if
+// limit is zero or less. If it stops short of the end, the scroll is
+// cleared as with breaking out of All.
+func (s *Scroll[T]) Collect(ctx context.Context, limit int) ([]T, error) {
+	return collect(s.AllContext(ctx), limit)
+}
+
+// Read returns the next result, or io.EOF once there are no more.
+func (s *Scroll[T]) Read() (T, error) {
+	return s.ReadContext(context.Background())
+}
+
+// ReadContext is like Read, but with a context.
+func (s *Scroll[T]) ReadContext(ctx context.Context) (T, error) {
+	v, ok, err := s.next(ctx)
+	if err == nil && !ok {
+		err = io.EOF
+	}
+	return v, err
+}
+
+// release clears a scroll that's been abandoned before reaching the end.
+func (s *Scroll[T]) release() {
+	if s.mc == nil || s.scrollID == "" {
+		return
+	}
+	if s.registered {
+		s.mc.killScroll(s.scrollID, true)
+		return
+	}
+	if _, err := s.kill(true); err != nil {
+		s.mc.logger.Printf("%s", err)
+	}
+}
+
+// seq turns read, which returns io.EOF at the end, into an iterator. If the
+// loop is broken out of, cleanup is called if it's not nil.
+func seq[T any](ctx context.Context, read func(context.Context) (T, error),
+	cleanup func()) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := read(ctx)
+			switch {
+			case err == io.EOF:
+				return
+			case err != nil:
+				yield(v, err)
+				return
+			case !yield(v, nil):
+				if cleanup != nil {
+					cleanup()
+				}
+				return
+			}
+		}
+	}
+}
+
+func collect[T any](it iter.Seq2[T, error], limit int) ([]T, error) {
+	var items []T
+	for v, err := range it {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, v)
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+	}
+	return items, nil
+}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"strings"
 )
 
@@ -28,6 +29,10 @@ func (m *Module) MetaCPANURL() string {
 }
 
 func (m *Module) Package() (*Package, error) {
+	return m.PackageContext(context.Background())
+}
+
+func (m *Module) PackageContext(ctx context.Context) (*Package, error) {
 	if m.pkg != nil {
 		return m.pkg, nil
 	}
@@ -35,11 +40,16 @@ func (m *Module) Package() (*Package, error) {
 		return nil, ErrNilClient
 	}
 	var err error
-	m.pkg, err = m.mc.Package(m.Documentation)
+	m.pkg, err = m.mc.PackageContext(ctx, m.Documentation)
 	return m.pkg, err
 }
 
 func (m *Module) Permission() (*Permission, error) {
+	return m.PermissionContext(context.Background())
+}
+
+func (m *Module) PermissionContext(ctx context.Context) (*Permission,
+	error) {
 	if m.perm != nil {
 		return m.perm, nil
 	}
@@ -47,6 +57,6 @@ func (m *Module) Permission() (*Permission, error) {
 		return nil, ErrNilClient
 	}
 	var err error
-	m.perm, err = m.mc.Permission(m.Documentation)
+	m.perm, err = m.mc.PermissionContext(ctx, m.Documentation)
 	return m.perm, err
 }
@@ -0,0 +1,220 @@
+package metacpanclient
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Logger is what the Client writes debug output to, along with any errors
+// that can't be handed back to the caller (such as failing to clean up a
+// scroll). *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option configures a Client. See New.
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	cache        Cache
+	domains      []string
+	httpClient   *http.Client
+	immutable    func(path string) bool
+	logger       Logger
+	rateLimit    float64
+	rateBurst    int
+	maxInFlight  int
+	retry        RetryPolicy
+	roundTripper http.RoundTripper
+	scrollSize   uint16
+	scrollTime   time.Duration
+	timeout      time.Duration
+	userAgent    string
+	debug        bool
+}
+
+// WithCache sets a Cache for responses to lookups. Searches and scrolls are
+// never cached. Responses are only kept as long as their Cache-Control or
+// Expires headers allow, and revalidated with If-None-Match or
+// If-Modified-Since when they have an ETag or Last-Modified header.
+func WithCache(c Cache) Option {
+	return func(conf *clientConfig) {
+		conf.cache = c
+	}
+}
+
+// WithDebug enables dumping every request and response to the Client's
+// Logger.
+func WithDebug(debug bool) Option {
+	return func(c *clientConfig) {
+		c.debug = debug
+	}
+}
+
+// WithDomains sets the MetaCPAN API endpoints to use, in order of
+// preference. The default is MetaCPANURL.
+func WithDomains(domains ...string) Option {
+	return func(c *clientConfig) {
+		c.domains = append([]string{}, domains...)
+	}
+}
+
+// WithHTTPClient sets the http.Client used for requests. The Client is
+// copied, so later changes to it have no effect. Its Transport, if any, is
+// wrapped rather than replaced.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *clientConfig) {
+		c.httpClient = hc
+	}
+}
+
+// WithImmutable marks the request paths for which fn returns true as never
+// changing, so they're cached indefinitely regardless of what the response
+// headers say. ImmutablePath covers the common cases. It has no effect
+// without WithCache.
+func WithImmutable(fn func(path string) bool) Option {
+	return func(c *clientConfig) {
+		c.immutable = fn
+	}
+}
+
+// WithLogger sets where debug output and background errors go. The default
+// is log.Default().
+func WithLogger(l Logger) Option {
+	return func(c *clientConfig) {
+		c.logger = l
+	}
+}
+
+// WithMaxInFlight caps how many requests the Client has outstanding at once,
+// counting a request as outstanding until its response body is closed. Zero
+// means no cap.
+func WithMaxInFlight(n int) Option {
+	return func(c *clientConfig) {
+		c.maxInFlight = n
+	}
+}
+
+// WithRateLimit limits the Client to perSecond requests per second on
+// average, allowing bursts of up to burst requests. Retries count against
+// the limit like any other request. Zero means no limit.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *clientConfig) {
+		c.rateLimit = perSecond
+		c.rateBurst = burst
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried, and when a failing
+// domain is skipped. The default is DefaultRetryPolicy(); pass a zero
+// RetryPolicy to turn retrying off.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *clientConfig) {
+		c.retry = p
+	}
+}
+
+// WithRoundTripper sets the http.RoundTripper requests are ultimately sent
+// through, including searches. It takes precedence over the Transport of a
+// client given to WithHTTPClient.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		c.roundTripper = rt
+	}
+}
+
+// WithScrollSize sets the default number of results fetched per scroll page.
+func WithScrollSize(size uint16) Option {
+	return func(c *clientConfig) {
+		c.scrollSize = size
+	}
+}
+
+// WithScrollTime sets how long MetaCPAN keeps a scroll alive between pages.
+func WithScrollTime(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.scrollTime = d
+	}
+}
+
+// WithTimeout sets a limit on how long any single request may take,
+// including reading the response body. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *clientConfig) {
+		c.userAgent = ua
+	}
+}
+
+func (c *clientConfig) validate() error {
+	if c.scrollTime > 24*time.Hour {
+		return errScrollTimeMax
+	}
+	if c.scrollSize > 10000 {
+		return errScrollSizeMax
+	}
+	if c.timeout < 0 {
+		return errNegativeTimeout
+	}
+	if c.rateLimit < 0 {
+		return errNegativeRateLimit
+	}
+	if c.maxInFlight < 0 {
+		return errNegativeInFlight
+	}
+	return c.retry.validate()
+}
+
+func (c *clientConfig) baseTransport() http.RoundTripper {
+	switch {
+	case c.roundTripper != nil:
+		return c.roundTripper
+	case c.httpClient != nil && c.httpClient.Transport != nil:
+		return c.httpClient.Transport
+	default:
+		return http.DefaultTransport
+	}
+}
+
+func newClientConfig(opts []Option) *clientConfig {
+	c := &clientConfig{
+		domains:    []string{MetaCPANURL},
+		logger:     log.Default(),
+		retry:      DefaultRetryPolicy(),
+		scrollSize: defaultScrollSize,
+		scrollTime: defaultScrollTime,
+		userAgent:  defaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// zero values mean "use the default", as they always have
+	if c.scrollSize == 0 {
+		c.scrollSize = defaultScrollSize
+	}
+	if c.scrollTime == 0 {
+		c.scrollTime = defaultScrollTime
+	}
+	if c.userAgent == "" {
+		c.userAgent = defaultUserAgent
+	}
+	if len(c.domains) == 0 {
+		c.domains = []string{MetaCPANURL}
+	}
+	if c.logger == nil {
+		c.logger = log.Default()
+	}
+	return c
+}
+
+var (
+	errNegativeTimeout = errors.New("timeout cannot be negative")
+)
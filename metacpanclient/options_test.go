@@ -0,0 +1,149 @@
+package metacpanclient
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/author/SRI" {
+				http.NotFound(w, r)
+				return
+			}
+			if r.UserAgent() != "test-agent" {
+				t.Errorf("unexpected user agent %q",
+					r.UserAgent())
+			}
+			_, _ = w.Write([]byte(`{"pauseid":"SRI"}`))
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL+"/"), WithUserAgent("test-agent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	if d := mc.Domains(); len(d) != 1 || d[0] != srv.URL {
+		t.Errorf("unexpected domains %v", d)
+	}
+	if mc.UserAgent() != "test-agent" {
+		t.Errorf("unexpected user agent")
+	}
+	a, err := mc.Author("SRI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.PauseID != "SRI" {
+		t.Errorf("unexpected pause id")
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	t.Parallel()
+	if _, err := New(WithScrollSize(10001)); err != errScrollSizeMax {
+		t.Errorf("expected errScrollSizeMax, got %v", err)
+	}
+	if _, err := New(WithScrollTime(25 * time.Hour)); err !=
+		errScrollTimeMax {
+		t.Errorf("expected errScrollTimeMax, got %v", err)
+	}
+	if _, err := New(WithTimeout(-1)); err != errNegativeTimeout {
+		t.Errorf("expected errNegativeTimeout, got %v", err)
+	}
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	rt := tRoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: tNopCloser{strings.NewReader(
+				`{"name":"Mojolicious"}`)},
+			Header:  http.Header{},
+			Request: r,
+		}, nil
+	})
+	// the round tripper should win over the http client's transport
+	hc := &http.Client{Transport: tRoundTripperFunc(
+		func(r *http.Request) (*http.Response, error) {
+			t.Error("http client transport used")
+			return nil, http.ErrNotSupported
+		})}
+	mc, err := New(WithHTTPClient(hc), WithRoundTripper(rt),
+		WithDomains("http://metacpan.invalid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	d, err := mc.Distribution(mojo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != mojo {
+		t.Errorf("unexpected name")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"name":"Mojolicious"}`))
+		}))
+	defer srv.Close()
+	buf := &tSyncBuffer{}
+	mc, err := New(WithDomains(srv.URL), WithDebug(true),
+		WithLogger(log.New(buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	if _, err = mc.Distribution(mojo); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "GET /distribution/Mojolicious") {
+		t.Errorf("expected request dump, got %q", buf.String())
+	}
+}
+
+type tRoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f tRoundTripperFunc) RoundTrip(r *http.Request) (*http.Response,
+	error) {
+	return f(r)
+}
+
+type tNopCloser struct{ *strings.Reader }
+
+func (tNopCloser) Close() error { return nil }
+
+type tSyncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *tSyncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *tSyncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
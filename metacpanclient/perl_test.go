@@ -214,7 +214,7 @@ func TestPerl_MetaCPAN_Client_DownloadURL(t *testing.T) {
 	if !isSHA256Regex.MatchString(du.ChecksumSHA256) {
 		t.Errorf("expected a checksum, got empty string")
 	}
-	vr, err := version.ParseRange("1.01")
+	vr, err := version.ParseRange("== 1.01")
 	if err != nil {
 		t.Error(err)
 	}
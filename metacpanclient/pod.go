@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"errors"
 	"io"
 	"strings"
@@ -40,7 +41,7 @@ type Pod struct {
 	mc    *Client
 }
 
-func (p *Pod) load(kind PodKind) (string, error) {
+func (p *Pod) load(ctx context.Context, kind PodKind) (string, error) {
 	qb := strings.Builder{}
 	qb.WriteString("content-type=text/")
 	qb.WriteString(string(kind))
@@ -49,7 +50,7 @@ func (p *Pod) load(kind PodKind) (string, error) {
 		qb.WriteString(p.URLPrefix)
 	}
 	url := buildRequestURL("/pod", p.Name, qb.String())
-	rc, err := p.mc.request("", url, nil)
+	rc, err := p.mc.request(ctx, "", url, nil)
 	if err != nil {
 		return "", err
 	}
@@ -63,6 +64,13 @@ func (p *Pod) load(kind PodKind) (string, error) {
 }
 
 func NewPod(name, urlPrefix string, mc *Client) (*Pod, error) {
+	return NewPodContext(context.Background(), name, urlPrefix, mc)
+}
+
+// NewPodContext is like NewPod, but with a context. If any of the requests
+// fail, the remaining ones are cancelled.
+func NewPodContext(ctx context.Context, name, urlPrefix string,
+	mc *Client) (*Pod, error) {
 	if mc == nil {
 		return nil, ErrNilClient
 	}
@@ -71,8 +79,11 @@ func NewPod(name, urlPrefix string, mc *Client) (*Pod, error) {
 		URLPrefix: urlPrefix,
 		mc:        mc,
 	}
-	errCh := make(chan error)
-	doneCh := make(chan struct{})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// buffered, so the stragglers don't block forever once we've returned
+	errCh := make(chan error, 4)
+	doneCh := make(chan struct{}, 4)
 	res := make([]string, 4)
 	for i, kind := range []PodKind{
 		PodKindHTML,
@@ -81,7 +92,7 @@ func NewPod(name, urlPrefix string, mc *Client) (*Pod, error) {
 		PodKindXPod,
 	} {
 		go func(i int, kind PodKind) {
-			s, err := p.load(kind)
+			s, err := p.load(ctx, kind)
 			if err != nil {
 				errCh <- err
 				return
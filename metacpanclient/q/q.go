@@ -0,0 +1,193 @@
+// Package q builds MetaCPAN search queries without hand-writing nested
+// maps.
+//
+// Queries are built from leaf conditions (Term, Terms, Wildcard, Prefix,
+// Range, Exists, MatchAll) combined with Either, All and Not, which mean
+// the same as the either, all and not keys of the map syntax accepted by
+// metacpanclient. A Query can be passed straight to any of the client's
+// search methods; to also pick the sort order, fields or page size, wrap it
+// with NewSearch:
+//
+//	rs, err := mc.ReleaseSearch(q.NewSearch(
+//		q.All(
+//			q.Term("author", "ETHER"),
+//			q.Not(q.Term("status", "backpan")),
+//		),
+//	).Sort(q.Desc("date")).Source("name", "version"))
+//
+// Both Query and Search are maps underneath, so they're accepted anywhere a
+// map[string]interface{} of search arguments is.
+package q
+
+import (
+	"time"
+)
+
+// Query is a search condition.
+type Query map[string]interface{}
+
+// Term matches documents where field is exactly value. Unlike the map
+// syntax, a string value containing '*' is not treated as a wildcard.
+func Term(field string, value interface{}) Query {
+	return leaf("term", field, value)
+}
+
+// Terms matches documents where field is exactly any of values.
+func Terms(field string, values ...interface{}) Query {
+	return leaf("terms", field, values)
+}
+
+// Wildcard matches documents where field matches pattern, in which '*'
+// matches any run of characters and '?' any single character.
+func Wildcard(field, pattern string) Query {
+	return leaf("wildcard", field, pattern)
+}
+
+// Prefix matches documents where field starts with prefix.
+func Prefix(field, prefix string) Query {
+	return leaf("prefix", field, prefix)
+}
+
+// Exists matches documents that have a value for field.
+func Exists(field string) Query {
+	return leaf("exists", "field", field)
+}
+
+// MatchAll matches every document.
+func MatchAll() Query {
+	return Query{"match_all": map[string]interface{}{}}
+}
+
+// Range matches documents where field is within all the given bounds. With
+// no bounds, it matches any document with a value for field.
+func Range(field string, bounds ...Bound) Query {
+	b := make(map[string]interface{}, len(bounds))
+	for _, bound := range bounds {
+		b[bound.op] = bound.value
+	}
+	return leaf("range", field, b)
+}
+
+// Bound is one end of a Range.
+type Bound struct {
+	op    string
+	value interface{}
+}
+
+// Gt bounds a Range to values greater than v.
+func Gt(v interface{}) Bound {
+	return Bound{"gt", v}
+}
+
+// Gte bounds a Range to values greater than or equal to v.
+func Gte(v interface{}) Bound {
+	return Bound{"gte", v}
+}
+
+// Lt bounds a Range to values less than v.
+func Lt(v interface{}) Bound {
+	return Bound{"lt", v}
+}
+
+// Lte bounds a Range to values less than or equal to v.
+func Lte(v interface{}) Bound {
+	return Bound{"lte", v}
+}
+
+// Either matches documents matching at least one of queries.
+func Either(queries ...Query) Query {
+	return Query{"either": list(queries)}
+}
+
+// All matches documents matching every one of queries.
+func All(queries ...Query) Query {
+	return Query{"all": list(queries)}
+}
+
+// Not matches documents matching none of queries.
+func Not(queries ...Query) Query {
+	return Query{"not": list(queries)}
+}
+
+// Search is a Query along with the options for running it.
+type Search map[string]interface{}
+
+// NewSearch returns a Search for documents matching query.
+func NewSearch(query Query) Search {
+	return Search{"query": map[string]interface{}(query)}
+}
+
+// Sort sets the order results come back in. It replaces any previous sort.
+func (s Search) Sort(sorts ...SortField) Search {
+	v := make([]map[string]map[string]string, len(sorts))
+	for i, sf := range sorts {
+		v[i] = map[string]map[string]string{
+			sf.field: {"order": sf.order},
+		}
+	}
+	s["sort"] = v
+	return s
+}
+
+// Fields limits the stored fields returned for each result.
+func (s Search) Fields(fields ...string) Search {
+	s["fields"] = fields
+	return s
+}
+
+// Source limits the parts of each result's source that are returned.
+func (s Search) Source(fields ...string) Search {
+	s["source"] = fields
+	return s
+}
+
+// Size sets how many results are fetched per page.
+func (s Search) Size(n int) Search {
+	s["size"] = n
+	return s
+}
+
+// Time sets how long the scroll is kept open between pages.
+func (s Search) Time(d time.Duration) Search {
+	s["time"] = d
+	return s
+}
+
+// Slices splits the search into n slices that are scrolled through
+// concurrently, which is much faster for large result sets. Results from
+// different slices are interleaved as they arrive, so a sort only holds
+// within each slice.
+func (s Search) Slices(n int) Search {
+	s["slices"] = n
+	return s
+}
+
+// SortField is a field to sort by, and in which direction.
+type SortField struct {
+	field string
+	order string
+}
+
+// Asc sorts by field, smallest first.
+func Asc(field string) SortField {
+	return SortField{field, "asc"}
+}
+
+// Desc sorts by field, largest first.
+func Desc(field string) SortField {
+	return SortField{field, "desc"}
+}
+
+func leaf(typ, field string, value interface{}) Query {
+	return Query{typ: map[string]interface{}{field: value}}
+}
+
+// list converts queries into the form metacpanclient expects for the
+// either, all and not keys.
+func list(queries []Query) []interface{} {
+	l := make([]interface{}, len(queries))
+	for i, q := range queries {
+		l[i] = map[string]interface{}(q)
+	}
+	return l
+}
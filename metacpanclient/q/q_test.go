@@ -0,0 +1,69 @@
+package q
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query    Query
+		expected string
+	}{
+		{Term("author", "ETHER"), `{"term":{"author":"ETHER"}}`},
+		{Terms("status", "latest", "cpan"),
+			`{"terms":{"status":["latest","cpan"]}}`},
+		{Wildcard("name", "Moose*"), `{"wildcard":{"name":"Moose*"}}`},
+		{Prefix("name", "Moose"), `{"prefix":{"name":"Moose"}}`},
+		{Exists("abstract"), `{"exists":{"field":"abstract"}}`},
+		{MatchAll(), `{"match_all":{}}`},
+		{Range("date", Gte("2020-01-01"), Lt("2021-01-01")),
+			`{"range":{"date":{"gte":"2020-01-01","lt":"2021-01-01"}}}`},
+		{Range("version_numified", Gt(1), Lte(2)),
+			`{"range":{"version_numified":{"gt":1,"lte":2}}}`},
+		{Either(Term("a", 1), Not(Term("b", 2))),
+			`{"either":[{"term":{"a":1}},{"not":[{"term":{"b":2}}]}]}`},
+		{All(Term("a", 1)), `{"all":[{"term":{"a":1}}]}`},
+	} {
+		b, err := json.Marshal(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, b)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	s := NewSearch(Term("author", "ETHER")).
+		Sort(Desc("date"), Asc("name")).
+		Fields("name").
+		Source("name", "version").
+		Size(50).
+		Time(time.Minute).
+		Slices(4)
+	sort, ok := s["sort"].([]map[string]map[string]string)
+	if !ok || len(sort) != 2 || sort[0]["date"]["order"] != "desc" ||
+		sort[1]["name"]["order"] != "asc" {
+		t.Errorf("unexpected sort %#v", s["sort"])
+	}
+	if _, ok := s["query"].(map[string]interface{}); !ok {
+		t.Errorf("unexpected query %#v", s["query"])
+	}
+	if f, ok := s["fields"].([]string); !ok || len(f) != 1 {
+		t.Errorf("unexpected fields %#v", s["fields"])
+	}
+	if f, ok := s["source"].([]string); !ok || len(f) != 2 {
+		t.Errorf("unexpected source %#v", s["source"])
+	}
+	if s["size"] != 50 || s["time"] != time.Minute {
+		t.Errorf("unexpected size or time: %v, %v", s["size"], s["time"])
+	}
+	if s["slices"] != 4 {
+		t.Errorf("unexpected slices %v", s["slices"])
+	}
+}
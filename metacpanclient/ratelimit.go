@@ -0,0 +1,191 @@
+package metacpanclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// LimiterStats describes how much requests have been held back by the
+// Client's rate limit and in-flight cap. See WithRateLimit and
+// WithMaxInFlight.
+type LimiterStats struct {
+	// Requests is the number of requests that have passed through the
+	// limiter.
+	Requests uint64
+
+	// Waited is the number of those requests that had to wait at all.
+	Waited uint64
+
+	// TotalWait is the sum of time requests spent waiting.
+	TotalWait time.Duration
+
+	// MaxWait is the longest any single request has waited.
+	MaxWait time.Duration
+
+	// InFlight is the number of requests currently holding a slot,
+	// including ones whose response bodies haven't been closed yet.
+	InFlight int
+}
+
+// limiter sits in front of every request the Client makes, whether it comes
+// from a lookup, a search, a scroll or a scroll being killed.
+type limiter struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+	stats  LimiterStats
+	mu     sync.Mutex
+}
+
+// acquire blocks until the request is allowed through, returning a function
+// that gives its in-flight slot back.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	var waited time.Duration
+	if l.bucket != nil {
+		d, err := l.bucket.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		waited += d
+	}
+	release := func() {}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		default:
+			start := time.Now()
+			select {
+			case l.sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			waited += time.Since(start)
+		}
+		once := sync.Once{}
+		release = func() {
+			once.Do(func() {
+				<-l.sem
+				l.mu.Lock()
+				l.stats.InFlight--
+				l.mu.Unlock()
+			})
+		}
+	}
+	l.record(waited)
+	return release, nil
+}
+
+func (l *limiter) record(waited time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Requests++
+	if l.sem != nil {
+		l.stats.InFlight++
+	}
+	if waited <= 0 {
+		return
+	}
+	l.stats.Waited++
+	l.stats.TotalWait += waited
+	if waited > l.stats.MaxWait {
+		l.stats.MaxWait = waited
+	}
+}
+
+func (l *limiter) snapshot() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+func newLimiter(rate float64, burst, maxInFlight int) *limiter {
+	l := &limiter{}
+	if rate > 0 {
+		l.bucket = newTokenBucket(rate, burst)
+	}
+	if maxInFlight > 0 {
+		l.sem = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// tokenBucket is a plain token bucket: it holds up to burst tokens, refilled
+// at rate tokens per second, and each request takes one.
+type tokenBucket struct {
+	last   time.Time
+	now    func() time.Time
+	rate   float64
+	burst  float64
+	tokens float64
+	mu     sync.Mutex
+}
+
+// reserve takes a token, going into debt if there isn't one, and returns
+// how long the caller has to wait for the debt to be paid off.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// unreserve hands back a token taken by reserve that was never used.
+func (b *tokenBucket) unreserve() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *tokenBucket) wait(ctx context.Context) (time.Duration, error) {
+	d := b.reserve()
+	if err := sleepContext(ctx, d); err != nil {
+		b.unreserve()
+		return 0, err
+	}
+	return d, nil
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	now := time.Now
+	return &tokenBucket{
+		last:   now(),
+		now:    now,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// limitedBody gives back a request's in-flight slot once its body is closed.
+type limitedBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *limitedBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+var (
+	errNegativeRateLimit = errors.New("rate limit cannot be negative")
+	errNegativeInFlight  = errors.New("max in-flight requests cannot be " +
+		"negative")
+)
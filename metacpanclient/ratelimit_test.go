@@ -0,0 +1,112 @@
+package metacpanclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	b := newTokenBucket(2, 2)
+	b.now = func() time.Time { return now }
+	b.last = now
+	for i := 0; i < 2; i++ {
+		if d := b.reserve(); d != 0 {
+			t.Errorf("expected burst token %d to be free, waited %v",
+				i, d)
+		}
+	}
+	if d := b.reserve(); d != 500*time.Millisecond {
+		t.Errorf("expected 500ms wait, got %v", d)
+	}
+	if d := b.reserve(); d != time.Second {
+		t.Errorf("expected 1s wait, got %v", d)
+	}
+	b.unreserve()
+	now = now.Add(time.Second)
+	if d := b.reserve(); d != 0 {
+		t.Errorf("expected refilled token, waited %v", d)
+	}
+}
+
+func TestLimiter_acquire(t *testing.T) {
+	t.Parallel()
+	l := newLimiter(0, 0, 1)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err = l.acquire(ctx); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	release()
+	release() // must be idempotent
+	if _, err = l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats := l.snapshot()
+	if stats.Requests != 2 || stats.InFlight != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestClient_maxInFlight(t *testing.T) {
+	t.Parallel()
+	var cur, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&cur, 1)
+			defer atomic.AddInt32(&cur, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak,
+					p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"name":"Mojolicious"}`))
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL), WithMaxInFlight(2),
+		WithRateLimit(1000, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mc.Distribution(mojo); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("expected at most 2 requests in flight, saw %d", p)
+	}
+	stats := mc.LimiterStats()
+	if stats.Requests != 8 {
+		t.Errorf("expected 8 requests, got %d", stats.Requests)
+	}
+	if stats.Waited == 0 || stats.TotalWait <= 0 || stats.MaxWait <= 0 {
+		t.Errorf("expected some waiting, got %+v", stats)
+	}
+	if stats.InFlight != 0 {
+		t.Errorf("expected nothing in flight, got %d", stats.InFlight)
+	}
+}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
@@ -54,6 +55,10 @@ type Release struct {
 }
 
 func (r *Release) Changes() (string, error) {
+	return r.ChangesContext(context.Background())
+}
+
+func (r *Release) ChangesContext(ctx context.Context) (string, error) {
 	if r.changes != "" {
 		return r.changes, nil
 	}
@@ -68,7 +73,7 @@ func (r *Release) Changes() (string, error) {
 	req := NewRequest[*wrapper[struct {
 		Content string `json:"content"`
 	}]]("", "", r.mc.Debug(), r.mc)
-	c, err := req.Fetch(sb.String(), nil)
+	c, err := req.FetchContext(ctx, sb.String(), nil)
 	if err != nil {
 		return "", err
 	}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -15,9 +16,14 @@ type request[T result] struct {
 	BaseURL string
 	debug   bool
 	mc      *Client
+
+	// pinned is whether the request may only go to Domain, rather than
+	// trying it first and then the others.
+	pinned bool
 }
 
-func (r *request[T]) fetch(s string, m map[string]interface{}) (T, error) {
+func (r *request[T]) fetch(ctx context.Context, s string,
+	m map[string]interface{}) (T, error) {
 	if r.mc == nil {
 		var null T
 		return null, ErrNilClient
@@ -33,7 +39,11 @@ func (r *request[T]) fetch(s string, m map[string]interface{}) (T, error) {
 	}
 	var rc io.ReadCloser
 	var err error
-	rc, err = r.mc.request(r.Domain, s, b)
+	if r.pinned {
+		rc, err = r.mc.requestDomain(ctx, r.Domain, s, b)
+	} else {
+		rc, err = r.mc.request(ctx, r.Domain, s, b)
+	}
 	if err != nil {
 		var null T
 		return null, err
@@ -68,23 +78,70 @@ func (r *Request[T]) SetClient(mc *Client) error {
 }
 
 func (r *Request[T]) Fetch(s string, m map[string]interface{}) (T, error) {
+	return r.FetchContext(context.Background(), s, m)
+}
+
+func (r *Request[T]) FetchContext(ctx context.Context, s string,
+	m map[string]interface{}) (T, error) {
 	if r.mc == nil {
 		var null T
 		return null, ErrNilClient
 	}
-	return r.request.fetch(s, m)
+	return r.request.fetch(ctx, s, m)
 }
 
 func (r *Request[T]) SSearch(params map[string]interface{}) (*Scroll[T],
 	error) {
+	return r.SSearchContext(context.Background(), params)
+}
+
+func (r *Request[T]) SSearchContext(ctx context.Context,
+	params map[string]interface{}) (*Scroll[T], error) {
 	if r.mc == nil {
 		return nil, ErrNilClient
 	}
+	conf, err := r.searchConfig(params)
+	if err != nil {
+		return nil, err
+	}
+	if conf.slices > 1 {
+		return nil, errSlicedSearch
+	}
+	return r.search(ctx, conf)
+}
+
+func (r *Request[T]) search(ctx context.Context,
+	conf *searchConfig) (*Scroll[T], error) {
+	sr, err := newSearch[T](conf)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := sr.do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := rs.Scroller()
+
+	return s, err
+}
+
+// searchConfig turns search arguments into a searchConfig.
+func (r *Request[T]) searchConfig(params map[string]interface{}) (
+	*searchConfig, error) {
+	// keys are removed as they're handled, so work on a copy in case the
+	// caller wants to reuse theirs
+	args := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		args[k] = v
+	}
+	params = args
 	var query, filter map[string]interface{}
 	var scrollerSize uint16
 	var scrollerTime time.Duration
 	var fields, source []string
 	var sort []map[string]map[string]string
+	var slices int
+	var aggregations map[string]Aggregation
 
 	if v, ok := params["filter"]; ok {
 		if filter, ok = v.(map[string]interface{}); !ok {
@@ -124,6 +181,21 @@ func (r *Request[T]) SSearch(params map[string]interface{}) (*Scroll[T],
 		}
 		delete(params, "sort")
 	}
+	if v, ok := params["slices"]; ok {
+		if slices, ok = v.(int); !ok {
+			return nil, errSlicesType
+		}
+		if slices < 1 || slices > maxSlices {
+			return nil, errSlicesRange
+		}
+		delete(params, "slices")
+	}
+	if v, ok := params["aggregations"]; ok {
+		if aggregations, ok = v.(map[string]Aggregation); !ok {
+			return nil, errAggregationsType
+		}
+		delete(params, "aggregations")
+	}
 	if v, ok := params["query"]; ok {
 		if query, ok = v.(map[string]interface{}); !ok {
 			return nil, errQueryType
@@ -135,7 +207,7 @@ func (r *Request[T]) SSearch(params map[string]interface{}) (*Scroll[T],
 	} else {
 		query = params
 	}
-	sr, err := newSearch[T](&searchConfig{
+	return &searchConfig{
 		query:  query,
 		filter: filter,
 		size:   scrollerSize,
@@ -143,18 +215,11 @@ func (r *Request[T]) SSearch(params map[string]interface{}) (*Scroll[T],
 		fields: fields,
 		source: source,
 		sort:   sort,
-		mc:     r.mc,
-	})
-	if err != nil {
-		return nil, err
-	}
-	rs, err := sr.do()
-	if err != nil {
-		return nil, err
-	}
-	s := rs.Scroller()
+		slices: slices,
 
-	return s, err
+		aggregations: aggregations,
+		mc:           r.mc,
+	}, nil
 }
 
 func NewRequest[T result](domain, baseURL string, debug bool,
@@ -185,4 +250,12 @@ var (
 	errSourceType = errors.New("source must be of type []string")
 	errSortType   = errors.New("sort must be of type " +
 		"[]map[string]map[string]string")
+	errSlicesType   = errors.New("slices must be of type int")
+	errSlicesRange  = errors.New("slices must be between 1 and 1024")
+	errSlicedSearch = errors.New("sliced searches must use SSearchSliced")
+
+	errAggregationsType = errors.New("aggregations must be of type " +
+		"map[string]Aggregation")
+	errSlicedAggregations = errors.New("aggregations cannot be used " +
+		"with sliced searches")
 )
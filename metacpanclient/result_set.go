@@ -1,7 +1,10 @@
 package metacpanclient
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"iter"
 )
 
 type ResultSet[T result] interface {
@@ -9,7 +12,14 @@ type ResultSet[T result] interface {
 	Scroller() *Scroll[T]
 	Items() []T
 	Aggregations() map[string]interface{}
+	Aggregation(name string) *AggregationResult
 	Next() (T, error)
+	NextContext(context.Context) (T, error)
+	Read() (T, error)
+	ReadContext(context.Context) (T, error)
+	All() iter.Seq2[T, error]
+	AllContext(context.Context) iter.Seq2[T, error]
+	Collect(ctx context.Context, limit int) ([]T, error)
 	Total() int
 
 	_type() Type
@@ -34,6 +44,10 @@ func (r *resultSetFetch[T]) Aggregations() map[string]interface{} {
 	return nil
 }
 
+func (r *resultSetFetch[T]) Aggregation(string) *AggregationResult {
+	return nil
+}
+
 func (r *resultSetFetch[T]) HasScroller() bool {
 	return false
 }
@@ -55,13 +69,15 @@ func (r *resultSetFetch[T]) Total() int {
 }
 
 func (r *resultSetFetch[T]) Next() (T, error) {
-	var null T
-	if r.itemIndex >= len(r.items) {
-		return null, nil
+	return r.NextContext(context.Background())
+}
+
+func (r *resultSetFetch[T]) NextContext(ctx context.Context) (T, error) {
+	v, err := r.ReadContext(ctx)
+	if err == io.EOF {
+		return v, nil
 	}
-	v := r.items[r.itemIndex]
-	r.itemIndex++
-	return v, nil
+	return v, err
 }
 
 func newResultSetFetch[T result](items []T, total int) *resultSetFetch[T] {
@@ -80,8 +96,16 @@ func (r *resultSetScroll[T]) Aggregations() map[string]interface{} {
 	return r.scroller.aggregations
 }
 
+func (r *resultSetScroll[T]) Aggregation(name string) *AggregationResult {
+	return r.scroller.Aggregation(name)
+}
+
 func (r *resultSetScroll[T]) Next() (T, error) {
-	v, err := r.scroller.Next()
+	return r.NextContext(context.Background())
+}
+
+func (r *resultSetScroll[T]) NextContext(ctx context.Context) (T, error) {
+	v, err := r.scroller.NextContext(ctx)
 	if err != nil {
 		var blank T
 		return blank, err
@@ -1 +1,71 @@
 package metacpanclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestResultSetFetch_All(t *testing.T) {
+	t.Parallel()
+	rs := tNewResultSetFetch("a", "b", "c")
+	var got []string
+	for a, err := range rs.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, a.PauseID)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("unexpected results %v", got)
+	}
+	// picks up where the loop left off
+	a, err := rs.Read()
+	if err != nil || a.PauseID != "c" {
+		t.Errorf("expected c, got %v, %v", a, err)
+	}
+	if _, err = rs.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if a, err = rs.Next(); a != nil || err != nil {
+		t.Errorf("expected nil, nil from Next, got %v, %v", a, err)
+	}
+}
+
+func TestResultSetFetch_Collect(t *testing.T) {
+	t.Parallel()
+	items, err := tNewResultSetFetch("a", "b", "c").Collect(
+		context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(items))
+	}
+	items, err = tNewResultSetFetch("a", "b", "c").Collect(
+		context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 items, got %d", len(items))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = tNewResultSetFetch("a").Collect(ctx, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func tNewResultSetFetch(ids ...string) ResultSet[*Author] {
+	items := make([]*Author, len(ids))
+	for i, id := range ids {
+		items[i] = &Author{PauseID: id}
+	}
+	return newResultSetFetch[*Author](items, len(items))
+}
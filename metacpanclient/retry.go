@@ -0,0 +1,280 @@
+package metacpanclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how the Client retries failed requests and when it
+// stops sending requests to a domain that keeps failing. Searches are
+// retried and failed over the same way as other requests. The zero value
+// disables retrying and circuit breaking entirely.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent to a single
+	// domain before moving on to the next one, including the first try.
+	// Values below 1 are treated as 1.
+	MaxAttempts int
+
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts, including waits asked for
+	// by a Retry-After header. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Multiplier is what the backoff is multiplied by after each retry.
+	// Values below 1 are treated as 1.
+	Multiplier float64
+
+	// Jitter is the fraction of each backoff that's randomized, between 0
+	// and 1, so that many clients don't retry in lockstep.
+	Jitter float64
+
+	// RetryableStatus is the set of HTTP status codes that are retried.
+	RetryableStatus []int
+
+	// BreakerThreshold is how many consecutive failed requests it takes
+	// before a domain is skipped. Zero disables circuit breaking.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long a domain is skipped for once it has
+	// tripped the breaker. After that, requests are sent to it again, but
+	// a single failure trips the breaker again until one succeeds.
+	BreakerCooldown time.Duration
+}
+
+// DefaultRetryPolicy returns the RetryPolicy a Client uses unless told
+// otherwise.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+func (p *RetryPolicy) validate() error {
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 || p.BreakerCooldown < 0 {
+		return errNegativeBackoff
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errInvalidJitter
+	}
+	return nil
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns how long to wait after the given (1-indexed) attempt has
+// failed.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatus {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// transient reports whether err is worth retrying, or counting against a
+// domain's health: a response with one of RetryableStatus, a timeout, or a
+// failure to reach the domain or read its response. Anything else, such as a
+// response that can't be decoded, a lookup of a host that doesn't exist or a
+// cancelled context, isn't going to get better by trying again.
+func (p *RetryPolicy) transient(err error) bool {
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ae *APIError
+	if errors.As(err, &ae) {
+		return p.retryableStatus(ae.StatusCode)
+	}
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return !de.IsNotFound
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return true
+	}
+	// the connection was closed before the response was read
+	var ue *url.Error
+	return errors.As(err, &ue) && (errors.Is(ue.Err, io.EOF) ||
+		errors.Is(ue.Err, io.ErrUnexpectedEOF))
+}
+
+// wait returns how long to wait before retrying after the given attempt
+// failed with err, and whether to retry at all.
+func (p *RetryPolicy) wait(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.attempts() || !p.transient(err) {
+		return 0, false
+	}
+	d := p.backoff(attempt)
+	var ae *APIError
+	if errors.As(err, &ae) && ae.RetryAfter > d {
+		d = ae.RetryAfter
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			d = p.MaxBackoff
+		}
+	}
+	return d, true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// domainHealth is a circuit breaker over the Client's domains.
+type domainHealth struct {
+	states    map[string]*domainState
+	now       func() time.Time
+	cooldown  time.Duration
+	threshold int
+	mu        sync.Mutex
+}
+
+type domainState struct {
+	openUntil time.Time
+	failures  int
+}
+
+func (h *domainHealth) available(domain string) bool {
+	if h.threshold == 0 {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.states[domain]
+	return !ok || !h.now().Before(st.openUntil)
+}
+
+// order returns the domains that aren't currently tripped, in their
+// original order. If every domain is tripped, all of them are returned, as
+// there's nothing better to try.
+func (h *domainHealth) order(domains []string) []string {
+	ret := make([]string, 0, len(domains))
+	for _, d := range domains {
+		if h.available(d) {
+			ret = append(ret, d)
+		}
+	}
+	if len(ret) == 0 {
+		return domains
+	}
+	return ret
+}
+
+func (h *domainHealth) success(domain string) {
+	if h.threshold == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.states, domain)
+}
+
+func (h *domainHealth) failure(domain string) {
+	if h.threshold == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.states[domain]
+	if !ok {
+		st = &domainState{}
+		h.states[domain] = st
+	}
+	st.failures++
+	if st.failures >= h.threshold {
+		st.openUntil = h.now().Add(h.cooldown)
+		// one more failure after the cooldown trips it again
+		st.failures = h.threshold - 1
+	}
+}
+
+func newDomainHealth(p *RetryPolicy) *domainHealth {
+	return &domainHealth{
+		states:    make(map[string]*domainState),
+		now:       time.Now,
+		cooldown:  p.BreakerCooldown,
+		threshold: p.BreakerThreshold,
+	}
+}
+
+var (
+	errNegativeBackoff = errors.New("retry backoff and cooldown cannot " +
+		"be negative")
+	errInvalidJitter = errors.New("retry jitter must be between 0 and 1")
+)
@@ -0,0 +1,339 @@
+package metacpanclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClient_retry(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"Mojolicious"}`))
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond,
+		Multiplier:      2,
+		RetryableStatus: []int{http.StatusServiceUnavailable},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	d, err := mc.Distribution(mojo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != mojo {
+		t.Errorf("unexpected name")
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("expected 3 calls, got %d", n)
+	}
+}
+
+func TestClient_failover(t *testing.T) {
+	t.Parallel()
+	var downCalls, upCalls int32
+	down := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&downCalls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&upCalls, 1)
+			_, _ = w.Write([]byte(`{"name":"Mojolicious"}`))
+		}))
+	defer up.Close()
+	mc, err := New(WithDomains(down.URL, up.URL),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts:      2,
+			RetryableStatus:  []int{http.StatusBadGateway},
+			BreakerThreshold: 1,
+			BreakerCooldown:  time.Hour,
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	for i := 0; i < 3; i++ {
+		if _, err = mc.Distribution(mojo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// two attempts on the first call, then the breaker skips it
+	if n := atomic.LoadInt32(&downCalls); n != 2 {
+		t.Errorf("expected 2 calls to failing domain, got %d", n)
+	}
+	if n := atomic.LoadInt32(&upCalls); n != 3 {
+		t.Errorf("expected 3 calls to healthy domain, got %d", n)
+	}
+}
+
+func TestClient_searchRetry(t *testing.T) {
+	t.Parallel()
+	var downCalls, upCalls int32
+	down := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&downCalls, 1)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&upCalls, 1)
+			_, _ = w.Write([]byte(`{"hits": {"total": 1, ` +
+				`"hits": [{"_type": "distribution", ` +
+				`"_source": {"name": "Mojolicious"}}]}}`))
+		}))
+	defer up.Close()
+	mc, err := New(WithDomains(down.URL, up.URL),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts:      2,
+			RetryableStatus:  []int{http.StatusServiceUnavailable},
+			BreakerThreshold: 1,
+			BreakerCooldown:  time.Hour,
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		rs, err := mc.DistributionSearch(map[string]interface{}{
+			"name": mojo,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// not read, so there's no scroll to clear once the servers
+		// are gone
+		if n := rs.Total(); n != 1 {
+			t.Errorf("expected 1 result, got %d", n)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected Retry-After to be waited for, took %v",
+			elapsed)
+	}
+	// two attempts on the first search, then the breaker skips it
+	if n := atomic.LoadInt32(&downCalls); n != 2 {
+		t.Errorf("expected 2 calls to failing domain, got %d", n)
+	}
+	if n := atomic.LoadInt32(&upCalls); n != 3 {
+		t.Errorf("expected 3 calls to healthy domain, got %d", n)
+	}
+}
+
+func TestClient_scrollPinned(t *testing.T) {
+	t.Parallel()
+	var scrollCalls, otherCalls int32
+	opener := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodDelete:
+			case strings.HasPrefix(r.URL.Path, "/_search/scroll"):
+				atomic.AddInt32(&scrollCalls, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				_, _ = w.Write([]byte(`{"_scroll_id": "abc", ` +
+					`"hits": {"total": 2, "hits": [` +
+					`{"_type": "distribution", ` +
+					`"_source": {"name": "Mojolicious"}}` +
+					`]}}`))
+			}
+		}))
+	defer opener.Close()
+	other := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&otherCalls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer other.Close()
+	mc, err := New(WithDomains(opener.URL, other.URL),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts:     2,
+			RetryableStatus: []int{http.StatusServiceUnavailable},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	rs, err := mc.DistributionSearch(map[string]interface{}{"name": mojo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rs.Next(); err != nil {
+		t.Fatal(err)
+	}
+	var ae *APIError
+	if _, err = rs.Next(); !errors.As(err, &ae) ||
+		ae.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 continuing the scroll, got %v", err)
+	}
+	if n := atomic.LoadInt32(&scrollCalls); n != 2 {
+		t.Errorf("expected 2 attempts to continue the scroll, got %d",
+			n)
+	}
+	if n := atomic.LoadInt32(&otherCalls); n != 0 {
+		t.Errorf("expected no requests to the other domain, got %d", n)
+	}
+}
+
+func TestRetryPolicy_wait(t *testing.T) {
+	t.Parallel()
+	p := RetryPolicy{
+		MaxAttempts:     4,
+		InitialBackoff:  100 * time.Millisecond,
+		MaxBackoff:      300 * time.Millisecond,
+		Multiplier:      2,
+		RetryableStatus: []int{http.StatusTooManyRequests},
+	}
+	limited := &APIError{StatusCode: http.StatusTooManyRequests}
+	for i, expected := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+	} {
+		d, ok := p.wait(i+1, limited)
+		if !ok || d != expected {
+			t.Errorf("wait(%d) = %v, %v; expected %v, true", i+1, d,
+				ok, expected)
+		}
+	}
+	if _, ok := p.wait(4, limited); ok {
+		t.Errorf("expected no retry after MaxAttempts")
+	}
+	if _, ok := p.wait(1, &APIError{StatusCode: 404}); ok {
+		t.Errorf("expected no retry on 404")
+	}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	if _, ok := p.wait(1, reset); !ok {
+		t.Errorf("expected retry on network error")
+	}
+	limited.RetryAfter = time.Hour
+	if d, _ := p.wait(1, limited); d != p.MaxBackoff {
+		t.Errorf("expected Retry-After to be capped, got %v", d)
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(1)
+		if d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("backoff out of jitter range: %v", d)
+		}
+	}
+}
+
+func TestRetryPolicy_transient(t *testing.T) {
+	t.Parallel()
+	p := DefaultRetryPolicy()
+	var syntax *json.SyntaxError
+	if err := json.Unmarshal([]byte("{"), &struct{}{}); !errors.As(err,
+		&syntax) {
+		t.Fatalf("expected a syntax error, got %v", err)
+	}
+	for _, test := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"503", &APIError{StatusCode: http.StatusServiceUnavailable},
+			true},
+		{"429", &APIError{StatusCode: http.StatusTooManyRequests},
+			true},
+		{"404", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"connection refused", &url.Error{Op: "Get", URL: "http://a",
+			Err: &net.OpError{Op: "dial", Net: "tcp",
+				Err: syscall.ECONNREFUSED}}, true},
+		{"timeout", &url.Error{Op: "Get", URL: "http://a",
+			Err: &net.DNSError{IsTimeout: true}}, true},
+		{"closed connection", &url.Error{Op: "Get", URL: "http://a",
+			Err: io.EOF}, true},
+		{"no such host", &net.DNSError{IsNotFound: true}, false},
+		{"cancelled", context.Canceled, false},
+		{"undecodable response", syntax, false},
+		{"truncated response", io.ErrUnexpectedEOF, false},
+		{"other", errors.New("unsupported protocol scheme"), false},
+	} {
+		if got := p.transient(test.err); got != test.expected {
+			t.Errorf("%s: expected transient to be %v", test.name,
+				test.expected)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Sat, 01 Oct 2022 12:00:30 GMT", 30 * time.Second},
+		{"Sat, 01 Oct 2022 11:00:00 GMT", 0},
+		{"soon", 0},
+	} {
+		if got := parseRetryAfter(tc.header, now); got != tc.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v",
+				tc.header, got, tc.expected)
+		}
+	}
+}
+
+func TestDomainHealth(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	h := newDomainHealth(&RetryPolicy{
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+	h.now = func() time.Time { return now }
+	domains := []string{"a", "b"}
+	h.failure("a")
+	if got := h.order(domains); len(got) != 2 {
+		t.Errorf("tripped too early: %v", got)
+	}
+	h.failure("a")
+	if got := h.order(domains); len(got) != 1 || got[0] != "b" {
+		t.Errorf("expected only b, got %v", got)
+	}
+	h.failure("b")
+	h.failure("b")
+	if got := h.order(domains); len(got) != 2 {
+		t.Errorf("expected every domain when all tripped, got %v", got)
+	}
+	now = now.Add(2 * time.Minute)
+	if !h.available("a") {
+		t.Errorf("expected a to be available after cooldown")
+	}
+	h.success("a")
+	h.failure("a")
+	if !h.available("a") {
+		t.Errorf("expected success to reset failures")
+	}
+}
@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 
 type Scroll[T result] struct {
 	aggregations map[string]interface{}
+	aggResults   map[string]*AggregationResult
 	baseURL      string
 	buffer       []T
 	bufferIndex  int
@@ -35,6 +37,10 @@ func (s *Scroll[T]) kill(force bool) (bool, error) {
 		return false, nil
 	}
 	defer s.mu.Unlock()
+	if !force && !s.timedOut() {
+		// still being read from
+		return false, nil
+	}
 	sb := strings.Builder{}
 	sb.WriteString(s.baseURL)
 	sb.WriteRune('/')
@@ -46,13 +52,16 @@ func (s *Scroll[T]) kill(force bool) (bool, error) {
 		return true, fmt.Errorf("error creating DELETE request for "+
 			"scroll '%s': %s", s.scrollID, err)
 	}
-	switch resp, err := s.mc.auto.RoundTrip(req); {
+	switch resp, err := s.mc.hc.Do(req); {
 	case err != nil:
 		return true, fmt.Errorf("error sending DELETE request for "+
 			"scroll '%s': %s", s.scrollID, err)
 	case resp.StatusCode != http.StatusOK:
+		defer internal.CloseBody(resp.Body)
 		return true, fmt.Errorf("error sending DELETE request for "+
-			"scroll '%s': %s", s.scrollID, resp.Status)
+			"scroll '%s': %w", s.scrollID, newAPIError(s.baseURL,
+			"/_search/scroll", resp.StatusCode, resp.Header,
+			resp.Body))
 	default:
 		internal.CloseBody(resp.Body)
 		return true, nil
@@ -81,35 +90,55 @@ type mortal interface {
 }
 
 func (s *Scroll[T]) Next() (T, error) {
+	return s.NextContext(context.Background())
+}
+
+// NextContext is like Next, but with a context. It returns a zero T and a
+// nil error once there are no more results; see ReadContext for a version
+// that returns io.EOF instead.
+func (s *Scroll[T]) NextContext(ctx context.Context) (T, error) {
+	v, _, err := s.next(ctx)
+	return v, err
+}
+
+// next returns the next result, or false if there are no more.
+func (s *Scroll[T]) next(ctx context.Context) (T, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	var null T
 	if s.timedOut() {
-		return null, errors.New("scroll timed out")
+		return null, false, ErrScrollExpired
 	}
 	if !s.registered {
 		s.mc.registerScroll(s)
 		s.registered = true
 	}
 	if s.bufferIndex == len(s.buffer) {
-		if err := s.fetchNext(); err != nil {
-			return null, err
+		if err := s.fetchNext(ctx); err != nil {
+			return null, false, err
 		}
 	}
 	if len(s.buffer) == 0 {
-		return null, nil
+		return null, false, nil
 	}
 	s.lastUpdate = time.Now()
 	v := s.buffer[s.bufferIndex]
 	s.buffer[s.bufferIndex] = null
 	s.bufferIndex++
-	return v, nil
+	return v, true, nil
 }
 
 func (s *Scroll[T]) Total() int {
 	return s.total
 }
 
+// Aggregation returns the result of the named aggregation, or nil if the
+// search didn't ask for it. Aggregations are computed over everything the
+// search matched, and come with the first page of results.
+func (s *Scroll[T]) Aggregation(name string) *AggregationResult {
+	return s.aggResults[name]
+}
+
 func (s *Scroll[T]) Type() Type {
 	return getType[T]()
 }
@@ -121,10 +150,29 @@ func (s *Scroll[T]) UnmarshalJSON(data []byte) error {
 			Total int      `json:"total"`
 			Hits  []hit[T] `json:"hits"`
 		} `json:"hits"`
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
 	}
 	if err := json.Unmarshal(data, &v); err != nil {
 		return err
 	}
+	if v.Aggregations != nil {
+		s.aggregations = make(map[string]interface{},
+			len(v.Aggregations))
+		s.aggResults = make(map[string]*AggregationResult,
+			len(v.Aggregations))
+		for k, raw := range v.Aggregations {
+			var agg interface{}
+			var res AggregationResult
+			if err := json.Unmarshal(raw, &agg); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, &res); err != nil {
+				return fmt.Errorf("aggregation %s: %w", k, err)
+			}
+			s.aggregations[k] = agg
+			s.aggResults[k] = &res
+		}
+	}
 	items := make([]T, len(v.Hits.Hits))
 	for i, hit := range v.Hits.Hits {
 		items[i] = hit.Source
@@ -146,26 +194,28 @@ func NewScroll[T result](baseURL string, dur time.Duration,
 	}
 }
 
-func (s *Scroll[T]) fetchNext() error {
+func (s *Scroll[T]) fetchNext(ctx context.Context) error {
 	// assumes we've already locked the buffer in Next()
 	if s.scrollID == "" {
-		return errors.New("no scroll id")
+		return errNoScrollID
 	}
 	if s.mc == nil {
-		return errors.New("no client")
+		return ErrNilClient
 	}
 	// have to do this directly, or we hit an instantiation error, because
 	// we'd have to instantiate a Scroll[T] even though it would never be
 	// used
 	req := &request[*wrapper[struct {
 		Hits struct {
-			Hits []T `json:"hits"`
+			Hits []hit[T] `json:"hits"`
 		} `json:"hits"`
 	}]]{
-		Domain:  "",
+		// the scroll only exists on the domain that opened it
+		Domain:  s.baseURL,
 		BaseURL: "",
 		debug:   s.mc.Debug(),
 		mc:      s.mc,
+		pinned:  s.baseURL != "",
 	}
 	scrollTime := s.timeout
 	if scrollTime == 0 {
@@ -177,14 +227,20 @@ func (s *Scroll[T]) fetchNext() error {
 	}
 	path := fmt.Sprintf("/_search/scroll/%s?scroll=%s&size=%d",
 		s.scrollID, adjustTimeString(scrollTime), scrollSize)
-	set, err := req.fetch(path, nil)
-	s.bufferIndex = 0
+	set, err := req.fetch(ctx, path, nil)
 	if err != nil {
-
 		return err
 	}
-	items := set.Result.Hits.Hits
+	items := make([]T, len(set.Result.Hits.Hits))
+	for i, hit := range set.Result.Hits.Hits {
+		items[i] = hit.Source
+	}
 	s.total = len(items)
 	s.buffer = items
+	s.bufferIndex = 0
 	return nil
 }
+
+var (
+	errNoScrollID = errors.New("no scroll id")
+)
@@ -1 +1,41 @@
 package metacpanclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScroll_failedFetch(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tCloseClient(mc, t)
+	s := &Scroll[*Author]{
+		baseURL:    srv.URL,
+		buffer:     []*Author{{PauseID: "SRI"}},
+		mc:         mc,
+		scrollID:   "abc",
+		timeout:    time.Hour,
+		lastUpdate: time.Now(),
+		registered: true,
+	}
+	if a, err := s.Next(); err != nil || a.PauseID != "SRI" {
+		t.Fatalf("unexpected first result %v, %v", a, err)
+	}
+	// a failed fetch mustn't leave the results already read to be read
+	// again
+	for i := 0; i < 2; i++ {
+		if a, err := s.Next(); err == nil {
+			t.Errorf("expected an error, got %v", a)
+		}
+	}
+}
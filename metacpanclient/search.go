@@ -2,6 +2,7 @@ package metacpanclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 
 	// local
 	pui "github.com/cmburn/perlutils/internal"
+	"github.com/cmburn/perlutils/metacpanclient/q"
 
 	// external
 	"github.com/elastic/go-elasticsearch/v7"
@@ -20,6 +22,8 @@ type search[T result] struct {
 	fields        []string
 	source        []string
 	Query         map[string]interface{} `json:"query,omitempty"`
+	Slice         *searchSlice           `json:"slice,omitempty"`
+	Aggregations  map[string]interface{} `json:"aggs,omitempty"`
 	filter        map[string]interface{}
 	sort          []string
 	haveWildcards bool
@@ -34,9 +38,19 @@ type searchConfig struct {
 	query  map[string]interface{}
 	filter map[string]interface{}
 	sort   []map[string]map[string]string
+	slice  *searchSlice
 	time   time.Duration
 	size   uint16
-	mc     *Client
+	slices int
+
+	aggregations map[string]Aggregation
+	mc           *Client
+}
+
+// searchSlice picks out one slice of a sliced scroll.
+type searchSlice struct {
+	ID  int `json:"id"`
+	Max int `json:"max"`
 }
 
 func newSearch[T result](config *searchConfig) (*search[T], error) {
@@ -50,10 +64,15 @@ func newSearch[T result](config *searchConfig) (*search[T], error) {
 	if config.size > 10000 {
 		return nil, errScrollSizeMax
 	}
+	config.query = copyQuery(config.query).(map[string]interface{})
 	wc, err := adjust(config.query)
 	if err != nil {
 		return nil, err
 	}
+	aggs, err := buildAggregations(config.aggregations)
+	if err != nil {
+		return nil, err
+	}
 	if config.time == 0 {
 		config.time = config.mc.timeout
 	}
@@ -79,6 +98,8 @@ func newSearch[T result](config *searchConfig) (*search[T], error) {
 	}
 	s := &search[T]{
 		Query:         config.query,
+		Slice:         config.slice,
+		Aggregations:  aggs,
 		filter:        config.filter,
 		fields:        config.fields,
 		source:        config.source,
@@ -91,12 +112,34 @@ func newSearch[T result](config *searchConfig) (*search[T], error) {
 	return s, nil
 }
 
-func (s *search[T]) do() (*resultSetScroll[T], error) {
-	opts, err := s.build(s.mc.es)
+// do runs the search, failing over between domains and retrying as other
+// requests do.
+func (s *search[T]) do(ctx context.Context) (*resultSetScroll[T], error) {
+	t := getType[T]()
+	path := "/" + t.String() + "/_search"
+	return eachDomain(ctx, s.mc, "", path,
+		func(ctx context.Context,
+			domain string) (*resultSetScroll[T], error) {
+			return s.doDomain(ctx, domain, path)
+		})
+}
+
+// doDomain runs the search against a single domain.
+func (s *search[T]) doDomain(ctx context.Context, domain,
+	path string) (*resultSetScroll[T], error) {
+	es := s.mc.es[domain]
+	// built afresh each time, as the body is read by each attempt
+	opts, err := s.build(es)
 	if err != nil {
 		return nil, err
 	}
-	res, err := s.mc.es.Search(opts...)
+	if s.mc.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.mc.requestTimeout)
+		defer cancel()
+	}
+	opts = append(opts, es.Search.WithContext(ctx))
+	res, err := es.Search(opts...)
 	if res != nil {
 		defer pui.CloseBody(res.Body)
 	}
@@ -104,7 +147,8 @@ func (s *search[T]) do() (*resultSetScroll[T], error) {
 		return nil, err
 	}
 	if res.IsError() {
-		return nil, errors.New(res.Status())
+		return nil, newAPIError(domain, path, res.StatusCode,
+			res.Header, res.Body)
 	}
 	var rs resultSetScroll[T]
 	if err := json.NewDecoder(res.Body).Decode(&rs); err != nil {
@@ -112,13 +156,13 @@ func (s *search[T]) do() (*resultSetScroll[T], error) {
 	}
 	rs.scroller.timeout = s.scrollerTime
 	rs.scroller.mc = s.mc
-	rs.scroller.baseURL = s.mc.domains[0]
+	rs.scroller.baseURL = domain
 	return &rs, nil
 }
 
 func (s *search[T]) build(es *elasticsearch.Client) (
 	[]func(*esapi.SearchRequest), error) {
-	nOpts := 2
+	nOpts := 3
 	for _, cond := range []bool{s.fields != nil, s.scrollerSize > 0,
 		s.scrollerTime > 0, s.sort != nil, s.source != nil,
 		s.haveWildcards} {
@@ -243,6 +287,11 @@ func adjustMap(m map[string]interface{}) (bool, error) {
 			// do nothing
 			return wc, nil
 		}
+		_, isMap := value.(map[string]interface{})
+		if isMap && isLeafQuery(key) {
+			// already an Elasticsearch query, as built by package q
+			return key == "wildcard", nil
+		}
 		if !isValue(value) {
 			return false, errInvalidSearchEntry
 		}
@@ -261,6 +310,45 @@ func adjustMap(m map[string]interface{}) (bool, error) {
 	return wc, nil
 }
 
+func isLeafQuery(key string) bool {
+	switch key {
+	case "term", "terms", "wildcard", "prefix", "range", "exists":
+		return true
+	default:
+		return false
+	}
+}
+
+// copyQuery deep-copies the maps and slices of a query, as adjust rewrites
+// them in place and the caller may want to reuse theirs. Queries from package
+// q become plain maps, so they're adjusted wherever they're nested.
+func copyQuery(i interface{}) interface{} {
+	switch v := i.(type) {
+	case q.Query:
+		return copyQuery(map[string]interface{}(v))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = copyQuery(x)
+		}
+		return m
+	case []q.Query:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = copyQuery(x)
+		}
+		return l
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = copyQuery(x)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
 func adjust(i interface{}) (bool, error) {
 	switch v := i.(type) {
 	case map[string]interface{}:
@@ -4,6 +4,9 @@ import (
 	goJson "encoding/json"
 	"reflect"
 	"testing"
+
+	// local
+	"github.com/cmburn/perlutils/metacpanclient/q"
 )
 
 func TestSearchAdjust(t *testing.T) {
@@ -31,6 +34,43 @@ func TestSearchAdjust(t *testing.T) {
 	}
 }
 
+func TestSearchAdjust_query(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query    q.Query
+		expected string
+	}{
+		{q.Either(q.Wildcard("name", "Dave *"),
+			q.Wildcard("name", "David *")), testTestNewSearchInput0},
+		{q.All(q.Term("author", "ETHER"), q.Not(q.Term("status",
+			"backpan"))), `{"all": [{"author": "ETHER"},
+			{"not": [{"status": "backpan"}]}]}`},
+		// queries nested in a hand-built map
+		{q.Query{"all": []interface{}{q.Term("author", "ETHER"),
+			map[string]interface{}{"not": []q.Query{q.Term("status",
+				"backpan")}}}}, `{"all": [{"author": "ETHER"},
+			{"not": [{"status": "backpan"}]}]}`},
+	} {
+		got := tRemarshal(tNewSearch(tt.query).Query)
+		expected := tRemarshal(tNewSearch(tUnmarshal(tt.expected)).Query)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("got %v, expected %v", got, expected)
+		}
+	}
+	// queries aren't consumed by searching with them
+	query := q.Either(q.Term("a", 1), q.Term("b", 2))
+	first := tRemarshal(tNewSearch(query).Query)
+	second := tRemarshal(tNewSearch(query).Query)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("reused query gave %v, then %v", first, second)
+	}
+	// term is exact, even with a '*' in it
+	got := tRemarshal(tNewSearch(q.Term("name", "a*")).Query)
+	if _, ok := got["term"]; !ok {
+		t.Errorf("expected a term query, got %v", got)
+	}
+}
+
 type tFakeResult = *wrapper[struct{}]
 
 func tNewSearch(m map[string]interface{}) *search[tFakeResult] {
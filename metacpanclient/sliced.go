@@ -0,0 +1,235 @@
+package metacpanclient
+
+import (
+	"context"
+	"io"
+	"iter"
+	"sync"
+)
+
+// SSearchSliced is like SSearch, but splits the search into the number of
+// slices given by the "slices" argument, which are scrolled through
+// concurrently and merged into a single ResultSet. This is much faster for
+// large exports, at the cost of ordering: results from different slices are
+// interleaved in whatever order they arrive, so a sort only holds within
+// each slice.
+func (r *Request[T]) SSearchSliced(params map[string]interface{}) (
+	ResultSet[T], error) {
+	return r.SSearchSlicedContext(context.Background(), params)
+}
+
+// SSearchSlicedContext is like SSearchSliced, but with a context.
+func (r *Request[T]) SSearchSlicedContext(ctx context.Context,
+	params map[string]interface{}) (ResultSet[T], error) {
+	if r.mc == nil {
+		return nil, ErrNilClient
+	}
+	conf, err := r.searchConfig(params)
+	if err != nil {
+		return nil, err
+	}
+	n := conf.slices
+	if n < 1 {
+		n = 1
+	}
+	if n > 1 && conf.aggregations != nil {
+		// each slice would only aggregate its own share of the results
+		return nil, errSlicedAggregations
+	}
+	scrolls := make([]*Scroll[T], n)
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+	for i := range scrolls {
+		c := *conf
+		if n > 1 {
+			c.slice = &searchSlice{ID: i, Max: n}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scrolls[i], errs[i] = r.search(ctx, &c)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		for _, s := range scrolls {
+			if s != nil {
+				s.release()
+			}
+		}
+		return nil, err
+	}
+	for _, s := range scrolls {
+		// registered now rather than on first use, as the goroutines
+		// reading them mustn't take the client's scroll lock while
+		// holding their own
+		s.mc.registerScroll(s)
+		s.registered = true
+	}
+	return newResultSetSliced(scrolls), nil
+}
+
+// resultSetSliced merges the results of several scrolls.
+type resultSetSliced[T result] struct {
+	scrolls []*Scroll[T]
+	ch      chan sliceResult[T]
+	cancel  context.CancelFunc
+	total   int
+	start   sync.Once
+	wg      sync.WaitGroup
+}
+
+type sliceResult[T result] struct {
+	v   T
+	err error
+}
+
+func newResultSetSliced[T result](scrolls []*Scroll[T]) *resultSetSliced[T] {
+	r := &resultSetSliced[T]{
+		scrolls: scrolls,
+		ch:      make(chan sliceResult[T]),
+	}
+	for _, s := range scrolls {
+		// only the first page has the real total
+		r.total += s.total
+	}
+	return r
+}
+
+// run starts reading every slice, sending what's read to r.ch, which is
+// closed once they're all done. The slices are read until release is
+// called or the client is closed, so that a caller who stops reading early
+// doesn't leave them blocked forever.
+func (r *resultSetSliced[T]) run() {
+	parent := context.Background()
+	if mc := r.scrolls[0].mc; mc != nil && mc.ctx != nil {
+		parent = mc.ctx
+	}
+	ctx, cancel := context.WithCancel(parent)
+	r.cancel = cancel
+	for _, s := range r.scrolls {
+		r.wg.Add(1)
+		go func(s *Scroll[T]) {
+			defer r.wg.Done()
+			for {
+				v, err := s.ReadContext(ctx)
+				if err == io.EOF {
+					return
+				}
+				select {
+				case r.ch <- sliceResult[T]{v, err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(s)
+	}
+	go func() {
+		r.wg.Wait()
+		close(r.ch)
+	}()
+}
+
+// release stops reading and clears every slice's scroll.
+func (r *resultSetSliced[T]) release() {
+	r.start.Do(r.run)
+	r.cancel()
+	r.wg.Wait()
+	for _, s := range r.scrolls {
+		s.release()
+	}
+}
+
+func (r *resultSetSliced[T]) Aggregations() map[string]interface{} {
+	return nil
+}
+
+func (r *resultSetSliced[T]) Aggregation(string) *AggregationResult {
+	return nil
+}
+
+func (r *resultSetSliced[T]) HasScroller() bool {
+	return false
+}
+
+func (r *resultSetSliced[T]) Scroller() *Scroll[T] {
+	return nil
+}
+
+func (r *resultSetSliced[T]) Items() []T {
+	return nil
+}
+
+func (r *resultSetSliced[T]) Total() int {
+	return r.total
+}
+
+func (r *resultSetSliced[T]) Next() (T, error) {
+	return r.NextContext(context.Background())
+}
+
+func (r *resultSetSliced[T]) NextContext(ctx context.Context) (T, error) {
+	v, err := r.ReadContext(ctx)
+	if err == io.EOF {
+		return v, nil
+	}
+	return v, err
+}
+
+func (r *resultSetSliced[T]) Read() (T, error) {
+	return r.ReadContext(context.Background())
+}
+
+// ReadContext returns the next result from whichever slice has one first,
+// or io.EOF once they're all done. An error from one slice ends only that
+// slice.
+func (r *resultSetSliced[T]) ReadContext(ctx context.Context) (T, error) {
+	r.start.Do(r.run)
+	var null T
+	select {
+	case <-ctx.Done():
+		return null, ctx.Err()
+	case res, ok := <-r.ch:
+		if !ok {
+			return null, io.EOF
+		}
+		return res.v, res.err
+	}
+}
+
+func (r *resultSetSliced[T]) All() iter.Seq2[T, error] {
+	return r.AllContext(context.Background())
+}
+
+// AllContext returns an iterator over the merged results. Breaking out of
+// the loop early clears every slice's scroll.
+func (r *resultSetSliced[T]) AllContext(
+	ctx context.Context) iter.Seq2[T, error] {
+	return seq(ctx, r.ReadContext, r.release)
+}
+
+func (r *resultSetSliced[T]) Collect(ctx context.Context, limit int) ([]T,
+	error) {
+	return collect(r.AllContext(ctx), limit)
+}
+
+func (r *resultSetSliced[T]) _type() Type {
+	return getType[T]()
+}
+
+func (r *resultSetSliced[T]) setClient(mc *Client) {
+	for _, s := range r.scrolls {
+		s.mc = mc
+	}
+}
+
+const (
+	// maxSlices is Elasticsearch's default limit on slices per scroll.
+	maxSlices = 1024
+)
@@ -0,0 +1,44 @@
+package metacpanclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResultSetSliced_close(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	mc, err := New(WithDomains(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrolls := make([]*Scroll[*Author], 3)
+	for i := range scrolls {
+		scrolls[i] = &Scroll[*Author]{
+			buffer:     []*Author{{}, {}, {}},
+			mc:         mc,
+			timeout:    time.Hour,
+			lastUpdate: time.Now(),
+			registered: true,
+		}
+	}
+	r := newResultSetSliced(scrolls)
+	if _, err = r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// the slices are left blocked on results nobody's going to read
+	tCloseClient(mc, t)
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slices still being read after the client was closed")
+	}
+}
@@ -1,18 +1,22 @@
 package metacpanclient
 
 import (
-	"fmt"
 	"net/http"
 	"net/http/httputil"
 )
 
 type transport struct {
+	next      http.RoundTripper
+	limiter   *limiter
+	logger    Logger
 	userAgent string
 	post      bool
 	debug     bool
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers aren't supposed to modify the request they're given
+	req = req.Clone(req.Context())
 	if t.post {
 		req.Method = "POST"
 	}
@@ -20,19 +24,33 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.debug {
 		// ok if not covered by tests
 		dump, _ := httputil.DumpRequestOut(req, true)
-		fmt.Printf("%s", dump)
+		t.logger.Printf("%s", dump)
 	}
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	release, err := t.limiter.acquire(req.Context())
 	if err != nil {
 		return nil, err
 	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
 	if t.debug && resp != nil {
 		dump, _ := httputil.DumpResponse(resp, true)
-		fmt.Printf("%s", dump)
+		t.logger.Printf("%s", dump)
 	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, release: release}
 	return resp, nil
 }
 
-func newTransport(userAgent string, post, debug bool) *transport {
-	return &transport{userAgent: userAgent, post: post, debug: debug}
+func newTransport(next http.RoundTripper, lim *limiter, logger Logger,
+	userAgent string, post, debug bool) *transport {
+	return &transport{
+		next:      next,
+		limiter:   lim,
+		logger:    logger,
+		userAgent: userAgent,
+		post:      post,
+		debug:     debug,
+	}
 }
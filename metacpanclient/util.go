@@ -1,6 +1,7 @@
 package metacpanclient
 
 import (
+	"context"
 	"strings"
 	"time"
 )
@@ -17,11 +18,11 @@ func buildRequestURL(path, target, query string) string {
 	return sb.String()
 }
 
-func post[T any](mc *Client, path, target, query string,
+func post[T any](ctx context.Context, mc *Client, path, target, query string,
 	params map[string]interface{}) (T, error) {
 	req := newRequest[*wrapper[T]](mc)
 	url := buildRequestURL(path, target, query)
-	res, err := req.Fetch(url, params)
+	res, err := req.FetchContext(ctx, url, params)
 	if err != nil {
 		var null T
 		return null, err
@@ -29,15 +30,16 @@ func post[T any](mc *Client, path, target, query string,
 	return res.Result, nil
 }
 
-func get[T any](mc *Client, path, item string) (T, error) {
-	return post[T](mc, path, item, "", nil)
+func get[T any](ctx context.Context, mc *Client, path, item string) (T,
+	error) {
+	return post[T](ctx, mc, path, item, "", nil)
 }
 
-func getResult[T result](mc *Client, path, item string) (T,
-	error) {
+func getResult[T result](ctx context.Context, mc *Client, path,
+	item string) (T, error) {
 	req := newRequest[T](mc)
 	url := buildRequestURL(path, item, "")
-	v, err := req.Fetch(url, nil)
+	v, err := req.FetchContext(ctx, url, nil)
 	if err != nil {
 		var null T
 		return null, err
@@ -46,14 +48,15 @@ func getResult[T result](mc *Client, path, item string) (T,
 	return v, nil
 }
 
-func query[T any](mc *Client, path, item, query string) (T, error) {
-	return post[T](mc, path, item, query, nil)
+func query[T any](ctx context.Context, mc *Client, path, item,
+	query string) (T, error) {
+	return post[T](ctx, mc, path, item, query, nil)
 }
 
-func doSearch[T result](mc *Client, params map[string]interface{}) (*Scroll[T],
-	error) {
+func doSearch[T result](ctx context.Context, mc *Client,
+	params map[string]interface{}) (*Scroll[T], error) {
 	req := newRequest[T](mc)
-	res, err := req.SSearch(params)
+	res, err := req.SSearchContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +64,12 @@ func doSearch[T result](mc *Client, params map[string]interface{}) (*Scroll[T],
 	return res, nil
 }
 
-func rsSearch[T result](mc *Client, params map[string]interface{}) (
-	ResultSet[T], error) {
-	s, err := doSearch[T](mc, params)
+func rsSearch[T result](ctx context.Context, mc *Client,
+	params map[string]interface{}) (ResultSet[T], error) {
+	if _, ok := params["slices"]; ok {
+		return newRequest[T](mc).SSearchSlicedContext(ctx, params)
+	}
+	s, err := doSearch[T](ctx, mc, params)
 	if err != nil {
 		return nil, err
 	}
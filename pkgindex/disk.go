@@ -0,0 +1,400 @@
+package pkgindex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// A disk index file is laid out as:
+//
+//	magic
+//	header length (uint32) and header, as "Name: value\n" lines
+//	records, as "name\tversion\tpath", in the order they were read
+//	package table: offset (uint64) and length (uint32) of each record,
+//	    sorted by name
+//	distribution table: index into the package table (uint32) of each
+//	    record, sorted by path and then name
+//	footer: magic, count, package table offset, distribution table offset
+//
+// with every integer big-endian.
+const (
+	diskMagic      = "02PKGIDX"
+	diskEntrySize  = 12
+	diskDistSize   = 4
+	diskFooterSize = len(diskMagic) + 3*8
+)
+
+// DiskIndex is an Index kept in a file, which is read as packages are looked
+// up rather than being loaded into memory. It's safe for concurrent use.
+type DiskIndex struct {
+	f      *os.File
+	header *Header
+	n      int
+	pkgs   int64
+	dists  int64
+}
+
+// BuildDiskIndex reads an index file from r and writes it to a disk index at
+// path, for OpenDiskIndex. The file is written alongside path and renamed
+// into place, so a DiskIndex already open at path is left undisturbed. If a
+// package appears more than once, the last one wins.
+func BuildDiskIndex(path string, r io.Reader) (err error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rd.Close() }()
+	f, err := os.CreateTemp(filepath.Dir(path), ".pkgindex-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	b := &diskBuilder{w: bufio.NewWriter(f)}
+	if err = b.writeHeader(rd.Header()); err != nil {
+		return err
+	}
+	for p, err := range rd.All() {
+		if err != nil {
+			return err
+		}
+		if err = b.writeRecord(p); err != nil {
+			return err
+		}
+	}
+	if err = b.writeTables(); err != nil {
+		return err
+	}
+	if err = b.w.Flush(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// OpenDiskIndex opens a disk index written by BuildDiskIndex. It should be
+// closed once it's no longer needed.
+func OpenDiskIndex(path string) (*DiskIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &DiskIndex{f: f}
+	if err = d.load(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Close closes the file.
+func (d *DiskIndex) Close() error {
+	return d.f.Close()
+}
+
+func (d *DiskIndex) Header() *Header {
+	return d.header
+}
+
+func (d *DiskIndex) Len() int {
+	return d.n
+}
+
+func (d *DiskIndex) Package(name string) (*Package, error) {
+	var err error
+	i := sort.Search(d.n, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var p *Package
+		if p, err = d.record(i); err != nil {
+			return true
+		}
+		return compareNames(p.Name, name) >= 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	if i < d.n {
+		p, err := d.record(i)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (d *DiskIndex) Distribution(path string) ([]*Package, error) {
+	var err error
+	i := sort.Search(d.n, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var p *Package
+		if p, err = d.distRecord(i); err != nil {
+			return true
+		}
+		return p.Path >= path
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ps []*Package
+	for ; i < d.n; i++ {
+		p, err := d.distRecord(i)
+		if err != nil {
+			return nil, err
+		}
+		if p.Path != path {
+			break
+		}
+		ps = append(ps, p)
+	}
+	if len(ps) == 0 {
+		return nil, ErrNotFound
+	}
+	return ps, nil
+}
+
+func (d *DiskIndex) All() iter.Seq2[*Package, error] {
+	return func(yield func(*Package, error) bool) {
+		for i := 0; i < d.n; i++ {
+			p, err := d.record(i)
+			if !yield(p, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (d *DiskIndex) load() error {
+	fi, err := d.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	if size < int64(len(diskMagic)+4+diskFooterSize) {
+		return errDiskIndexFormat
+	}
+	buf := make([]byte, diskFooterSize)
+	if _, err = d.f.ReadAt(buf, size-int64(diskFooterSize)); err != nil {
+		return err
+	}
+	if string(buf[:len(diskMagic)]) != diskMagic {
+		return errDiskIndexFormat
+	}
+	buf = buf[len(diskMagic):]
+	n := binary.BigEndian.Uint64(buf)
+	d.pkgs = int64(binary.BigEndian.Uint64(buf[8:]))
+	d.dists = int64(binary.BigEndian.Uint64(buf[16:]))
+	if n > uint64(size) || d.pkgs < 0 ||
+		d.pkgs+int64(n)*diskEntrySize != d.dists ||
+		d.dists+int64(n)*diskDistSize != size-int64(diskFooterSize) {
+		return errDiskIndexFormat
+	}
+	d.n = int(n)
+	buf = make([]byte, len(diskMagic)+4)
+	if _, err = d.f.ReadAt(buf, 0); err != nil {
+		return err
+	}
+	if string(buf[:len(diskMagic)]) != diskMagic {
+		return errDiskIndexFormat
+	}
+	hlen := int64(binary.BigEndian.Uint32(buf[len(diskMagic):]))
+	if int64(len(buf))+hlen > d.pkgs {
+		return errDiskIndexFormat
+	}
+	text := make([]byte, hlen)
+	if _, err = d.f.ReadAt(text, int64(len(buf))); err != nil {
+		return err
+	}
+	d.header = &Header{}
+	for _, line := range strings.Split(string(text), "\n") {
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return errDiskIndexFormat
+		}
+		d.header.Fields = append(d.header.Fields, HeaderField{name,
+			value})
+	}
+	return nil
+}
+
+// record returns the i'th package, by name.
+func (d *DiskIndex) record(i int) (*Package, error) {
+	var buf [diskEntrySize]byte
+	_, err := d.f.ReadAt(buf[:], d.pkgs+int64(i)*diskEntrySize)
+	if err != nil {
+		return nil, err
+	}
+	off := int64(binary.BigEndian.Uint64(buf[:]))
+	rec := make([]byte, binary.BigEndian.Uint32(buf[8:]))
+	if _, err = d.f.ReadAt(rec, off); err != nil {
+		return nil, err
+	}
+	fields := strings.Split(string(rec), "\t")
+	if len(fields) != 3 {
+		return nil, errDiskIndexFormat
+	}
+	v, err := version.Parse(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return &Package{Name: fields[0], Version: v, Path: fields[2]}, nil
+}
+
+// distRecord returns the i'th package, by distribution.
+func (d *DiskIndex) distRecord(i int) (*Package, error) {
+	var buf [diskDistSize]byte
+	_, err := d.f.ReadAt(buf[:], d.dists+int64(i)*diskDistSize)
+	if err != nil {
+		return nil, err
+	}
+	j := int(binary.BigEndian.Uint32(buf[:]))
+	if j >= d.n {
+		return nil, errDiskIndexFormat
+	}
+	return d.record(j)
+}
+
+// diskBuilder writes a disk index, keeping just enough of each record in
+// memory to sort the tables.
+type diskBuilder struct {
+	w       *bufio.Writer
+	off     int64
+	entries []diskEntry
+}
+
+type diskEntry struct {
+	name, path string
+	off        int64
+	len        uint32
+	seq        int
+}
+
+func (b *diskBuilder) write(p []byte) error {
+	n, err := b.w.Write(p)
+	b.off += int64(n)
+	return err
+}
+
+func (b *diskBuilder) writeUint(v uint64, size int) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return b.write(buf[8-size:])
+}
+
+func (b *diskBuilder) writeHeader(h *Header) error {
+	var sb strings.Builder
+	for _, f := range h.Fields {
+		sb.WriteString(f.Name + ": " + f.Value + "\n")
+	}
+	if err := b.write([]byte(diskMagic)); err != nil {
+		return err
+	}
+	if err := b.writeUint(uint64(sb.Len()), 4); err != nil {
+		return err
+	}
+	return b.write([]byte(sb.String()))
+}
+
+func (b *diskBuilder) writeRecord(p *Package) error {
+	v := p.Version.Raw()
+	if v == "" {
+		v = "undef"
+	}
+	rec := p.Name + "\t" + v + "\t" + p.Path
+	b.entries = append(b.entries, diskEntry{
+		name: p.Name,
+		path: p.Path,
+		off:  b.off,
+		len:  uint32(len(rec)),
+		seq:  len(b.entries),
+	})
+	return b.write([]byte(rec))
+}
+
+func (b *diskBuilder) writeTables() error {
+	es := b.entries
+	sort.Slice(es, func(i, j int) bool {
+		if c := compareNames(es[i].name, es[j].name); c != 0 {
+			return c < 0
+		}
+		return es[i].seq < es[j].seq
+	})
+	// drop all but the last of each name
+	out := es[:0]
+	for i, e := range es {
+		if i+1 < len(es) && es[i+1].name == e.name {
+			continue
+		}
+		out = append(out, e)
+	}
+	es = out
+	if uint64(len(es)) > 1<<32-1 {
+		return errDiskIndexSize
+	}
+	pkgs := b.off
+	for _, e := range es {
+		if err := b.writeUint(uint64(e.off), 8); err != nil {
+			return err
+		}
+		if err := b.writeUint(uint64(e.len), 4); err != nil {
+			return err
+		}
+	}
+	dists := b.off
+	idx := make([]int, len(es))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		a, b := es[idx[i]], es[idx[j]]
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return idx[i] < idx[j]
+	})
+	for _, i := range idx {
+		if err := b.writeUint(uint64(i), 4); err != nil {
+			return err
+		}
+	}
+	if err := b.write([]byte(diskMagic)); err != nil {
+		return err
+	}
+	for _, v := range []int64{int64(len(es)), pkgs, dists} {
+		if err := b.writeUint(uint64(v), 8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	errDiskIndexFormat = errors.New("not a valid disk index")
+	errDiskIndexSize   = errors.New("too many packages for a disk index")
+)
@@ -0,0 +1,138 @@
+package pkgindex
+
+import (
+	"io"
+	"iter"
+	"sort"
+)
+
+// MemIndex is an Index held in memory. The zero value is not usable; use
+// NewMemIndex or Load.
+type MemIndex struct {
+	header   *Header
+	packages map[string]*Package
+	dists    map[string][]*Package
+	names    []string
+	sorted   bool
+}
+
+// NewMemIndex returns an empty MemIndex with the given header.
+func NewMemIndex(h *Header) *MemIndex {
+	if h == nil {
+		h = &Header{}
+	}
+	return &MemIndex{
+		header:   h,
+		packages: make(map[string]*Package),
+		dists:    make(map[string][]*Package),
+		sorted:   true,
+	}
+}
+
+// Load reads an index file from r into a MemIndex.
+func Load(r io.Reader) (*MemIndex, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rd.Close() }()
+	idx := NewMemIndex(rd.Header())
+	for p, err := range rd.All() {
+		if err != nil {
+			return nil, err
+		}
+		idx.Add(p)
+	}
+	return idx, nil
+}
+
+// Add adds p to the index, replacing any package with the same name.
+func (m *MemIndex) Add(p *Package) {
+	if old, ok := m.packages[p.Name]; ok {
+		m.removeFromDist(old)
+	} else {
+		m.names = append(m.names, p.Name)
+		m.sorted = false
+	}
+	m.packages[p.Name] = p
+	m.dists[p.Path] = append(m.dists[p.Path], p)
+}
+
+// Remove removes the named package, reporting whether it was there.
+func (m *MemIndex) Remove(name string) bool {
+	p, ok := m.packages[name]
+	if !ok {
+		return false
+	}
+	delete(m.packages, name)
+	m.removeFromDist(p)
+	m.sort()
+	i := sort.Search(len(m.names), func(i int) bool {
+		return compareNames(m.names[i], name) >= 0
+	})
+	m.names = append(m.names[:i], m.names[i+1:]...)
+	return true
+}
+
+func (m *MemIndex) Header() *Header {
+	return m.header
+}
+
+func (m *MemIndex) Len() int {
+	return len(m.packages)
+}
+
+func (m *MemIndex) Package(name string) (*Package, error) {
+	p, ok := m.packages[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *MemIndex) Distribution(path string) ([]*Package, error) {
+	ps := m.dists[path]
+	if len(ps) == 0 {
+		return nil, ErrNotFound
+	}
+	ps = append([]*Package(nil), ps...)
+	sort.Slice(ps, func(i, j int) bool {
+		return compareNames(ps[i].Name, ps[j].Name) < 0
+	})
+	return ps, nil
+}
+
+func (m *MemIndex) All() iter.Seq2[*Package, error] {
+	return func(yield func(*Package, error) bool) {
+		m.sort()
+		for _, name := range m.names {
+			if !yield(m.packages[name], nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *MemIndex) sort() {
+	if !m.sorted {
+		sort.Slice(m.names, func(i, j int) bool {
+			return compareNames(m.names[i], m.names[j]) < 0
+		})
+		m.sorted = true
+	}
+}
+
+func (m *MemIndex) removeFromDist(p *Package) {
+	ps := m.dists[p.Path]
+	for i, q := range ps {
+		if q == p {
+			ps = append(ps[:i], ps[i+1:]...)
+			break
+		}
+	}
+	if len(ps) == 0 {
+		delete(m.dists, p.Path)
+	} else {
+		m.dists[p.Path] = ps
+	}
+}
@@ -0,0 +1,163 @@
+// Package pkgindex reads, indexes and writes the PAUSE package index,
+// 02packages.details.txt, which maps every indexed package on CPAN to its
+// version and the distribution it's in.
+//
+// The file is a block of "Name: value" header fields, a blank line, and then
+// one line per package giving its name, version and the path of its
+// distribution under authors/id/. A Reader streams it, gzipped or not; a
+// MemIndex holds it in memory; a DiskIndex keeps it in a file and looks
+// packages up without loading it; and a Writer writes it back out in the
+// layout PAUSE uses.
+//
+//	f, err := os.Open("02packages.details.txt.gz")
+//	...
+//	idx, err := pkgindex.Load(f)
+//	...
+//	p, err := idx.Package("Moose")
+//	...
+//	fmt.Println(p.Version.Stringify(), p.Path)
+package pkgindex
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Package is a single line of the index.
+type Package struct {
+	// Name is the name of the package, such as "Moose::Role".
+	Name string
+
+	// Version is the version of the package. Packages without one have
+	// version.Undef().
+	Version version.Version
+
+	// Path is the path of the distribution the package is in, relative
+	// to authors/id/, such as "E/ET/ETHER/Moose-2.2207.tar.gz".
+	Path string
+}
+
+// Index is a package index that can be looked up.
+type Index interface {
+	// Header returns the header of the index.
+	Header() *Header
+
+	// Len returns the number of packages in the index.
+	Len() int
+
+	// Package returns the named package, or ErrNotFound.
+	Package(name string) (*Package, error)
+
+	// Distribution returns the packages in the distribution at path,
+	// sorted by name, or ErrNotFound if there are none.
+	Distribution(path string) ([]*Package, error)
+
+	// All iterates over every package, sorted by name the way PAUSE sorts
+	// them, which ignores case.
+	All() iter.Seq2[*Package, error]
+}
+
+// Header is the header of an index file: an ordered list of fields.
+type Header struct {
+	Fields []HeaderField
+}
+
+// HeaderField is a single "Name: value" header line.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// NewHeader returns the header PAUSE writes, with the given line count and
+// time of last update.
+func NewHeader(lineCount int, lastUpdated time.Time) *Header {
+	h := &Header{}
+	h.Set("File", "02packages.details.txt")
+	h.Set("URL", "http://www.perl.com/CPAN/modules/02packages.details.txt")
+	h.Set("Description", "Package names found in directory "+
+		"$CPAN/authors/id/")
+	h.Set("Columns", "package name, version, path")
+	h.Set("Intended-For", "Automated fetch routines, namespace "+
+		"documentation.")
+	h.Set("Written-By", "github.com/cmburn/perlutils/pkgindex")
+	h.Set("Line-Count", strconv.Itoa(lineCount))
+	h.Set("Last-Updated", lastUpdated.UTC().Format(time.RFC1123))
+	return h
+}
+
+// Get returns the value of the named field, or "" if there's no such
+// field. Names are matched case-insensitively.
+func (h *Header) Get(name string) string {
+	for _, f := range h.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set sets the value of the named field, adding it to the end if there's no
+// such field.
+func (h *Header) Set(name, value string) {
+	for i, f := range h.Fields {
+		if strings.EqualFold(f.Name, name) {
+			h.Fields[i].Value = value
+			return
+		}
+	}
+	h.Fields = append(h.Fields, HeaderField{name, value})
+}
+
+// LineCount returns the Line-Count field, which is the number of packages
+// in the index.
+func (h *Header) LineCount() (int, error) {
+	n, err := strconv.Atoi(h.Get("Line-Count"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid Line-Count: %w", err)
+	}
+	return n, nil
+}
+
+// LastUpdated returns the Last-Updated field.
+func (h *Header) LastUpdated() (time.Time, error) {
+	return time.Parse(time.RFC1123, h.Get("Last-Updated"))
+}
+
+// compareNames orders package names the way PAUSE does: ignoring case,
+// with names differing only in case ordered bytewise.
+func compareNames(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func (h *Header) clone() *Header {
+	return &Header{Fields: append([]HeaderField(nil), h.Fields...)}
+}
+
+// SyntaxError is returned when an index file can't be parsed.
+type SyntaxError struct {
+	// Line is the line number the error is on, starting at 1.
+	Line int
+
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+var (
+	// ErrNotFound is returned when a package or distribution isn't in an
+	// index.
+	ErrNotFound = errors.New("not found")
+)
@@ -0,0 +1,204 @@
+package pkgindex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const tDetails = `File:         02packages.details.txt
+URL:          http://www.perl.com/CPAN/modules/02packages.details.txt
+Description:  Package names found in directory $CPAN/authors/id/
+Columns:      package name, version, path
+Intended-For: Automated fetch routines, namespace documentation.
+Written-By:   PAUSE version 1.005
+Line-Count:   6
+Last-Updated: Sat, 17 Oct 2026 09:29:02 GMT
+
+Class::MOP                       2.2207  E/ET/ETHER/Moose-2.2207.tar.gz
+Class::MOP::Class::Immutable::Trait 2.2207  E/ET/ETHER/Moose-2.2207.tar.gz
+Moose                            2.2207  E/ET/ETHER/Moose-2.2207.tar.gz
+moose                             undef  Y/YA/YANICK/moose-1.0.tar.gz
+Moose::Role                      2.2207  E/ET/ETHER/Moose-2.2207.tar.gz
+Try::Tiny                          0.31  E/ET/ETHER/Try-Tiny-0.31.tar.gz
+`
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	idx, err := Load(strings.NewReader(tDetails))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tCheckIndex(t, idx)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(tDetails))
+	_ = zw.Close()
+	if idx, err = Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tCheckIndex(t, idx)
+	if !idx.Remove("Moose::Role") || idx.Remove("Moose::Role") {
+		t.Error("unexpected result from Remove")
+	}
+	idx.Add(&Package{Name: "Try::Tiny", Path: "E/ET/ETHER/x.tar.gz"})
+	ps, err := idx.Distribution("E/ET/ETHER/Moose-2.2207.tar.gz")
+	if err != nil || len(ps) != 3 {
+		t.Errorf("unexpected distribution %v, %v", ps, err)
+	}
+	_, err = idx.Distribution("E/ET/ETHER/Try-Tiny-0.31.tar.gz")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if idx.Len() != 5 {
+		t.Errorf("expected 5 packages, got %d", idx.Len())
+	}
+}
+
+func TestLoad_errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"", 0},
+		{"File 02packages.details.txt\n\n", 1},
+		{"File: x\n\nFoo 1.0\n", 3},
+		{"File: x\n\nFoo 1.0 F/FO/FOO/Foo.tar.gz extra\n", 3},
+		{"File: x\n\nFoo 1.0.0.x F/FO/FOO/Foo.tar.gz\n", 3},
+	}
+	for _, test := range tests {
+		_, err := Load(strings.NewReader(test.input))
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Line != test.line {
+			t.Errorf("%q: expected a SyntaxError on line %d, "+
+				"got %v", test.input, test.line, err)
+		}
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	t.Parallel()
+	idx, err := Load(strings.NewReader(tDetails))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = WriteIndex(&buf, idx); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != tDetails {
+		t.Errorf("expected\n%s\ngot\n%s", tDetails, buf.String())
+	}
+	h := NewHeader(0, time.Date(2026, 10, 17, 9, 29, 2, 0, time.UTC))
+	if n, err := h.LineCount(); err != nil || n != 0 {
+		t.Errorf("unexpected line count %d, %v", n, err)
+	}
+	if lu, err := h.LastUpdated(); err != nil || lu.Day() != 17 {
+		t.Errorf("unexpected last updated %v, %v", lu, err)
+	}
+	buf.Reset()
+	if err = WriteIndex(&buf, NewMemIndex(h)); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Len() != 0 || empty.Header().Get("written-by") !=
+		"github.com/cmburn/perlutils/pkgindex" {
+		t.Errorf("unexpected index %#v", empty)
+	}
+}
+
+func TestDiskIndex(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "packages.idx")
+	err := BuildDiskIndex(path, strings.NewReader(tDetails))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenDiskIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idx.Close() }()
+	tCheckIndex(t, idx)
+	var buf bytes.Buffer
+	if err = WriteIndex(&buf, idx); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != tDetails {
+		t.Errorf("expected\n%s\ngot\n%s", tDetails, buf.String())
+	}
+	// later lines replace earlier ones with the same name
+	err = BuildDiskIndex(path, strings.NewReader(tDetails+
+		"Moose 2.3 E/ET/ETHER/Moose-2.3.tar.gz\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx2, err := OpenDiskIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idx2.Close() }()
+	if p, err := idx2.Package("Moose"); err != nil ||
+		p.Version.Stringify() != "2.3" || idx2.Len() != 6 {
+		t.Errorf("unexpected package %#v, %v", p, err)
+	}
+	if _, err = OpenDiskIndex(filepath.Join(t.TempDir(), "x")); err == nil {
+		t.Error("expected an error opening a missing index")
+	}
+}
+
+func tCheckIndex(t *testing.T, idx Index) {
+	t.Helper()
+	if idx.Len() != 6 {
+		t.Errorf("expected 6 packages, got %d", idx.Len())
+	}
+	if n, err := idx.Header().LineCount(); err != nil || n != 6 {
+		t.Errorf("unexpected line count %d, %v", n, err)
+	}
+	p, err := idx.Package("Moose")
+	if err != nil || p.Version.Stringify() != "2.2207" ||
+		p.Path != "E/ET/ETHER/Moose-2.2207.tar.gz" {
+		t.Errorf("unexpected package %#v, %v", p, err)
+	}
+	p, err = idx.Package("moose")
+	if err != nil || p.Version.Raw() != "undef" {
+		t.Errorf("unexpected package %#v, %v", p, err)
+	}
+	if _, err = idx.Package("MOOSE"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	ps, err := idx.Distribution("E/ET/ETHER/Moose-2.2207.tar.gz")
+	var names []string
+	for _, p := range ps {
+		names = append(names, p.Name)
+	}
+	expected := "Class::MOP Class::MOP::Class::Immutable::Trait Moose " +
+		"Moose::Role"
+	if err != nil || strings.Join(names, " ") != expected {
+		t.Errorf("unexpected distribution %v, %v", names, err)
+	}
+	_, err = idx.Distribution("A/AB/ABC/None.tar.gz")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	names = names[:0]
+	for p, err := range idx.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, p.Name)
+	}
+	expected = "Class::MOP Class::MOP::Class::Immutable::Trait Moose " +
+		"moose Moose::Role Try::Tiny"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
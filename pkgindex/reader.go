@@ -0,0 +1,128 @@
+package pkgindex
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+
+	// local
+	"github.com/cmburn/perlutils/version"
+)
+
+// Reader streams an index file. Files compressed with gzip are decompressed
+// as they're read.
+type Reader struct {
+	sc     *bufio.Scanner
+	gz     *gzip.Reader
+	header *Header
+	line   int
+}
+
+// NewReader returns a Reader for r, having read the header.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	rd := &Reader{}
+	if magic, err := br.Peek(2); err == nil &&
+		bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if rd.gz, err = gzip.NewReader(br); err != nil {
+			return nil, err
+		}
+		rd.sc = bufio.NewScanner(rd.gz)
+	} else {
+		rd.sc = bufio.NewScanner(br)
+	}
+	if err := rd.readHeader(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// Header returns the header of the file.
+func (r *Reader) Header() *Header {
+	return r.header
+}
+
+// Next returns the next package, or io.EOF once there are no more.
+func (r *Reader) Next() (*Package, error) {
+	for r.sc.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.sc.Text())
+		if line == "" {
+			continue
+		}
+		return r.parsePackage(line)
+	}
+	if err := r.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// All iterates over the rest of the packages in the file, in the order
+// they're in. Iteration stops after the first error.
+func (r *Reader) All() iter.Seq2[*Package, error] {
+	return func(yield func(*Package, error) bool) {
+		for {
+			p, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(p, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close closes the gzip stream, if the file was compressed. It doesn't
+// close the underlying reader.
+func (r *Reader) Close() error {
+	if r.gz != nil {
+		return r.gz.Close()
+	}
+	return nil
+}
+
+func (r *Reader) readHeader() error {
+	r.header = &Header{}
+	for r.sc.Scan() {
+		r.line++
+		line := r.sc.Text()
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return &SyntaxError{r.line, "invalid header line"}
+		}
+		r.header.Fields = append(r.header.Fields, HeaderField{
+			Name:  name,
+			Value: strings.TrimSpace(value),
+		})
+	}
+	if err := r.sc.Err(); err != nil {
+		return err
+	}
+	if len(r.header.Fields) == 0 {
+		return &SyntaxError{r.line, "missing header"}
+	}
+	// a header with no packages after it
+	return nil
+}
+
+func (r *Reader) parsePackage(line string) (*Package, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return nil, &SyntaxError{r.line, "expected 3 columns, got " +
+			strconv.Itoa(len(fields))}
+	}
+	v, err := version.Parse(fields[1])
+	if err != nil {
+		return nil, &SyntaxError{r.line, err.Error()}
+	}
+	return &Package{Name: fields[0], Version: v, Path: fields[2]}, nil
+}
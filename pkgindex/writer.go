@@ -0,0 +1,72 @@
+package pkgindex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Writer writes an index file in the layout PAUSE uses.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a Writer for w, having written h. The packages written
+// after it should agree with its Line-Count field.
+func NewWriter(w io.Writer, h *Header) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	for _, f := range h.Fields {
+		if _, err := fmt.Fprintf(bw, "%-13s %s\n", f.Name+":",
+			f.Value); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.WriteByte('\n'); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// Write writes a package line. Package names are padded to 30 columns and
+// versions to 8, as PAUSE does, with long names eating into the version's
+// padding.
+func (w *Writer) Write(p *Package) error {
+	v := p.Version.Raw()
+	if v == "" {
+		v = "undef"
+	}
+	one, two := 30, 8
+	if len(p.Name) > one {
+		one += 8 - len(v)
+		two = len(v)
+	}
+	_, err := fmt.Fprintf(w.w, "%-"+strconv.Itoa(one)+"s %"+
+		strconv.Itoa(two)+"s  %s\n", p.Name, v, p.Path)
+	return err
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// WriteIndex writes every package in idx to w, with idx's header, updating
+// its Line-Count to match.
+func WriteIndex(w io.Writer, idx Index) error {
+	h := idx.Header().clone()
+	h.Set("Line-Count", strconv.Itoa(idx.Len()))
+	wr, err := NewWriter(w, h)
+	if err != nil {
+		return err
+	}
+	for p, err := range idx.All() {
+		if err != nil {
+			return err
+		}
+		if err = wr.Write(p); err != nil {
+			return err
+		}
+	}
+	return wr.Flush()
+}
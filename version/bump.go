@@ -0,0 +1,198 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parts of a Version, for Bump. In a decimal version, each part after
+// the first is three digits of the fraction, as version.pm reads it.
+const (
+	Major = iota
+	Minor
+	Patch
+)
+
+// Bump returns the next Version after v, adding one to the given part and
+// setting the parts after it to zero. The result is written the way v is:
+// "v1.2.3" bumps to "v1.3.0", and a decimal version is bumped in its last
+// digit within the part, so "1.09" bumps to "1.10" and "1.99" to "2.00". A
+// dev version is bumped as the release it's a dev version of, so "1.09_01"
+// bumps to "1.10", unless that doesn't sort after it. Then it's bumped
+// without its underscore, as version.pm reads it, so "v1.2.3_4", which is
+// "v1.2.34", bumps to "v1.2.35" in its last part.
+func (v *Version) Bump(part int) (Version, error) {
+	if part < 0 {
+		return Version{}, ErrInvalidPart
+	}
+	next, err := bump(v.release(), part)
+	if err != nil {
+		return Version{}, err
+	}
+	if next.Compare(v) <= 0 && v.alpha {
+		s := strings.ReplaceAll(v.original, "_", "")
+		if next, err = bump(s, part); err != nil {
+			return Version{}, err
+		}
+	}
+	if err = CheckNext(v, &next); err != nil {
+		return Version{}, err
+	}
+	return next, nil
+}
+
+// bump bumps a part of a version without a dev version, written either way.
+func bump(release string, part int) (Version, error) {
+	if strings.HasPrefix(release, "v") || strings.Count(release, ".") > 1 {
+		return Parse(bumpDotted(release, part))
+	}
+	return Parse(bumpDecimal(release, part))
+}
+
+// bumpDotted bumps a part of a dotted-decimal version, keeping as many
+// parts as it has.
+func bumpDotted(release string, part int) string {
+	prefix := ""
+	if strings.HasPrefix(release, "v") {
+		prefix = "v"
+	}
+	s := strings.TrimSuffix(strings.TrimPrefix(release, "v"), ".")
+	parts := strings.Split(s, ".")
+	for len(parts) <= part {
+		parts = append(parts, "0")
+	}
+	for i := part + 1; i < len(parts); i++ {
+		parts[i] = "0"
+	}
+	// Parse checks for overflow, so the error doesn't matter here
+	n, _ := strconv.ParseInt(parts[part], 10, 64)
+	parts[part] = strconv.FormatInt(n+1, 10)
+	return prefix + strings.Join(parts, ".")
+}
+
+// bumpDecimal bumps a part of a decimal version by adding one to its last
+// digit, carrying into the digits before it, and setting the digits after
+// it to zero. The fraction keeps at least as many digits as it has.
+func bumpDecimal(release string, part int) string {
+	integer, fraction, dotted := strings.Cut(release, ".")
+	if integer == "" {
+		integer = "0"
+	}
+	end := 0
+	if part > 0 {
+		end = min(len(fraction), part*3)
+		if end <= (part-1)*3 {
+			end = part * 3
+		}
+	}
+	width := max(len(fraction), end)
+	fraction += strings.Repeat("0", width-len(fraction))
+	digits := []byte(integer + fraction[:end])
+	i := len(digits) - 1
+	for ; i >= 0 && digits[i] == '9'; i-- {
+		digits[i] = '0'
+	}
+	if i < 0 {
+		digits = append([]byte{'1'}, digits...)
+	} else {
+		digits[i]++
+	}
+	integer = string(digits[:len(digits)-end])
+	fraction = string(digits[len(digits)-end:]) +
+		strings.Repeat("0", width-end)
+	if !dotted && width == 0 {
+		return integer
+	}
+	return integer + "." + fraction
+}
+
+// Dev returns the number after the underscore of a dev version, so that of
+// "1.09_01" is 1. It returns false if v isn't a dev version, or has nothing
+// after its underscore.
+func (v *Version) Dev() (int, bool) {
+	_, dev, ok := strings.Cut(v.original, "_")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(dev)
+	return n, err == nil
+}
+
+// WithDev returns v as the dev version dev, with at least two digits: for
+// "1.09", WithDev(1) is "1.09_01". Any dev version v already is is replaced.
+// The result has to be a version, so a decimal version without a fraction,
+// like "1", can't have one.
+func (v *Version) WithDev(dev int) (Version, error) {
+	if dev < 0 {
+		return Version{}, ErrInvalidPart
+	}
+	return Parse(fmt.Sprintf("%s_%02d", v.release(), dev))
+}
+
+// StripDev returns the release v is a dev version of, so that of "1.09_01"
+// is "1.09". The release sorts before the dev version. If v isn't a dev
+// version, it's returned as it is.
+func (v *Version) StripDev() (Version, error) {
+	if !v.alpha {
+		return *v, nil
+	}
+	return Parse(v.release())
+}
+
+// release returns the original representation of v without any dev
+// version. An undefined Version is "0".
+func (v *Version) release() string {
+	s, _, _ := strings.Cut(v.Stringify(), "_")
+	return s
+}
+
+// ToDotted returns v as a dotted-decimal Version that's Equal to it, as
+// Normal writes it: "1.09" is "v1.90.0". A dotted-decimal v is returned as
+// it is.
+func (v *Version) ToDotted() (Version, error) {
+	if v.qv {
+		return *v, nil
+	}
+	return Parse(v.Normal())
+}
+
+// ToDecimal returns v as a decimal Version that's Equal to it, as
+// NumifyString writes it: "v1.2.3" is "1.002003". A decimal v is returned
+// as it is. Only three digits of the fraction go to each part, so if a part
+// after the first is over 999, the error is ErrNotDecimal.
+func (v *Version) ToDecimal() (Version, error) {
+	if !v.qv {
+		return *v, nil
+	}
+	for _, n := range v.version[1:] {
+		if n > 999 {
+			return Version{}, ErrNotDecimal
+		}
+	}
+	return Parse(v.NumifyString())
+}
+
+// CheckNext returns an error wrapping ErrNotGreater unless next sorts after
+// previous, as a new release of a dist must.
+func CheckNext(previous, next *Version) error {
+	if next.Compare(previous) > 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not greater than %s", ErrNotGreater,
+		next.Stringify(), previous.Stringify())
+}
+
+var (
+	// ErrInvalidPart is the error for a negative part or dev version.
+	ErrInvalidPart = errors.New("invalid version part")
+
+	// ErrNotDecimal is the error for a Version that can't be written as a
+	// decimal version.
+	ErrNotDecimal = errors.New("version can't be written as a decimal")
+
+	// ErrNotGreater is the error for a version that doesn't sort after the
+	// one before it.
+	ErrNotGreater = errors.New("version is not greater")
+)
@@ -0,0 +1,298 @@
+package version
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyParts is the most parts a Key can hold, not counting zeros at the end.
+const keyParts = 16
+
+// Key is a comparable form of a Version, for use as a map key or with ==.
+// Two Keys are equal exactly when their Versions are Equal, so "v1.2" and
+// "v1.2.0" have the same Key, and whether a Version is alpha doesn't matter.
+type Key struct {
+	parts [keyParts]uint32
+}
+
+// Key returns the Key of v. It returns false if v has more than 16 parts,
+// not counting zeros at the end, as a Key can't hold them.
+func (v *Version) Key() (Key, bool) {
+	var k Key
+	parts := significant(v.version)
+	if len(parts) > keyParts {
+		return k, false
+	}
+	for i, n := range parts {
+		k.parts[i] = uint32(n)
+	}
+	return k, true
+}
+
+// Compare compares two Keys as Version.Compare compares their Versions.
+func (k Key) Compare(other Key) int {
+	for i := range k.parts {
+		switch {
+		case k.parts[i] < other.parts[i]:
+			return -1
+		case k.parts[i] > other.parts[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Bytes returns k in an encoding that sorts as Keys do: bytes.Compare of
+// two Keys' Bytes is the same as their Compare.
+func (k Key) Bytes() []byte {
+	parts := make([]int64, keyParts)
+	for i, n := range k.parts {
+		parts[i] = int64(n)
+	}
+	return appendOrdered(nil, significant(parts))
+}
+
+// String returns k as a dotted-decimal version with at least three parts,
+// as Normal does, so the Key of "1.2" is "v1.200.0".
+func (k Key) String() string {
+	n := keyParts
+	for n > 3 && k.parts[n-1] == 0 {
+		n--
+	}
+	sb := strings.Builder{}
+	for i, part := range k.parts[:n] {
+		if i == 0 {
+			sb.WriteByte('v')
+		} else {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(part), 10))
+	}
+	return sb.String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, returning
+// the original representation of the Version.
+func (v *Version) MarshalText() ([]byte, error) {
+	return []byte(v.original), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, parsing
+// the Version as Parse does.
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The
+// encoding sorts as the Versions do: where Compare says two Versions
+// differ, bytes.Compare of their encodings says the same. Equal Versions
+// written differently, like "1.2" and "1.200", are ordered by how they're
+// written. UnmarshalBinary decodes it exactly.
+func (v *Version) MarshalBinary() ([]byte, error) {
+	b := appendOrdered(nil, significant(v.version))
+	b = append(b, 0)
+	var flags byte
+	if v.alpha {
+		flags |= binaryAlpha
+	}
+	if v.qv {
+		flags |= binaryQv
+	}
+	b = append(b, flags)
+	b = binary.AppendUvarint(b, uint64(len(v.version)))
+	return append(b, v.original...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding a Version encoded by MarshalBinary.
+func (v *Version) UnmarshalBinary(data []byte) error {
+	var parts []int64
+	for {
+		if len(data) == 0 {
+			return ErrInvalidBinary
+		}
+		marker := data[0]
+		data = data[1:]
+		if marker == 0 {
+			break
+		}
+		size := int(marker - 1)
+		if size > 8 || size > len(data) {
+			return ErrInvalidBinary
+		}
+		var n uint64
+		for _, c := range data[:size] {
+			n = n<<8 | uint64(c)
+		}
+		parts = append(parts, int64(n))
+		data = data[size:]
+	}
+	if len(data) == 0 {
+		return ErrInvalidBinary
+	}
+	flags := data[0]
+	count, size := binary.Uvarint(data[1:])
+	// a Version never has more parts than characters, plus the zeros a
+	// dotted-decimal one is padded with
+	if size <= 0 || count < uint64(len(parts)) ||
+		count > uint64(len(data))+3 {
+		return ErrInvalidBinary
+	}
+	decoded := Version{
+		original: string(data[1+size:]),
+		alpha:    flags&binaryAlpha != 0,
+		qv:       flags&binaryQv != 0,
+	}
+	if count > 0 {
+		decoded.version = make([]int64, count)
+		copy(decoded.version, parts)
+	}
+	*v = decoded
+	return nil
+}
+
+// Scan implements the sql.Scanner interface. It reads a Version stored by
+// Value, or stored as text, so that existing columns of text can still be
+// read.
+func (v *Version) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		// the encoding starts with a byte below any printable one
+		if len(src) > 0 && src[0] < '\t' {
+			return v.UnmarshalBinary(src)
+		}
+		return v.UnmarshalText(src)
+	case string:
+		return v.UnmarshalText([]byte(src))
+	default:
+		return fmt.Errorf("can't scan %T into a version", src)
+	}
+}
+
+// Value implements the driver.Valuer interface, storing the Version as
+// MarshalBinary encodes it, so that a database sorts Versions properly in
+// a column of bytes, such as a bytea in PostgreSQL. It has a value receiver
+// so that a Version can be passed to a query as it is.
+func (v Version) Value() (driver.Value, error) {
+	return v.MarshalBinary()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, returning
+// the Range as String does.
+func (r *Range) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, parsing
+// the Range as ParseRange does. Empty text is an empty Range, which every
+// version is in.
+func (r *Range) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = Range{}
+		return nil
+	}
+	parsed, err := ParseRange(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. A Range
+// has no order, so it's encoded as its text.
+func (r *Range) MarshalBinary() ([]byte, error) {
+	return r.MarshalText()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding a Range encoded by MarshalBinary.
+func (r *Range) UnmarshalBinary(data []byte) error {
+	return r.UnmarshalText(data)
+}
+
+// Scan implements the sql.Scanner interface, reading a Range stored as
+// text.
+func (r *Range) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return r.UnmarshalText(src)
+	case string:
+		return r.UnmarshalText([]byte(src))
+	default:
+		return fmt.Errorf("can't scan %T into a version range", src)
+	}
+}
+
+// Value implements the driver.Valuer interface, storing the Range as text.
+// It has a value receiver so that a Range can be passed to a query as it
+// is.
+func (r Range) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// significant returns parts without the zeros at the end, which don't
+// change how a Version compares.
+func significant(parts []int64) []int64 {
+	n := len(parts)
+	for n > 0 && parts[n-1] == 0 {
+		n--
+	}
+	return parts[:n]
+}
+
+// appendOrdered appends parts to b so that the result sorts as the parts
+// do. Each part is a byte giving its length in bytes, plus one, then its
+// bytes, most significant first and without leading zeros. A longer part
+// is larger, so the length comes first. Parts are never negative.
+func appendOrdered(b []byte, parts []int64) []byte {
+	var buf [8]byte
+	for _, n := range parts {
+		binary.BigEndian.PutUint64(buf[:], uint64(n))
+		size := 8
+		for size > 0 && buf[8-size] == 0 {
+			size--
+		}
+		b = append(b, byte(size+1))
+		b = append(b, buf[8-size:]...)
+	}
+	return b
+}
+
+// The flags in a Version's binary encoding.
+const (
+	binaryAlpha byte = 1 << iota
+	binaryQv
+)
+
+var (
+	_ encoding.TextMarshaler     = (*Version)(nil)
+	_ encoding.TextUnmarshaler   = (*Version)(nil)
+	_ encoding.BinaryMarshaler   = (*Version)(nil)
+	_ encoding.BinaryUnmarshaler = (*Version)(nil)
+	_ sql.Scanner                = (*Version)(nil)
+	_ driver.Valuer              = Version{}
+	_ encoding.TextMarshaler     = (*Range)(nil)
+	_ encoding.TextUnmarshaler   = (*Range)(nil)
+	_ encoding.BinaryMarshaler   = (*Range)(nil)
+	_ encoding.BinaryUnmarshaler = (*Range)(nil)
+	_ sql.Scanner                = (*Range)(nil)
+	_ driver.Valuer              = Range{}
+)
+
+var (
+	// ErrInvalidBinary is the error for data that isn't a Version encoded
+	// by MarshalBinary.
+	ErrInvalidBinary = errors.New("invalid binary version")
+)
@@ -0,0 +1,300 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// These match the whole of a string, as version::is_lax and
+// version::is_strict do, where laxRegexp and strictRegexp only match the
+// end of one.
+var (
+	isLaxRegexp    = regexp.MustCompile(`^` + LaxVersionRegex)
+	isStrictRegexp = regexp.MustCompile(`^` + StrictVersionRegex)
+)
+
+// FormatError is the error for a string that isn't a valid version. Reason
+// is why, in version.pm's words, such as "non-numeric data".
+type FormatError struct {
+	// Version is the string that isn't valid.
+	Version string
+
+	// Reason says what's wrong with it.
+	Reason string
+}
+
+// Error returns the message version.pm dies with, as in "Invalid version
+// format (non-numeric data)".
+func (e *FormatError) Error() string {
+	return "Invalid version format (" + e.Reason + ")"
+}
+
+// IsLax reports whether version is a version under version.pm's lax rules,
+// as version::is_lax does. If it isn't, the error is a *FormatError giving
+// the reason.
+func IsLax(version string) (bool, error) {
+	if isLaxRegexp.MatchString(version) {
+		return true, nil
+	}
+	return false, notVersion(version, false)
+}
+
+// IsStrict reports whether version is a version under version.pm's strict
+// rules, as version::is_strict does. If it isn't, the error is a
+// *FormatError giving the reason.
+func IsStrict(version string) (bool, error) {
+	if isStrictRegexp.MatchString(version) {
+		return true, nil
+	}
+	return false, notVersion(version, true)
+}
+
+// notVersion returns the error for a version the regular expressions don't
+// match. The reason is the one version.pm's parser gives, except for the
+// few strings the parser accepts but the regular expressions don't, like
+// "1.2.3_".
+func notVersion(version string, strict bool) error {
+	p, err := prescan(version, strict, false)
+	if err != nil {
+		return err
+	}
+	reason := reasonNonNumeric
+	if p.end == len(version) && version[len(version)-1] == '_' {
+		reason = reasonMisplacedUnderscore
+	}
+	return &FormatError{Version: version, Reason: reason}
+}
+
+// prescanner is a port of prescan_version from version.pm's vutil.c. It
+// checks that a string starts with a version, without parsing it, and
+// finds where the version ends.
+type prescanner struct {
+	s      string
+	strict bool
+	d      int
+
+	// end is the offset of the end of the version.
+	end int
+
+	// decimals is the number of decimal points in the version.
+	decimals int
+
+	alpha bool
+}
+
+// prescan checks the version at the start of s, as version.pm does before
+// parsing one. If qv is true, as it is for Declare, a version that starts
+// with a digit is taken to be dotted-decimal.
+func prescan(s string, strict, qv bool) (prescanner, error) {
+	p := prescanner{s: s, strict: strict}
+	var err error
+	switch {
+	case qv && isDigit(p.at(0)):
+		err = p.dotted()
+	case p.at(0) == 'v':
+		p.d++
+		if !isDigit(p.at(p.d)) {
+			return p, p.fail(reasonThreeParts)
+		}
+		err = p.dotted()
+	default:
+		var restart bool
+		restart, err = p.decimal()
+		if restart {
+			p.d = 0
+			err = p.dotted()
+		}
+	}
+	if err != nil {
+		return p, err
+	}
+	return p, p.finish()
+}
+
+func (p *prescanner) at(i int) byte {
+	if i < 0 || i >= len(p.s) {
+		return 0
+	}
+	return p.s[i]
+}
+
+func (p *prescanner) digits() int {
+	start := p.d
+	for isDigit(p.at(p.d)) {
+		p.d++
+	}
+	return p.d - start
+}
+
+func (p *prescanner) dotted() error {
+	if p.strict && p.at(p.d) == '0' && isDigit(p.at(p.d+1)) {
+		return p.fail(reasonLeadingZeros)
+	}
+	p.digits()
+	if p.at(p.d) != '.' {
+		if p.strict {
+			return p.fail(reasonThreeParts)
+		}
+		return nil
+	}
+	p.decimals++
+	p.d++
+	parts := 0
+	for isDigit(p.at(p.d)) {
+		parts++
+		if p.digits() > 3 && p.strict {
+			return p.fail(reasonMaxDigits)
+		}
+		switch p.at(p.d) {
+		case '_':
+			if p.strict {
+				return p.fail(reasonNoUnderscores)
+			}
+			if p.alpha {
+				return p.fail(reasonMultipleUnderscores)
+			}
+			p.d++
+			p.alpha = true
+		case '.':
+			if p.alpha {
+				return p.fail(reasonAlphaBeforeDecimal)
+			}
+			p.decimals++
+			p.d++
+		}
+	}
+	if p.strict && parts < 2 {
+		return p.fail(reasonThreeParts)
+	}
+	return nil
+}
+
+// decimal checks a decimal version. It returns true if the version turns
+// out to be dotted-decimal without a leading "v", as in "1.2.3", which
+// must be checked again from the start.
+func (p *prescanner) decimal() (bool, error) {
+	if p.strict {
+		if p.at(p.d) == '.' {
+			return false, p.fail(reasonZeroBeforeDecimal)
+		}
+		if p.at(p.d) == '0' && isDigit(p.at(p.d+1)) {
+			return false, p.fail(reasonLeadingZeros)
+		}
+	}
+	if p.at(p.d) == '-' {
+		return false, p.fail(reasonNegative)
+	}
+	p.digits()
+	switch c := p.at(p.d); {
+	case c == '.':
+		p.decimals++
+		p.d++
+	case isVersionEnd(c):
+		if p.d == 0 {
+			return false, p.fail(reasonVersionRequired)
+		}
+		return false, nil
+	case p.d == 0:
+		return false, p.fail(reasonNonNumeric)
+	case c == '_':
+		if p.strict {
+			return false, p.fail(reasonNoUnderscores)
+		}
+		if isDigit(p.at(p.d + 1)) {
+			return false, p.fail(reasonAlphaWithoutDecimal)
+		}
+		return false, p.fail(reasonMisplacedUnderscore)
+	default:
+		return false, p.fail(reasonNonNumeric)
+	}
+	if c := p.at(p.d); !isDigit(c) && (p.strict || !isVersionEnd(c)) {
+		return false, p.fail(reasonFractionRequired)
+	}
+	for isDigit(p.at(p.d)) {
+		p.d++
+		switch p.at(p.d) {
+		case '.':
+			if p.alpha {
+				return false, p.fail(reasonAlphaBeforeDecimal)
+			}
+			if p.strict {
+				return false, p.fail(reasonDottedWithoutV)
+			}
+			return true, nil
+		case '_':
+			if p.strict {
+				return false, p.fail(reasonNoUnderscores)
+			}
+			if p.alpha {
+				return false, p.fail(reasonMultipleUnderscores)
+			}
+			if !isDigit(p.at(p.d + 1)) {
+				return false, p.fail(reasonMisplacedUnderscore)
+			}
+			p.d++
+			p.alpha = true
+		}
+	}
+	return false, nil
+}
+
+func (p *prescanner) finish() error {
+	p.end = p.d
+	for isSpace(p.at(p.d)) {
+		p.d++
+	}
+	if c := p.at(p.d); !isDigit(c) && !isVersionEnd(c) {
+		return p.fail(reasonNonNumeric)
+	}
+	if p.decimals > 1 && p.at(p.d-1) == '.' {
+		return p.fail(reasonTrailingDecimal)
+	}
+	return nil
+}
+
+func (p *prescanner) fail(reason string) error {
+	return &FormatError{Version: p.s, Reason: reason}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' ||
+		c == '\v'
+}
+
+// isVersionEnd reports whether c can follow a version, as in "1.2;" or
+// "1.2 {". A zero byte is the end of the string.
+func isVersionEnd(c byte) bool {
+	return c == 0 || c == ';' || c == '{' || c == '}' || isSpace(c)
+}
+
+// contentError returns the error for a version followed by something else,
+// as in "1.2 3", which version.pm warns about and ignores.
+func contentError(version string, end int) error {
+	return fmt.Errorf("Version string '%s' contains invalid data; "+
+		"ignoring: '%s'", version, version[end:])
+}
+
+// The reasons version.pm gives for a string not being a version.
+const (
+	reasonAlphaWithoutDecimal = "alpha without decimal"
+	reasonDottedWithoutV      = "dotted-decimal versions must " +
+		"begin with 'v'"
+	reasonFractionRequired    = "fractional part required"
+	reasonLeadingZeros        = "no leading zeros"
+	reasonMaxDigits           = "maximum 3 digits between decimals"
+	reasonMisplacedUnderscore = "misplaced underscore"
+	reasonMultipleUnderscores = "multiple underscores"
+	reasonNegative            = "negative version number"
+	reasonNoUnderscores       = "no underscores"
+	reasonNonNumeric          = "non-numeric data"
+	reasonThreeParts          = "dotted-decimal versions require " +
+		"at least three parts"
+	reasonTrailingDecimal    = "trailing decimal"
+	reasonAlphaBeforeDecimal = "underscores before decimal"
+	reasonVersionRequired    = "version required"
+	reasonZeroBeforeDecimal  = "0 before decimal required"
+)
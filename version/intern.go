@@ -0,0 +1,48 @@
+package version
+
+import "sync"
+
+// Interner caches Versions by the strings they're parsed from, so that
+// parsing a string again with its Parse doesn't allocate. Only its own Parse
+// goes through it; the package's Parse and the JSON types never do. The
+// Versions it returns share their integers, which nothing in this package
+// changes. It's safe for concurrent use.
+type Interner struct {
+	mu       sync.RWMutex
+	versions map[string]Version
+	size     int
+}
+
+// NewInterner returns an Interner that caches up to size Versions. Once
+// it's full, strings it hasn't seen are parsed without being cached.
+func NewInterner(size int) *Interner {
+	return &Interner{versions: make(map[string]Version), size: size}
+}
+
+// Parse is like the package's Parse, but returns the cached Version if
+// version has been parsed before. Errors aren't cached.
+func (in *Interner) Parse(version string) (Version, error) {
+	in.mu.RLock()
+	v, ok := in.versions[version]
+	in.mu.RUnlock()
+	if ok {
+		return v, nil
+	}
+	v, err := parse(version)
+	if err != nil {
+		return v, err
+	}
+	in.mu.Lock()
+	if len(in.versions) < in.size {
+		in.versions[version] = v
+	}
+	in.mu.Unlock()
+	return v, nil
+}
+
+// Len returns the number of Versions cached.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.versions)
+}
@@ -51,7 +51,7 @@ func (d laxDotted) toPerlVersionA(original string) VersionData {
 	if sb.Len() > 0 {
 		minors = dottedToMinors(sb.String())
 	}
-	numValues := len(minors)
+	numValues := len(minors) + 1
 	if numValues < minValues {
 		// implied zeroes in v-qualified lax Version
 		numValues = minValues
@@ -96,7 +96,7 @@ func (d laxDotted) toPerlVersionB(original string) VersionData {
 	return VersionData{
 		Original: original,
 		Alpha:    d.secondAlpha != "",
-		QV:       numValues == minValues,
+		QV:       numValues >= minValues,
 		Version:  values,
 	}
 }
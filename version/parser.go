@@ -0,0 +1,124 @@
+package version
+
+import "strings"
+
+// parse does the work of Parse. It checks the version with prescan,
+// then reads it in a single pass, allocating nothing but the Version's
+// integers.
+func parse(version string) (Version, error) {
+	version = strings.TrimSpace(version)
+	switch version {
+	case "undef":
+		return Undef(), nil
+	case ".":
+		return Version{original: version, version: []int64{0}}, nil
+	}
+	p, err := prescan(version, false, false)
+	if err != nil {
+		return Version{}, err
+	}
+	s := version[:p.end]
+	var v Version
+	var ok bool
+	if s[0] == 'v' || p.decimals > 1 {
+		v, ok = scanDotted(s)
+	} else {
+		v, ok = scanDecimal(s)
+	}
+	if !ok {
+		return Version{}, ErrIntegerOverflow
+	}
+	if p.end != len(version) {
+		return Version{}, contentError(version, p.end)
+	}
+	return v, nil
+}
+
+// scanDotted reads a dotted-decimal version that prescan has checked. Each
+// integer between the decimal points is a part of the version, ignoring any
+// underscore, and there are at least three of them. It returns false if an
+// integer overflows.
+func scanDotted(s string) (Version, bool) {
+	var buf [8]int64
+	parts := buf[:0]
+	v := Version{original: s, qv: true}
+	i := 0
+	if s[0] == 'v' {
+		i++
+	}
+	for i <= len(s) {
+		var n int64
+		digits := 0
+		for ; i < len(s) && s[i] != '.'; i++ {
+			if s[i] == '_' {
+				v.alpha = true
+				continue
+			}
+			n = n*10 + int64(s[i]-'0')
+			digits++
+			if n > maxVersion {
+				return Version{}, false
+			}
+		}
+		if len(parts) == 0 && digits > 10 {
+			return Version{}, false
+		}
+		// a missing integer is a zero, as in ".1.2", unless it's
+		// after a decimal point at the end, as in "v1."
+		if digits > 0 || i < len(s) {
+			parts = append(parts, n)
+		}
+		i++
+	}
+	v.version = make([]int64, max(len(parts), 3))
+	copy(v.version, parts)
+	return v, true
+}
+
+// scanDecimal reads a decimal version that prescan has checked. Its integer
+// part is the first part of the version, then each three digits of its
+// fraction, ignoring any underscore, are another. It returns false if the
+// integer part overflows.
+func scanDecimal(s string) (Version, bool) {
+	var buf [8]int64
+	v := Version{original: s}
+	i := 0
+	var n int64
+	for ; i < len(s) && s[i] != '.'; i++ {
+		n = n*10 + int64(s[i]-'0')
+		if n > maxVersion || i >= 10 {
+			return Version{}, false
+		}
+	}
+	parts := append(buf[:0], n)
+	if i < len(s) {
+		var group int64
+		width := 0
+		for i++; i < len(s); i++ {
+			if s[i] == '_' {
+				v.alpha = true
+				continue
+			}
+			group = group*10 + int64(s[i]-'0')
+			width++
+			if width == 3 {
+				parts = append(parts, group)
+				group, width = 0, 0
+			}
+		}
+		switch {
+		case width > 0:
+			// the last group is padded with zeros: ".1" is ".100"
+			for ; width < 3; width++ {
+				group *= 10
+			}
+			parts = append(parts, group)
+		case len(parts) == 1:
+			// "1." has a fraction of zero
+			parts = append(parts, 0)
+		}
+	}
+	v.version = make([]int64, len(parts))
+	copy(v.version, parts)
+	return v, true
+}
@@ -2,6 +2,7 @@ package version
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 )
 
@@ -28,11 +29,9 @@ func (r *Range) UnmarshalJSON(b []byte) error {
 	return json.Unmarshal(b, &r.conditions)
 }
 
+// String returns r as CPAN::Meta::Spec writes it. A bare version is a
+// minimum there, so a single "==" condition keeps its operator.
 func (r *Range) String() string {
-	if len(r.conditions) == 1 &&
-		r.conditions[0].Condition == RangeConditionEqual {
-		return r.conditions[0].Version.Raw()
-	}
 	sb := strings.Builder{}
 	for i, rc := range r.conditions {
 		if i > 0 {
@@ -71,8 +70,8 @@ func NewRange(conditions []RangeSpecifier) (*Range, error) {
 		}
 	}
 	r := &Range{}
-	r.conditions = make([]RangeSpecifier, 0, len(conditions))
-	copy(r.conditions, conditions)
+	r.conditions = append(make([]RangeSpecifier, 0, len(conditions)),
+		conditions...)
 	return r, nil
 }
 
@@ -86,23 +85,14 @@ func NewRangeNoFail(conditions []RangeSpecifier) *Range {
 }
 
 // ParseRange parses a string into a Range, in accordance with
-// CPAN::Meta::Spec.
+// CPAN::Meta::Spec: a comma separated list of clauses, each a version with
+// an optional "==", "!=", ">=", "<=", ">" or "<" before it. A bare version
+// is a minimum, as if it had ">=" before it, so "0" is any version.
+// Whitespace around each clause, and a comma at the end, are allowed. If
+// the string can't be parsed, the error is a *RangeSyntaxError.
 func ParseRange(s string) (*Range, error) {
-	// leading spaces, trailing spaces, and a comma at the end are all
-	// allowed
-	s = strings.TrimSpace(s)
-	s = strings.TrimSuffix(s, ",")
-	s = strings.TrimSpace(s) // catch any space before the comma
-	condStrings := strings.Split(s, ",")
-	r := &Range{}
-	for _, condString := range condStrings {
-		rc := RangeSpecifier{}
-		if err := parseCondition(&rc, condString); err != nil {
-			return nil, err
-		}
-		r.conditions = append(r.conditions, rc)
-	}
-	return r, nil
+	p := rangeParser{input: s}
+	return p.parse()
 }
 
 // MustParseRange is like ParseRange, but panics if there's an error
@@ -113,3 +103,141 @@ func MustParseRange(s string) *Range {
 	}
 	return r
 }
+
+// RangeSyntaxError is the error ParseRange returns for a string it can't
+// parse.
+type RangeSyntaxError struct {
+	// Input is the string being parsed.
+	Input string
+
+	// Clause is the index of the clause with the error, counting from 0.
+	Clause int
+
+	// Offset is the offset in bytes of the error in Input.
+	Offset int
+
+	// Msg describes the error.
+	Msg string
+
+	// Err is the error from parsing the clause's version, if that's what
+	// the error is.
+	Err error
+}
+
+func (e *RangeSyntaxError) Error() string {
+	return fmt.Sprintf("invalid version range %q: clause %d, offset %d: %s",
+		e.Input, e.Clause, e.Offset, e.Msg)
+}
+
+func (e *RangeSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+type rangeParser struct {
+	input  string
+	pos    int
+	clause int
+}
+
+func (p *rangeParser) parse() (*Range, error) {
+	r := &Range{}
+	for {
+		rc, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		r.conditions = append(r.conditions, rc)
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			return r, nil
+		}
+		if p.input[p.pos] != ',' {
+			return nil, p.errorf(nil, "unexpected %q after version",
+				p.token())
+		}
+		p.pos++
+		p.clause++
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			return r, nil
+		}
+	}
+}
+
+// condition parses a clause.
+func (p *rangeParser) condition() (RangeSpecifier, error) {
+	rc := RangeSpecifier{Condition: rangeConditionNone}
+	p.skipSpace()
+	for _, op := range rangeOperators {
+		if strings.HasPrefix(p.input[p.pos:], op.symbol) {
+			rc.Condition = op.condition
+			p.pos += len(op.symbol)
+			break
+		}
+	}
+	p.skipSpace()
+	start := p.pos
+	p.pos += len(p.token())
+	if start == p.pos {
+		if rc.Condition == rangeConditionNone {
+			return rc, p.errorf(nil, "empty clause")
+		}
+		return rc, p.errorf(nil, "missing version")
+	}
+	tok := p.input[start:p.pos]
+	v, err := Parse(tok)
+	if err != nil {
+		p.pos = start
+		return rc, p.errorf(err, "invalid version %q", tok)
+	}
+	rc.Version = v
+	return rc, nil
+}
+
+// token returns the input from the current position up to the next comma
+// or whitespace.
+func (p *rangeParser) token() string {
+	end := p.pos
+	for end < len(p.input) && p.input[end] != ',' &&
+		!isRangeSpace(p.input[end]) {
+		end++
+	}
+	return p.input[p.pos:end]
+}
+
+func (p *rangeParser) skipSpace() {
+	for p.pos < len(p.input) && isRangeSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *rangeParser) errorf(err error, format string,
+	args ...interface{}) error {
+	return &RangeSyntaxError{
+		Input:  p.input,
+		Clause: p.clause,
+		Offset: p.pos,
+		Msg:    fmt.Sprintf(format, args...),
+		Err:    err,
+	}
+}
+
+func isRangeSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// rangeOperators are the operators a clause can start with, longest first,
+// so that ">=" isn't taken for ">". "=" isn't in CPAN::Meta::Spec, but is
+// accepted for "==".
+var rangeOperators = []struct {
+	symbol    string
+	condition RangeCondition
+}{
+	{"==", RangeConditionEqual},
+	{"!=", RangeConditionNotEqual},
+	{">=", RangeConditionGreaterThanOrEqual},
+	{"<=", RangeConditionLessThanOrEqual},
+	{">", RangeConditionGreaterThan},
+	{"<", RangeConditionLessThan},
+	{"=", RangeConditionEqual},
+}
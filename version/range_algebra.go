@@ -0,0 +1,301 @@
+package version
+
+import "sort"
+
+// Intersect returns a Range of the versions in both r and other.
+func (r *Range) Intersect(other *Range) *Range {
+	conditions := make([]RangeSpecifier, 0,
+		len(r.conditions)+len(other.conditions))
+	conditions = append(conditions, r.conditions...)
+	conditions = append(conditions, other.conditions...)
+	return &Range{conditions: conditions}
+}
+
+// Union returns a Range of the versions in either r or other. A Range can
+// only hold one span of versions, so it returns false if there's a gap
+// between them, as with "< 1" and "> 2". A gap of a single version is fine,
+// as it can be excluded with "!=".
+func (r *Range) Union(other *Range) (*Range, bool) {
+	a, b := r.normalize(), other.normalize()
+	switch {
+	case a.empty():
+		return b.toRange(), true
+	case b.empty():
+		return a.toRange(), true
+	case a.before(&b) || b.before(&a):
+		return nil, false
+	}
+	hull := span{lower: looser(a.lower, b.lower, 1),
+		upper: looser(a.upper, b.upper, -1)}
+	// within the hull, the only versions missing from the union are the
+	// ones excluded from both, and the one between them if they meet at
+	// exclusive bounds
+	var missing []RangeSpecifier
+	for _, s := range []*span{&a, &b} {
+		missing = append(missing, s.excluded...)
+		for _, bound := range []*RangeSpecifier{s.lower, s.upper} {
+			if bound != nil {
+				missing = append(missing, RangeSpecifier{
+					Condition: RangeConditionNotEqual,
+					Version:   bound.Version,
+				})
+			}
+		}
+	}
+	for _, rc := range missing {
+		if hull.within(&rc.Version) && !a.contains(&rc.Version) &&
+			!b.contains(&rc.Version) {
+			hull.excluded = append(hull.excluded, rc)
+		}
+	}
+	return hull.toRange().Simplify(), true
+}
+
+// IsEmpty reports whether no version is in r, because its conditions
+// contradict each other, as in ">= 2, < 1".
+func (r *Range) IsEmpty() bool {
+	s := r.normalize()
+	return s.empty()
+}
+
+// Simplify returns an equivalent Range without redundant conditions, so
+// that ">= 1.0, >= 1.2, != 0.5" becomes ">= 1.2". The conditions are
+// ordered as the lower bound, then the upper bound, then any versions
+// excluded between them, and a range of one version is given as "==". An
+// empty Range is given as its contradictory bounds.
+func (r *Range) Simplify() *Range {
+	s := r.normalize()
+	return s.toRange()
+}
+
+// Min returns the oldest version in r. It returns false if there isn't
+// one, because r is empty or its lower bound is exclusive: there's no
+// oldest version newer than 1.0, as there's always a v1.0.0.1 and a
+// v1.0.0.0.1.
+func (r *Range) Min() (Version, bool) {
+	s := r.normalize()
+	if s.empty() {
+		return Version{}, false
+	}
+	if s.lower == nil {
+		zero := MustParse("0")
+		return zero, r.Contains(&zero)
+	}
+	if s.lower.Condition != RangeConditionGreaterThanOrEqual {
+		return Version{}, false
+	}
+	return s.lower.Version, true
+}
+
+// Subsumes reports whether every version in other is also in r.
+func (r *Range) Subsumes(other *Range) bool {
+	if other.IsEmpty() {
+		return true
+	}
+	s := r.normalize()
+	for _, bound := range []*RangeSpecifier{s.lower, s.upper} {
+		if bound == nil {
+			continue
+		}
+		outside := &Range{conditions: []RangeSpecifier{
+			bound.complement(),
+		}}
+		if !other.Intersect(outside).IsEmpty() {
+			return false
+		}
+	}
+	for i := range s.excluded {
+		if other.Contains(&s.excluded[i].Version) {
+			return false
+		}
+	}
+	return true
+}
+
+// span is the normal form of a Range: the versions between its bounds,
+// either of which may be missing, except for those excluded. Each of the
+// excluded versions is strictly between the bounds.
+type span struct {
+	lower, upper *RangeSpecifier
+	excluded     []RangeSpecifier
+}
+
+func (r *Range) normalize() span {
+	var s span
+	var excluded []RangeSpecifier
+	for _, rc := range r.conditions {
+		switch rc.Condition {
+		case RangeConditionGreaterThan,
+			RangeConditionGreaterThanOrEqual:
+			s.lower = tighter(s.lower, &rc, 1)
+		case rangeConditionNone:
+			// a bare version is a minimum
+			s.lower = tighter(s.lower, &RangeSpecifier{
+				Condition: RangeConditionGreaterThanOrEqual,
+				Version:   rc.Version,
+			}, 1)
+		case RangeConditionLessThan, RangeConditionLessThanOrEqual:
+			s.upper = tighter(s.upper, &rc, -1)
+		case RangeConditionEqual:
+			s.lower = tighter(s.lower, &RangeSpecifier{
+				Condition: RangeConditionGreaterThanOrEqual,
+				Version:   rc.Version,
+			}, 1)
+			s.upper = tighter(s.upper, &RangeSpecifier{
+				Condition: RangeConditionLessThanOrEqual,
+				Version:   rc.Version,
+			}, -1)
+		case RangeConditionNotEqual:
+			excluded = append(excluded, rc)
+		}
+	}
+	sort.SliceStable(excluded, func(i, j int) bool {
+		return excluded[i].Version.LessThan(&excluded[j].Version)
+	})
+	for _, rc := range excluded {
+		n := len(s.excluded)
+		// excluding an inclusive bound makes it exclusive
+		switch {
+		case s.lower != nil && rc.Version.Equal(&s.lower.Version):
+			s.lower = &RangeSpecifier{
+				Condition: RangeConditionGreaterThan,
+				Version:   s.lower.Version,
+			}
+		case s.upper != nil && rc.Version.Equal(&s.upper.Version):
+			s.upper = &RangeSpecifier{
+				Condition: RangeConditionLessThan,
+				Version:   s.upper.Version,
+			}
+		case !s.within(&rc.Version):
+		case n > 0 && rc.Version.Equal(&s.excluded[n-1].Version):
+		default:
+			s.excluded = append(s.excluded, rc)
+		}
+	}
+	return s
+}
+
+func (s *span) empty() bool {
+	if s.upper == nil {
+		return false
+	}
+	lower := s.lower
+	if lower == nil {
+		lower = &noLower
+	}
+	if c := lower.Version.Compare(&s.upper.Version); c != 0 {
+		return c > 0
+	}
+	return lower.Condition == RangeConditionGreaterThan ||
+		s.upper.Condition == RangeConditionLessThan
+}
+
+// noLower is the lower bound of a span without one, as there's no version
+// older than zero.
+var noLower = RangeSpecifier{
+	Condition: RangeConditionGreaterThanOrEqual,
+	Version:   Version{original: "0", version: []int64{0}},
+}
+
+// within reports whether v is within the bounds of s, whether or not it's
+// excluded.
+func (s *span) within(v *Version) bool {
+	return (s.lower == nil || s.lower.contains(v)) &&
+		(s.upper == nil || s.upper.contains(v))
+}
+
+func (s *span) contains(v *Version) bool {
+	if !s.within(v) {
+		return false
+	}
+	for i := range s.excluded {
+		if v.Equal(&s.excluded[i].Version) {
+			return false
+		}
+	}
+	return true
+}
+
+// before reports whether every version in s is older than every version
+// in other, with at least one version between them.
+func (s *span) before(other *span) bool {
+	if s.upper == nil || other.lower == nil {
+		return false
+	}
+	return s.upper.Version.LessThan(&other.lower.Version)
+}
+
+func (s *span) toRange() *Range {
+	r := &Range{}
+	if s.lower != nil && s.upper != nil &&
+		s.lower.Condition == RangeConditionGreaterThanOrEqual &&
+		s.upper.Condition == RangeConditionLessThanOrEqual &&
+		s.lower.Version.Equal(&s.upper.Version) {
+		r.conditions = append(r.conditions, RangeSpecifier{
+			Condition: RangeConditionEqual,
+			Version:   s.lower.Version,
+		})
+		return r
+	}
+	for _, bound := range []*RangeSpecifier{s.lower, s.upper} {
+		if bound != nil {
+			r.conditions = append(r.conditions, *bound)
+		}
+	}
+	if !s.empty() {
+		r.conditions = append(r.conditions, s.excluded...)
+	}
+	return r
+}
+
+// tighter returns whichever of the bounds a and b allows fewer versions,
+// where dir is 1 for lower bounds and -1 for upper ones. Either may be nil,
+// for no bound.
+func tighter(a, b *RangeSpecifier, dir int) *RangeSpecifier {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if c := a.Version.Compare(&b.Version); c != 0 {
+		if c == dir {
+			return a
+		}
+		return b
+	}
+	if b.Condition == RangeConditionGreaterThan ||
+		b.Condition == RangeConditionLessThan {
+		return b
+	}
+	return a
+}
+
+// looser returns whichever of the bounds a and b allows more versions, or
+// nil if either is nil.
+func looser(a, b *RangeSpecifier, dir int) *RangeSpecifier {
+	if a == nil || b == nil {
+		return nil
+	}
+	if tighter(a, b, dir) == a {
+		return b
+	}
+	return a
+}
+
+// complement returns the condition met by exactly the versions that don't
+// meet rc, which must be a bound.
+func (rc *RangeSpecifier) complement() RangeSpecifier {
+	c := RangeSpecifier{Version: rc.Version}
+	switch rc.Condition {
+	case RangeConditionGreaterThan:
+		c.Condition = RangeConditionLessThanOrEqual
+	case RangeConditionGreaterThanOrEqual:
+		c.Condition = RangeConditionLessThan
+	case RangeConditionLessThan:
+		c.Condition = RangeConditionGreaterThanOrEqual
+	case RangeConditionLessThanOrEqual:
+		c.Condition = RangeConditionGreaterThan
+	}
+	return c
+}
@@ -18,7 +18,7 @@ const (
 	RangeConditionLessThanOrEqual
 	RangeConditionNotEqual
 	RangeConditionEqual
-	rangeConditionNone // implicit equal, unexported
+	rangeConditionNone // implicit minimum, unexported
 )
 
 func (c *RangeCondition) String() string {
@@ -47,9 +47,11 @@ func (c *RangeCondition) String() string {
 	}
 }
 
+// URLString returns the condition as MetaCPAN's API takes it, which has no
+// bare versions, so a bare version is given as the minimum it is.
 func (c *RangeCondition) URLString() string {
 	if *c == rangeConditionNone {
-		return "=="
+		return ">="
 	}
 	return c.String()
 }
@@ -66,7 +68,7 @@ func rangeTypeFromString(s string) (RangeCondition, error) {
 		return RangeConditionLessThanOrEqual, nil
 	case "!=":
 		return RangeConditionNotEqual, nil
-	case "=":
+	case "==", "=":
 		return RangeConditionEqual, nil
 	case "":
 		return rangeConditionNone, nil
@@ -1,7 +1,5 @@
 package version
 
-import "strings"
-
 type RangeSpecifier struct {
 	Condition RangeCondition `json:"range_type"`
 	Version   Version        `json:"version"`
@@ -11,7 +9,8 @@ func (rc *RangeSpecifier) contains(v *Version) bool {
 	switch rc.Condition {
 	case RangeConditionGreaterThan:
 		return v.GreaterThan(&rc.Version)
-	case RangeConditionGreaterThanOrEqual:
+	case RangeConditionGreaterThanOrEqual, rangeConditionNone:
+		// a bare version is a minimum, as in CPAN::Meta::Spec
 		return v.GreaterThanOrEqual(&rc.Version)
 	case RangeConditionLessThan:
 		return v.LessThan(&rc.Version)
@@ -21,7 +20,7 @@ func (rc *RangeSpecifier) contains(v *Version) bool {
 		return v.NotEqual(&rc.Version)
 	case RangeConditionEqual:
 		fallthrough
-	case rangeConditionNone, rangeConditionUndef:
+	case rangeConditionUndef:
 		return v.Equal(&rc.Version)
 	}
 	return false
@@ -41,42 +40,3 @@ func (rc *RangeSpecifier) String() string {
 func (rc *RangeSpecifier) URLString() string {
 	return rc.Condition.URLString() + rc.Version.Raw()
 }
-
-func parseCondition(rc *RangeSpecifier, s string) error {
-	s = strings.TrimSpace(s)
-	symbol := string(s[0])
-	foundSymbol := true
-	switch s[0] {
-	case '>':
-		fallthrough
-	case '<':
-		fallthrough
-	case '=':
-		fallthrough
-	case '!':
-		if s[1] == '=' {
-			symbol += "="
-		}
-	default:
-		foundSymbol = false
-	}
-	if foundSymbol {
-		rt, err := rangeTypeFromString(symbol)
-		if err != nil {
-			rc.Condition = rangeConditionUndef
-			return err
-		}
-		rc.Condition = rt
-		s = strings.TrimSpace(s[len(symbol):])
-	}
-	v, err := Parse(s)
-	if err != nil {
-		rc.Condition = rangeConditionUndef
-		return err
-	}
-	if rc.Condition == rangeConditionUndef {
-		rc.Condition = rangeConditionNone
-	}
-	rc.Version = v
-	return nil
-}
@@ -1,13 +1,11 @@
 package version
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
-
-	// local
-	"github.com/cmburn/perlutils/internal"
 )
 
 // JSON is a wrapper around Version that implements json.Marshaler and
@@ -16,17 +14,15 @@ type JSON struct {
 	Version
 }
 
-func (j *JSON) MarshalJSON() ([]byte, error) {
+func (j JSON) MarshalJSON() ([]byte, error) {
 	str := j.Raw()
 	return json.Marshal(str)
 }
 
 func (j *JSON) UnmarshalJSON(b []byte) error {
 	// unmarshal as a string
-	var str string
-	if b[0] != '"' {
-		str = string(b)
-	} else if err := json.Unmarshal(b, &str); err != nil {
+	str, err := unquote(b)
+	if err != nil {
 		return err
 	}
 	str = strings.Trim(str, `"`)
@@ -38,23 +34,38 @@ func (j *JSON) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// unquote returns the string b holds, which may be a JSON string or a bare
+// number. A string without escapes is common enough to be worth not going
+// through json.Unmarshal for.
+func unquote(b []byte) (string, error) {
+	if len(b) == 0 || b[0] != '"' {
+		return string(b), nil
+	}
+	if len(b) > 1 && b[len(b)-1] == '"' &&
+		bytes.IndexByte(b[1:len(b)-1], '\\') < 0 &&
+		bytes.IndexByte(b[1:len(b)-1], '"') < 0 {
+		return string(b[1 : len(b)-1]), nil
+	}
+	var str string
+	err := json.Unmarshal(b, &str)
+	return str, err
+}
+
 func (j *JSON) String() string {
 	return j.Raw()
 }
 
 type JSONNoFail struct{ Version }
 
-func (j *JSONNoFail) MarshalJSON() ([]byte, error) {
+func (j JSONNoFail) MarshalJSON() ([]byte, error) {
 	str := j.Raw()
 	return json.Marshal(str)
 }
 
 func (j *JSONNoFail) UnmarshalJSON(b []byte) error {
 	// unmarshal as a string
-	var str string
-	if b[0] != '"' {
-		str = string(b)
-	} else if err := json.Unmarshal(b, &str); err != nil {
+	str, err := unquote(b)
+	if err != nil {
 		return err
 	}
 	str = strings.Trim(str, `"`)
@@ -82,20 +93,44 @@ func (j *JSONNoFail) String() string {
 // json.Unmarshaler as a string.
 type RangeJSON struct {
 	Range
+	original string
 }
 
 func (rs *RangeJSON) UnmarshalJSON(b []byte) error {
-	s := strings.Trim(string(b), `"`)
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
 	r, err := ParseRange(s)
 	if err != nil {
 		return err
 	}
 	rs.Range = *r
+	rs.original = strings.TrimSpace(s)
 	return nil
 }
 
-func (rs *RangeJSON) MarshalJSON() ([]byte, error) {
-	return internal.WrapEnumTypeJSON(&rs.Range)
+// Raw returns the string the range was unmarshalled from, or String if it
+// wasn't unmarshalled.
+func (rs RangeJSON) Raw() string {
+	if rs.original != "" {
+		return rs.original
+	}
+	return rs.Range.String()
+}
+
+// MarshalJSON marshals the range as Raw does, so that ranges round-trip as
+// they were written.
+func (rs RangeJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(rs.Raw()); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
 
 var _ json.Marshaler = (*JSON)(nil)
@@ -0,0 +1,156 @@
+#!/usr/bin/env perl
+# Generates conformance.tsv from the version.pm that perl loads. Run it from
+# this directory with "perl conformance.pl > conformance.tsv".
+use strict;
+use warnings;
+use version;
+
+# version.pm warns about these and carries on; Parse and Declare fail with
+# the warning as the error.
+local $SIG{__WARN__} = sub {
+	die $_[0] unless $_[0] =~ /lossy/;
+};
+
+print <<"HEADER";
+# Generated by conformance.pl from version.pm $version::VERSION. Each line is
+# the input, is_lax and is_strict of it, then the normal, numify, stringify,
+# is_alpha and is_qv of version->parse and of version->declare, separated by
+# tabs. If either dies, its normal is the error after a "!", and the rest is
+# empty.
+HEADER
+
+while (my $in = <DATA>) {
+	chomp $in;
+	next if $in eq '';
+	my @f = ($in, version::is_lax($in) ? 1 : 0,
+		version::is_strict($in) ? 1 : 0);
+	for my $method ('parse', 'declare') {
+		my $v = eval { version->$method($in) };
+		if (!defined $v) {
+			(my $err = $@) =~ s/ at \S+ line \d+.*\z//s;
+			push @f, "!$err", ('') x 4;
+			next;
+		}
+		push @f, $v->normal, $v->numify, $v->stringify,
+			$v->is_alpha ? 1 : 0, $v->is_qv ? 1 : 0;
+	}
+	print join("\t", @f), "\n";
+}
+
+__DATA__
+0
+1
+01
+1.
+.1
+.1.2
+.1_2
+1.0
+1.00
+1.002
+1.02
+1.2
+1.23
+1.234
+1.2345
+1.10
+1.9
+1.999
+1.9999
+0.000001
+0.0000001
+0001.002
+01.0203
+12.345
+42
+1.11111111111
+1.1234567890123456789
+3.14159265358979323846
+5.005_03
+5.6.1
+5.8.8
+5.010001
+1.02_03
+1.2345_01
+1.2_3
+0.1_1
+1.0_0
+1.2_
+1_2
+1._2
+1_
+_1
+1_000
+1.2__3
+1.2_3_4
+1.2_3.4
+1.2.3
+1.2.3_4
+1.2.3_
+1.2.3.4
+1.02.03
+1.2.3_4.5
+1.2.
+1..2
+1.2.3.
+1.2.3.4.5.6
+2147483647
+2147483648
+10000000000000000000
+2147483647.000
+2147483647.2147483647
+v2147483647.2147483647
+v2147483648
+0000000001
+00000000001
+v1.2.01182816528
+.00648708334
+5452081166;
+undef
+undef1
+.
+v0
+v1
+v01
+v1.
+v1.2
+v1.02
+v1.2.3
+v1.2.3.4
+v1.1982.9.2
+v0.87.50.0_
+v1.2.3_4
+v1.2_3
+v1.2_3.4
+v1.02_03
+v1.2345.6
+v1.1000
+v1.999.999
+v1.2.30
+v1.2.3_0
+v1.2.3_4_5
+v5.36.0
+v0001.002
+v1.02.03
+v1.2.
+v1.2.3.
+v1..2
+v1.2__3
+v.1
+v
+V1.2
+-1
+-1.2
+1.2a
+a1.2
+1.2e3
+0x10
+1.2-3
+1,2
+1.2 3
+1.2. 686516
+1 2
+1.2;
+1.2{
+1.2}
+ 1.2
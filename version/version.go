@@ -59,7 +59,9 @@ func (v *Version) IsQv() bool {
 }
 
 // Normal is a convenience function for normalizing a Version string. It
-// returns it in standardized qv form, with at least three subversions.
+// returns it in standardized qv form, with at least three subversions. As in
+// version.pm, an alpha Version loses its underscore, so "v1.2.3_4" is
+// "v1.2.34".
 func (v *Version) Normal() string {
 	num := len(v.version)
 	if num < 3 {
@@ -78,23 +80,28 @@ func (v *Version) Normal() string {
 // "v1.2.3" would return 1.002003. This is useful for quick comparisons, and
 // embedding in maps, though if you have a Version with many subversions, it's
 // probably better to use the relevant comparison methods (which are probably
-// faster regardless).
+// faster regardless). A float64 only holds about 15 digits, so use
+// NumifyString if you need them all.
 func (v *Version) Numify() float64 {
+	out, _ := strconv.ParseFloat(v.NumifyString(), 64)
+	return out
+}
+
+// NumifyString returns the numeric Version of a Version string exactly, as
+// version.pm's numify does: "v1.2.3" is "1.002003", and "1" is "1.000". As
+// in version.pm, an alpha Version loses its underscore, so "1.02_03" is
+// "1.020300".
+func (v *Version) NumifyString() string {
+	sb := strings.Builder{}
+	sb.WriteString(strconv.FormatInt(v.version[0], 10))
+	sb.WriteByte('.')
 	if len(v.version) == 1 {
-		return float64(v.version[0])
+		sb.WriteString("000")
 	}
-	asStrings := make([]string, len(v.version)-1)
-	for i, v := range v.version[1:] {
-		asStrings[i] = strconv.FormatInt(v, 10)
-		// pad with zeros
-		for len(asStrings[i]) < 3 {
-			asStrings[i] = "0" + asStrings[i]
-		}
+	for _, n := range v.version[1:] {
+		fmt.Fprintf(&sb, "%03d", n)
 	}
-	tail := strings.Join(asStrings, "")
-	str := fmt.Sprintf("%d.%s", v.version[0], tail)
-	out, _ := strconv.ParseFloat(str, 64)
-	return out
+	return sb.String()
 }
 
 // Stringify matches its Perl equivalent- functionally it acts the same as Raw,
@@ -171,35 +178,17 @@ func min(a, b int) int {
 
 // LessThan checks whether a Version is older than another.
 func (v *Version) LessThan(other *Version) bool {
-	length := min(len(v.version), len(other.version))
-	for i := 0; i < length; i++ {
-		if v.version[i] < other.version[i] {
-			return true
-		}
-		if v.version[i] > other.version[i] {
-			return false
-		}
-	}
-	return false
+	return v.Compare(other) < 0
 }
 
 // GreaterThan checks whether a Version is newer than another.
 func (v *Version) GreaterThan(other *Version) bool {
-	length := min(len(v.version), len(other.version))
-	for i := 0; i < length; i++ {
-		if v.version[i] > other.version[i] {
-			return true
-		}
-		if v.version[i] < other.version[i] {
-			return false
-		}
-	}
-	return false
+	return v.Compare(other) > 0
 }
 
 // Equal checks whether two versions are the same. This doesn't strictly
 // mean they're identical, it means, for example, "v5.34" counts as the same as
-// "v5.34.0" *or* "v5.34.1".
+// "v5.34.0".
 func (v *Version) Equal(other *Version) bool {
 	return !(v.LessThan(other) || v.GreaterThan(other))
 }
@@ -223,13 +212,24 @@ func (v *Version) NotEqual(other *Version) bool {
 }
 
 // Compare compares two versions. It returns -1 if the receiver is older,
-// 0 if they're equivalent, and 1 if the receiver is newer.
+// 0 if they're equivalent, and 1 if the receiver is newer. As in version.pm,
+// missing components count as zero, so "v1.2" is older than "v1.2.1".
 func (v *Version) Compare(other *Version) int {
-	if v.LessThan(other) {
-		return -1
-	}
-	if v.GreaterThan(other) {
-		return 1
+	length := max(len(v.version), len(other.version))
+	for i := 0; i < length; i++ {
+		var a, b int64
+		if i < len(v.version) {
+			a = v.version[i]
+		}
+		if i < len(other.version) {
+			b = other.version[i]
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
 	}
 	return 0
 }
@@ -242,9 +242,42 @@ func internalToVersion(dst *Version, src *internal.VersionData) {
 }
 
 // Parse parses a string into a Version. The string can be either a lax or
-// strict versioning scheme, as defined in Version::Internals.
+// strict versioning scheme, as defined in Version::Internals. If it's not a
+// version, the error is a *FormatError with the reason version.pm gives.
+// Unlike version.pm, whitespace around the version is ignored, and anything
+// else after it is an error rather than a warning.
 func Parse(version string) (Version, error) {
+	return parse(version)
+}
+
+// parseRegexp parses a string into a Version as Parse does, but with the
+// regular expressions from Version::Internals rather than by hand. It's much
+// slower, and is kept as the reference Parse is tested against.
+func parseRegexp(version string) (Version, error) {
 	version = strings.TrimSpace(version)
+	if version != "undef" {
+		p, err := prescan(version, false, false)
+		if err != nil {
+			return Version{}, err
+		}
+		if overflows(version[:p.end]) {
+			return Version{}, ErrIntegerOverflow
+		}
+		if p.end != len(version) {
+			return Version{}, contentError(version, p.end)
+		}
+		// version.pm allows "v1." and ".", which the regular
+		// expressions don't
+		if version == "." {
+			return Version{original: version, version: []int64{0}},
+				nil
+		}
+		if version[0] == 'v' && strings.HasSuffix(version, ".") {
+			v, err := parseRegexp(strings.TrimSuffix(version, "."))
+			v.original = version
+			return v, err
+		}
+	}
 	endsWithAlpha := strings.HasSuffix(version, "_")
 	laxMatch := laxRegexp.FindStringSubmatch(version)
 	strictMatch := strictRegexp.FindStringSubmatch(version)
@@ -281,9 +314,79 @@ func Parse(version string) (Version, error) {
 		return v, nil
 	}
 
-	return Version{}, errors.New("invalid Version string: " + version)
+	return Version{}, &FormatError{Version: version,
+		Reason: reasonNonNumeric}
+}
+
+// Declare parses a string into a dotted-decimal Version, as version.pm's
+// declare does, so that "1.2" is "v1.2.0" rather than "v1.200.0". As in
+// version.pm, a version with a single decimal point gets a "v" in front of
+// it when stringified.
+func Declare(version string) (Version, error) {
+	version = strings.TrimSpace(version)
+	if version == "undef" {
+		// version.pm gives a version with four parts here, for no
+		// apparent reason
+		v := Undef()
+		v.version = []int64{0, 0, 0, 0}
+		v.qv = true
+		return v, nil
+	}
+	p, err := prescan(version, false, true)
+	if err != nil {
+		return Version{}, err
+	}
+	dotted := version[:p.end]
+	switch {
+	case strings.HasPrefix(dotted, "."):
+		dotted = "v0" + dotted
+	case !strings.HasPrefix(dotted, "v"):
+		dotted = "v" + dotted
+	}
+	v, err := Parse(strings.TrimSuffix(dotted, "."))
+	if err != nil {
+		return Version{}, err
+	}
+	if p.end != len(version) {
+		return Version{}, contentError(version, p.end)
+	}
+	v.original = version
+	if p.decimals == 1 && version[0] != 'v' {
+		v.original = "v" + version
+	}
+	return v, nil
+}
+
+// Qv is the same as Declare, as version.pm's qv is the same as its declare.
+func Qv(version string) (Version, error) {
+	return Declare(version)
+}
+
+// overflows reports whether any of the integers in version are larger than
+// version.pm allows. The first can't have more than 10 digits either, even
+// if they're leading zeros. Only the integer part of a decimal version
+// counts, as its fraction is read three digits at a time.
+func overflows(version string) bool {
+	s := strings.ReplaceAll(strings.TrimPrefix(version, "v"), "_", "")
+	parts := strings.Split(s, ".")
+	if !strings.HasPrefix(version, "v") && len(parts) < 3 {
+		parts = parts[:1]
+	}
+	if len(parts[0]) > 10 {
+		return true
+	}
+	for _, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if errors.Is(err, strconv.ErrRange) || n > maxVersion {
+			return true
+		}
+	}
+	return false
 }
 
+// maxVersion is the largest integer version.pm allows in a version.
+const maxVersion = 1<<31 - 1
+
 func parseMulti(a, b string) (Version, Version, error) {
 	aPv, err := Parse(a)
 	if err != nil {
@@ -336,3 +439,9 @@ func LooksValid(version string) bool {
 	return laxRegexp.MatchString(version) ||
 		strictRegexp.MatchString(version)
 }
+
+var (
+	// ErrIntegerOverflow is the error for a version with an integer larger
+	// than version.pm allows, which it warns about and treats as infinite.
+	ErrIntegerOverflow = errors.New("Integer overflow in version")
+)
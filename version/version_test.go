@@ -3,11 +3,17 @@
 package version
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/quick"
 )
 
 func getFractionValue(s string, t *testing.T) []int64 {
@@ -1227,3 +1233,908 @@ func TestParseRange(t *testing.T) {
 	}
 
 }
+
+func TestRange_IsEmpty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b  string
+		empty bool
+	}{
+		{">= 1", "< 2", false},
+		{">= 2", "< 1", true},
+		{">= 1", "<= 1", false},
+		{"> 1", "<= 1", true},
+		{">= 1, <= 1", "!= 1", true},
+		{">= 1", "!= 1", false},
+		{"1.5", ">= 1, < 2", false},
+		{"1.5", "< 1.5", true},
+		{"1.5", "1.6", false},
+		{"== 1.5", "== 1.6", true},
+		{"0", "< 0", true},
+		{"< 0", "!= 1", true},
+		{"<= 0", "!= 0", true},
+		{"!= 1", "!= 2", false},
+	}
+	for _, test := range tests {
+		r := MustParseRange(test.a).Intersect(MustParseRange(test.b))
+		if r.IsEmpty() != test.empty {
+			t.Errorf("(%s) & (%s): expected IsEmpty to be %v",
+				test.a, test.b, test.empty)
+		}
+	}
+}
+
+// tRange is a random Range for property tests, made of bounds and
+// exclusions from tRangeVersions.
+type tRange struct {
+	*Range
+}
+
+var tRangeVersions = []string{"0", "1", "1.5", "2", "2.5", "3"}
+
+// tRangeSamples are versions either side of, and between, each of
+// tRangeVersions, so that any Range that isn't empty contains one.
+var tRangeSamples = []string{"0", "0.5", "1", "1.25", "1.5", "1.75", "2",
+	"2.25", "2.5", "2.75", "3", "3.5"}
+
+func (tRange) Generate(rand *rand.Rand, size int) reflect.Value {
+	ops := []string{">", ">=", "<", "<=", "!=", "==", ""}
+	var conds []string
+	for i := rand.Intn(4); i >= 0; i-- {
+		conds = append(conds, ops[rand.Intn(len(ops))]+" "+
+			tRangeVersions[rand.Intn(len(tRangeVersions))])
+	}
+	return reflect.ValueOf(tRange{
+		MustParseRange(strings.Join(conds, ", ")),
+	})
+}
+
+func tCheckRangeProperty(t *testing.T, name string, f interface{}) {
+	t.Helper()
+	if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+}
+
+// tSamples calls f on each of tRangeSamples, reporting whether it returned
+// true for all of them.
+func tSamples(f func(v *Version) bool) bool {
+	for _, s := range tRangeSamples {
+		v := MustParse(s)
+		if !f(&v) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRange_algebra(t *testing.T) {
+	t.Parallel()
+	tCheckRangeProperty(t, "Intersect", func(a, b tRange) bool {
+		r := a.Intersect(b.Range)
+		return tSamples(func(v *Version) bool {
+			return r.Contains(v) == (a.Contains(v) && b.Contains(v))
+		})
+	})
+	tCheckRangeProperty(t, "Simplify", func(a tRange) bool {
+		r := a.Simplify()
+		return r.String() == r.Simplify().String() &&
+			tSamples(func(v *Version) bool {
+				return r.Contains(v) == a.Contains(v)
+			})
+	})
+	tCheckRangeProperty(t, "IsEmpty", func(a tRange) bool {
+		return a.IsEmpty() == tSamples(func(v *Version) bool {
+			return !a.Contains(v)
+		})
+	})
+	tCheckRangeProperty(t, "Union", func(a, b tRange) bool {
+		r, ok := a.Union(b.Range)
+		if !ok {
+			return !a.IsEmpty() && !b.IsEmpty() &&
+				!a.Subsumes(b.Range) && !b.Subsumes(a.Range)
+		}
+		return tSamples(func(v *Version) bool {
+			return r.Contains(v) == (a.Contains(v) || b.Contains(v))
+		})
+	})
+	tCheckRangeProperty(t, "Subsumes", func(a, b tRange) bool {
+		return a.Subsumes(b.Range) == tSamples(func(v *Version) bool {
+			return !b.Contains(v) || a.Contains(v)
+		})
+	})
+	tCheckRangeProperty(t, "Min", func(a tRange) bool {
+		min, ok := a.Min()
+		if !ok {
+			return true
+		}
+		return a.Contains(&min) && tSamples(func(v *Version) bool {
+			return !v.LessThan(&min) || !a.Contains(v)
+		})
+	})
+}
+
+func TestRange_Contains(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input, version string
+		in             bool
+	}{
+		{"0", "0", true},
+		{"0", "undef", true},
+		{"0", "v1.2.3", true},
+		{"0", "2147483647", true},
+		{"1.5", "1.5", true},
+		{"1.5", "2.0", true},
+		{"1.5", "v1.500.1", true},
+		{"1.5", "1.4", false},
+		{"v1.2, < 2", "1.9", true},
+		{"v1.2, < 2", "2", false},
+		{"== 1.5", "1.5", true},
+		{"== 1.5", "1.500", true},
+		{"== 1.5", "2.0", false},
+		{"=1.5", "2.0", false},
+	}
+	for _, test := range tests {
+		r := MustParseRange(test.input)
+		v := MustParse(test.version)
+		if r.Contains(&v) != test.in {
+			t.Errorf("(%s).Contains(%s): expected %v", test.input,
+				test.version, test.in)
+		}
+	}
+	for _, test := range []struct {
+		input, str, url string
+	}{
+		{"1.5", "1.5", ">=1.5"},
+		{"== 1.5", "==1.5", "==1.5"},
+		{"0, != 1", "0, !=1", ">=0,!=1"},
+	} {
+		r := MustParseRange(test.input)
+		if got := r.String(); got != test.str {
+			t.Errorf("String(%q) => %q, expected %q", test.input,
+				got, test.str)
+		}
+		if got := r.URLString(); got != test.url {
+			t.Errorf("URLString(%q) => %q, expected %q",
+				test.input, got, test.url)
+		}
+	}
+}
+
+func TestRange_Simplify(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input, expected string
+	}{
+		{">= 1.0, >= 1.2", ">=1.2"},
+		{">= 1.0, < 2, != 0.5, != 1.5, != 1.5", ">=1.0, <2, !=1.5"},
+		{">= 1, <= 1", "==1"},
+		{">= 1, != 1, <= 2, != 2", ">1, <2"},
+		{"1.5, > 1", ">=1.5"},
+		{"== 1.5, > 1", "==1.5"},
+		{"> 2, < 1, != 1.5", ">2, <1"},
+	}
+	for _, test := range tests {
+		r := MustParseRange(test.input).Simplify()
+		if got := r.String(); got != test.expected {
+			t.Errorf("Simplify(%q) => %q, expected %q", test.input,
+				got, test.expected)
+		}
+	}
+	u, ok := MustParseRange("< 1").Union(MustParseRange("> 1, < 2"))
+	if !ok || u.String() != "<2, !=1" {
+		t.Errorf("unexpected union %v, %v", u, ok)
+	}
+	if _, ok = MustParseRange("< 1").Union(MustParseRange("> 2")); ok {
+		t.Error("expected no union for disjoint ranges")
+	}
+	if min, ok := MustParseRange("> 1, >= 2").Min(); !ok ||
+		min.Raw() != "2" {
+		t.Errorf("unexpected minimum %v, %v", min, ok)
+	}
+	// a bare version is a minimum, so "0" is any version
+	zero, atLeast2 := MustParseRange("0"), MustParseRange(">= 2")
+	if zero.Intersect(atLeast2).IsEmpty() {
+		t.Error("expected 0 and >= 2 to intersect")
+	}
+	if !zero.Subsumes(atLeast2) || atLeast2.Subsumes(zero) {
+		t.Error("expected 0 to subsume >= 2, and not the other way")
+	}
+	u, ok = MustParseRange("1.0").Union(MustParseRange("2.0"))
+	if !ok || u.String() != ">=1.0" {
+		t.Errorf("unexpected union %v, %v", u, ok)
+	}
+	if min, ok := MustParseRange("1.5, < 2").Min(); !ok ||
+		min.Raw() != "1.5" {
+		t.Errorf("unexpected minimum %v, %v", min, ok)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1", "1.5", -1},
+		{"v1.2", "v1.2.1", -1},
+		{"v1.2", "v1.2.0", 0},
+		{"1.10", "1.9", -1},
+		{"undef", "0", 0},
+		// missing parts used to be ignored, so these were Equal
+		{"v5.34", "v5.34.1", -1},
+		{"1.002", "1.002003", -1},
+	}
+	for _, test := range tests {
+		a, b := MustParse(test.a), MustParse(test.b)
+		if got := a.Compare(&b); got != test.expected {
+			t.Errorf("Compare(%q, %q) => %d, expected %d", test.a,
+				test.b, got, test.expected)
+		}
+		if got := b.Compare(&a); got != -test.expected {
+			t.Errorf("Compare(%q, %q) => %d, expected %d", test.b,
+				test.a, got, -test.expected)
+		}
+		if got := a.Equal(&b); got != (test.expected == 0) {
+			t.Errorf("Equal(%q, %q) => %v", test.a, test.b, got)
+		}
+		if got := a.LessThan(&b); got != (test.expected < 0) {
+			t.Errorf("LessThan(%q, %q) => %v", test.a, test.b, got)
+		}
+		if got := a.GreaterThan(&b); got != (test.expected > 0) {
+			t.Errorf("GreaterThan(%q, %q) => %v", test.a, test.b,
+				got)
+		}
+	}
+}
+
+func TestParseRange_errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input          string
+		clause, offset int
+	}{
+		{"", 0, 0},
+		{">", 0, 1},
+		{">= ", 0, 3},
+		{"1, , 2", 1, 3},
+		{",", 0, 0},
+		{"1,,", 1, 2},
+		{">= 1 2", 0, 5},
+		{">= 1, < x", 1, 8},
+		{">=> 1", 0, 2},
+		{"=== 1", 0, 2},
+	}
+	for _, test := range tests {
+		_, err := ParseRange(test.input)
+		var se *RangeSyntaxError
+		if !errors.As(err, &se) || se.Clause != test.clause ||
+			se.Offset != test.offset {
+			t.Errorf("ParseRange(%q): expected an error in clause %d "+
+				"at offset %d, got %v", test.input, test.clause,
+				test.offset, err)
+		}
+	}
+	for _, s := range []string{"0", "== 1.0", " >= 1, < 2 , ", "=1",
+		"!=v1.2.3,\t<=2"} {
+		if _, err := ParseRange(s); err != nil {
+			t.Errorf("ParseRange(%q) returned error: %v", s, err)
+		}
+	}
+}
+
+func FuzzParseRange(f *testing.F) {
+	for _, s := range []string{"", ">", ">= 1, < 2", "== v1.2.3", "1,",
+		"!= 1.0_01", " , ", "<=undef"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		r, err := ParseRange(s)
+		if err != nil {
+			var se *RangeSyntaxError
+			if !errors.As(err, &se) || se.Offset < 0 ||
+				se.Offset > len(s) {
+				t.Fatalf("ParseRange(%q): unexpected error %v", s,
+					err)
+			}
+			return
+		}
+		r2, err := ParseRange(r.String())
+		if err != nil {
+			t.Fatalf("ParseRange(%q): can't reparse %q: %v", s,
+				r.String(), err)
+		}
+		if r2.String() != r.String() {
+			t.Fatalf("ParseRange(%q): %q reparsed as %q", s,
+				r.String(), r2.String())
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile(filepath.Join("testdata", "conformance.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 13 {
+			t.Fatalf("malformed line %q", line)
+		}
+		input := fields[0]
+		if lax, _ := IsLax(input); tFlag(lax) != fields[1] {
+			t.Errorf("IsLax(%q) => %t", input, lax)
+		}
+		if strict, _ := IsStrict(input); tFlag(strict) != fields[2] {
+			t.Errorf("IsStrict(%q) => %t", input, strict)
+		}
+		v, err := Parse(input)
+		tCheckConformance(t, "Parse", input, v, err, fields[3:8])
+		v, err = Declare(input)
+		tCheckConformance(t, "Declare", input, v, err, fields[8:])
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version string
+		lax     string
+		strict  string
+	}{
+		{"v1.2.3", "", ""},
+		{"1.2.3", "", "dotted-decimal versions must begin with 'v'"},
+		{"v1.2", "", "dotted-decimal versions require at least " +
+			"three parts"},
+		{"01", "", "no leading zeros"},
+		{"v01.2.3", "", "no leading zeros"},
+		{".1", "", "0 before decimal required"},
+		{"1.", "", "fractional part required"},
+		{"1.2_3", "", "no underscores"},
+		{"v1.1000.0", "", "maximum 3 digits between decimals"},
+		{"1.2.3_", "misplaced underscore",
+			"dotted-decimal versions must begin with 'v'"},
+		{"1.2 3", "non-numeric data", "non-numeric data"},
+		{"", "version required", "version required"},
+		{"undef", "", "non-numeric data"},
+	}
+	for _, test := range tests {
+		ok, err := IsLax(test.version)
+		tCheckReason(t, "IsLax", test.version, ok, err, test.lax)
+		ok, err = IsStrict(test.version)
+		tCheckReason(t, "IsStrict", test.version, ok, err, test.strict)
+	}
+}
+
+func tCheckReason(t *testing.T, name, input string, ok bool, err error,
+	expected string) {
+	t.Helper()
+	var reason string
+	var fe *FormatError
+	if errors.As(err, &fe) {
+		reason = fe.Reason
+	}
+	if ok != (expected == "") || (err == nil) != ok || reason != expected {
+		t.Errorf("%s(%q) => %t, %v, expected reason %q", name, input,
+			ok, err, expected)
+	}
+}
+
+func tCheckConformance(t *testing.T, name, input string, v Version,
+	err error, expected []string) {
+	t.Helper()
+	if msg, ok := strings.CutPrefix(expected[0], "!"); ok {
+		if err == nil || err.Error() != msg {
+			t.Errorf("%s(%q): expected error %q, got %v", name,
+				input, msg, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s(%q) returned error: %v", name, input, err)
+		return
+	}
+	got := []string{v.Normal(), v.NumifyString(), v.Stringify(),
+		tFlag(v.IsAlpha()), tFlag(v.IsQv())}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("%s(%q) => %q, expected %q", name, input, got,
+			expected)
+	}
+}
+
+func tFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// tParseInputs returns the inputs from the conformance corpus, and strings
+// made up of pieces of versions.
+func tParseInputs(t testing.TB) []string {
+	data, err := os.ReadFile(filepath.Join("testdata", "conformance.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			inputs = append(inputs, strings.Split(line, "\t")[0])
+		}
+	}
+	pieces := []string{"0", "1", "9", "00", "12", "999", "1000",
+		"2147483647", "2147483648", ".", ".", "_", "v", "undef", " ",
+		";", "-", "x"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		sb := strings.Builder{}
+		for j := r.Intn(8); j >= 0; j-- {
+			sb.WriteString(pieces[r.Intn(len(pieces))])
+		}
+		inputs = append(inputs, sb.String())
+	}
+	return inputs
+}
+
+func TestParse_differential(t *testing.T) {
+	t.Parallel()
+	for _, s := range tParseInputs(t) {
+		tCheckParse(t, s)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{"1.2.3", "v1.2_3", ".1", "1.02_03", "1.",
+		"undef", "v1.", "1.2 3", "2147483648"} {
+		f.Add(s)
+	}
+	f.Fuzz(tCheckParse)
+}
+
+// tCheckParse checks that Parse and parseRegexp agree on s.
+func tCheckParse(t *testing.T, s string) {
+	v, err := parse(s)
+	expected, expectedErr := parseRegexp(s)
+	if (err == nil) != (expectedErr == nil) ||
+		(err != nil && err.Error() != expectedErr.Error()) {
+		t.Fatalf("Parse(%q) returned error %v, expected %v", s, err,
+			expectedErr)
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("Parse(%q) => %#v, expected %#v", s, v, expected)
+	}
+}
+
+func TestInterner(t *testing.T) {
+	t.Parallel()
+	in := NewInterner(2)
+	for _, s := range []string{"1.2", "1.2", "v1.2.3", "x", "1.3"} {
+		v, err := in.Parse(s)
+		expected, expectedErr := parse(s)
+		if !reflect.DeepEqual(v, expected) ||
+			(err == nil) != (expectedErr == nil) {
+			t.Errorf("Parse(%q) => %v, %v, expected %v, %v", s, v,
+				err, expected, expectedErr)
+		}
+	}
+	if in.Len() != 2 {
+		t.Errorf("expected 2 cached versions, got %d", in.Len())
+	}
+}
+
+// TestParse_allocs can't be parallel, as AllocsPerRun counts every
+// goroutine's allocations.
+func TestParse_allocs(t *testing.T) {
+	in := NewInterner(1)
+	for _, test := range []struct {
+		name     string
+		fn       func(string) (Version, error)
+		expected float64
+	}{{"Parse", Parse, 1}, {"Interner.Parse", in.Parse, 0}} {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = test.fn("v1.2.3_4")
+		})
+		if allocs != test.expected {
+			t.Errorf("%s: expected %v allocations, got %v",
+				test.name, test.expected, allocs)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	tBenchmarkParse(b, Parse)
+}
+
+func BenchmarkParse_regexp(b *testing.B) {
+	tBenchmarkParse(b, parseRegexp)
+}
+
+func BenchmarkInterner_Parse(b *testing.B) {
+	tBenchmarkParse(b, NewInterner(1024).Parse)
+}
+
+func BenchmarkJSON_UnmarshalJSON(b *testing.B) {
+	data := []byte(`"1.002003"`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var j JSON
+		if err := j.UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// tBenchVersions are typical of the versions on CPAN.
+var tBenchVersions = []string{"1.002003", "0.31", "v1.2.3", "2.2207",
+	"1.02_03", "5.036000", "v5.36.0", "0.9929", "1.2.3", "undef"}
+
+func tBenchmarkParse(b *testing.B, fn func(string) (Version, error)) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := fn(tBenchVersions[i%len(tBenchVersions)])
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// tKeyVersions are versions to compare each other with, some of them Equal
+// but written differently.
+var tKeyVersions = []string{"0", "undef", "v0.0.0", "0.001", "v0.1", "1",
+	"1.0", "v1", "1.2", "1.200", "v1.200.0", "v1.2", "v1.2.0", "1.2_3",
+	"v1.2.3", "1.002003", "1.9", "1.10", "v1.10", "v1.9", "v1.1982.9.2",
+	"v1.256", "v1.65536", "1.1234567890123456789", "2147483647",
+	"v2147483647.2147483647.1", "1.0.0.0.0.0.1"}
+
+func TestVersion_Key(t *testing.T) {
+	t.Parallel()
+	for _, a := range tKeyVersions {
+		va := MustParse(a)
+		ka, ok := va.Key()
+		if !ok {
+			t.Fatalf("Key(%q) failed", a)
+		}
+		for _, b := range tKeyVersions {
+			vb := MustParse(b)
+			kb, _ := vb.Key()
+			expected := va.Compare(&vb)
+			if (ka == kb) != (expected == 0) {
+				t.Errorf("Key(%q) == Key(%q) => %t", a, b,
+					ka == kb)
+			}
+			if got := ka.Compare(kb); got != expected {
+				t.Errorf("Key(%q).Compare(Key(%q)) => %d, "+
+					"expected %d", a, b, got, expected)
+			}
+			got := bytes.Compare(ka.Bytes(), kb.Bytes())
+			if got != expected {
+				t.Errorf("bytes.Compare of the Keys of %q "+
+					"and %q => %d, expected %d", a, b, got,
+					expected)
+			}
+			ea, _ := va.MarshalBinary()
+			eb, _ := vb.MarshalBinary()
+			got = bytes.Compare(ea, eb)
+			if expected != 0 && got != expected ||
+				(got == 0) != (a == b) {
+				t.Errorf("bytes.Compare of the encodings of "+
+					"%q and %q => %d, expected %d", a, b,
+					got, expected)
+			}
+		}
+	}
+	short := MustParse("1.2")
+	if k, _ := short.Key(); k.String() != "v1.200.0" {
+		t.Errorf("unexpected Key %s", k)
+	}
+	long := MustParse("v1" + strings.Repeat(".1", keyParts) + ".0")
+	if _, ok := long.Key(); ok {
+		t.Errorf("Key(%q) should fail", long.Raw())
+	}
+}
+
+func TestVersion_MarshalBinary(t *testing.T) {
+	t.Parallel()
+	for _, s := range tParseInputs(t) {
+		v, err := Parse(s)
+		if err != nil {
+			continue
+		}
+		for _, c := range []struct {
+			name      string
+			marshal   func() ([]byte, error)
+			unmarshal func(*Version, []byte) error
+		}{
+			{"binary", v.MarshalBinary, (*Version).UnmarshalBinary},
+			{"text", v.MarshalText, (*Version).UnmarshalText},
+		} {
+			data, err := c.marshal()
+			if err != nil {
+				t.Fatalf("%s: Marshal(%q): %v", c.name, s, err)
+			}
+			var decoded Version
+			if err = c.unmarshal(&decoded, data); err != nil {
+				t.Fatalf("%s: Unmarshal(%q): %v", c.name, s,
+					err)
+			}
+			if !reflect.DeepEqual(decoded, v) {
+				t.Fatalf("%s: %q decoded as %#v", c.name, s,
+					decoded)
+			}
+		}
+	}
+	for _, data := range []string{"", "\x02\x01", "\x0a", "\x00",
+		"\x00\x00", "\x02\x01\x00\x00\x00", "\x00\x00\x09v1"} {
+		var v Version
+		err := v.UnmarshalBinary([]byte(data))
+		if err != ErrInvalidBinary {
+			t.Errorf("UnmarshalBinary(%q) returned %v", data, err)
+		}
+	}
+}
+
+func TestVersion_Scan(t *testing.T) {
+	t.Parallel()
+	expected := MustParse("v1.2.3_4")
+	value, err := expected.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []any{value, "v1.2.3_4", []byte(" v1.2.3_4")} {
+		var v Version
+		if err = v.Scan(src); err != nil {
+			t.Errorf("Scan(%q) returned error: %v", src, err)
+		} else if !reflect.DeepEqual(v, expected) {
+			t.Errorf("Scan(%q) => %#v", src, v)
+		}
+	}
+	for _, src := range []any{nil, 1, "x"} {
+		var v Version
+		if err = v.Scan(src); err == nil {
+			t.Errorf("Scan(%v) should fail", src)
+		}
+	}
+}
+
+func TestRange_Scan(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{">= 1.2, < 2, != 1.5", "v1.2.3", ""} {
+		r := Range{}
+		if err := r.UnmarshalText([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		value, err := r.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, src := range []any{value, []byte(value.(string))} {
+			var scanned Range
+			if err = scanned.Scan(src); err != nil {
+				t.Errorf("Scan(%q) returned error: %v", src,
+					err)
+			} else if scanned.String() != r.String() {
+				t.Errorf("Scan(%q) => %q", src,
+					scanned.String())
+			}
+		}
+	}
+	var r Range
+	for _, src := range []any{nil, 1, "> x"} {
+		if err := r.Scan(src); err == nil {
+			t.Errorf("Scan(%v) should fail", src)
+		}
+	}
+}
+
+func TestVersion_Bump(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version  string
+		part     int
+		expected string
+		err      error
+	}{
+		{"1.09", Major, "2.00", nil},
+		{"1.09", Minor, "1.10", nil},
+		{"1.99", Minor, "2.00", nil},
+		{"9.999", Minor, "10.000", nil},
+		{"1.09", Patch, "1.090001", nil},
+		{"1.002003", Minor, "1.003000", nil},
+		{"1.002003", Patch, "1.002004", nil},
+		{"1", Major, "2", nil},
+		{"1", Minor, "1.001", nil},
+		{"1.", Major, "2.", nil},
+		{".5", Minor, "0.6", nil},
+		{"undef", Minor, "0.001", nil},
+		{"1.09_01", Minor, "1.10", nil},
+		{"1.09_01", Patch, "1.0902", nil},
+		{"v1.2.3", Major, "v2.0.0", nil},
+		{"v1.2.3", Minor, "v1.3.0", nil},
+		{"v1.2.3", Patch, "v1.2.4", nil},
+		{"v1.2.3", 3, "v1.2.3.1", nil},
+		{"1.2.3", Minor, "1.3.0", nil},
+		{"v1.", Minor, "v1.1", nil},
+		{"v1.999.0", Minor, "v1.1000.0", nil},
+		{"v1.2.3_4", Minor, "v1.3.0", nil},
+		{"v1.2.3_4", Patch, "v1.2.35", nil},
+		{"2147483647", Major, "", ErrIntegerOverflow},
+		{"v1.2147483647", Minor, "", ErrIntegerOverflow},
+		{"v1.2.3", -1, "", ErrInvalidPart},
+	}
+	for _, test := range tests {
+		v := MustParse(test.version)
+		bumped, err := v.Bump(test.part)
+		if err != test.err {
+			t.Errorf("Bump(%q, %d) returned error %v, expected %v",
+				test.version, test.part, err, test.err)
+		} else if bumped.Raw() != test.expected {
+			t.Errorf("Bump(%q, %d) => %q, expected %q",
+				test.version, test.part, bumped.Raw(),
+				test.expected)
+		}
+	}
+	for _, s := range tParseInputs(t) {
+		v, err := Parse(s)
+		if err != nil {
+			continue
+		}
+		for part := Major; part <= 4; part++ {
+			bumped, err := v.Bump(part)
+			if err == ErrIntegerOverflow {
+				continue
+			} else if err != nil {
+				t.Fatalf("Bump(%q, %d) returned error: %v", s,
+					part, err)
+			}
+			if bumped.IsQv() != v.IsQv() || bumped.IsAlpha() {
+				t.Errorf("Bump(%q, %d) => %q", s, part,
+					bumped.Raw())
+			}
+		}
+	}
+}
+
+func TestVersion_Dev(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version  string
+		dev      int
+		withDev  string
+		stripped string
+	}{
+		{"1.09", 1, "1.09_01", "1.09"},
+		{"1.09_01", 2, "1.09_02", "1.09"},
+		{"v1.2.3", 10, "v1.2.3_10", "v1.2.3"},
+		{"v1.2.3_4", 123, "v1.2.3_123", "v1.2.3"},
+	}
+	for _, test := range tests {
+		v := MustParse(test.version)
+		withDev, err := v.WithDev(test.dev)
+		if err != nil {
+			t.Fatalf("WithDev(%q, %d) returned error: %v",
+				test.version, test.dev, err)
+		}
+		if withDev.Raw() != test.withDev || !withDev.IsAlpha() {
+			t.Errorf("WithDev(%q, %d) => %q, expected %q",
+				test.version, test.dev, withDev.Raw(),
+				test.withDev)
+		}
+		if dev, ok := withDev.Dev(); !ok || dev != test.dev {
+			t.Errorf("Dev(%q) => %d, %t", withDev.Raw(), dev, ok)
+		}
+		stripped, err := withDev.StripDev()
+		if err != nil {
+			t.Fatalf("StripDev(%q) returned error: %v",
+				withDev.Raw(), err)
+		}
+		if stripped.Raw() != test.stripped || stripped.IsAlpha() {
+			t.Errorf("StripDev(%q) => %q, expected %q",
+				withDev.Raw(), stripped.Raw(), test.stripped)
+		}
+		if !stripped.LessThan(&withDev) {
+			t.Errorf("%q should sort before %q", stripped.Raw(),
+				withDev.Raw())
+		}
+	}
+	v := MustParse("1.09")
+	if _, ok := v.Dev(); ok {
+		t.Errorf("Dev(%q) should fail", v.Raw())
+	}
+	if _, err := v.WithDev(-1); err != ErrInvalidPart {
+		t.Errorf("WithDev(%q, -1) returned %v", v.Raw(), err)
+	}
+	v = MustParse("1")
+	if _, err := v.WithDev(1); err == nil {
+		t.Errorf("WithDev(%q, 1) should fail", v.Raw())
+	}
+}
+
+func TestVersion_ToDotted(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version string
+		dotted  string
+		decimal string
+	}{
+		{"1.09", "v1.90.0", "1.09"},
+		{"1.2_3", "v1.230.0", "1.2_3"},
+		{"v1.2.3", "v1.2.3", "1.002003"},
+		{"1.2.3", "1.2.3", "1.002003"},
+		{"v1.2", "v1.2", "1.002000"},
+		{"v1.2.3_4", "v1.2.3_4", "1.002034"},
+		{"undef", "v0.0.0", "undef"},
+	}
+	for _, test := range tests {
+		v := MustParse(test.version)
+		dotted, err := v.ToDotted()
+		if err != nil || dotted.Raw() != test.dotted {
+			t.Errorf("ToDotted(%q) => %q, %v, expected %q",
+				test.version, dotted.Raw(), err, test.dotted)
+		}
+		decimal, err := v.ToDecimal()
+		if err != nil || decimal.Raw() != test.decimal {
+			t.Errorf("ToDecimal(%q) => %q, %v, expected %q",
+				test.version, decimal.Raw(), err, test.decimal)
+		}
+	}
+	v := MustParse("v1.1000")
+	if _, err := v.ToDecimal(); err != ErrNotDecimal {
+		t.Errorf("ToDecimal(%q) returned %v", v.Raw(), err)
+	}
+	for _, s := range tParseInputs(t) {
+		v, err := Parse(s)
+		if err != nil {
+			continue
+		}
+		dotted, err := v.ToDotted()
+		if err != nil {
+			t.Fatalf("ToDotted(%q) returned error: %v", s, err)
+		}
+		if !dotted.IsQv() || !dotted.Equal(&v) {
+			t.Errorf("ToDotted(%q) => %q", s, dotted.Raw())
+		}
+		decimal, err := dotted.ToDecimal()
+		if err == ErrNotDecimal || err == ErrIntegerOverflow {
+			continue
+		} else if err != nil {
+			t.Fatalf("ToDecimal(%q) returned error: %v",
+				dotted.Raw(), err)
+		}
+		if decimal.IsQv() || !decimal.Equal(&v) {
+			t.Errorf("ToDecimal(%q) => %q", dotted.Raw(),
+				decimal.Raw())
+		}
+	}
+}
+
+func TestCheckNext(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		previous string
+		next     string
+		ok       bool
+	}{
+		{"1.09", "1.10", true},
+		{"1.09", "1.090", false},
+		{"1.10", "1.9", true},
+		{"1.9", "1.10", false},
+		{"v1.2.3", "v1.2.3_1", true},
+		{"1.09_01", "1.09", false},
+		{"undef", "0.001", true},
+	}
+	for _, test := range tests {
+		previous := MustParse(test.previous)
+		next := MustParse(test.next)
+		err := CheckNext(&previous, &next)
+		if (err == nil) != test.ok ||
+			err != nil && !errors.Is(err, ErrNotGreater) {
+			t.Errorf("CheckNext(%q, %q) returned %v", test.previous,
+				test.next, err)
+		}
+	}
+}